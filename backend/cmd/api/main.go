@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/irfan-ghzl/fasisi-backend/config"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
@@ -13,6 +15,8 @@ import (
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/handler"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/router"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/mail"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
 )
 
 func main() {
@@ -22,6 +26,11 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Initialize PostgreSQL
 	db, err := database.NewPostgresDB(
 		cfg.DBHost,
@@ -49,7 +58,7 @@ func main() {
 	}
 	log.Println("Uploads directory ready")
 
-	// Initialize fixed users (Irfan and Sisti)
+	// Initialize default users (Irfan and Sisti) as the first couple
 	if err := initializeUsers(ctx, db); err != nil {
 		log.Println("Note: Users may already exist:", err)
 	}
@@ -58,32 +67,137 @@ func main() {
 	userRepo := database.NewUserRepository(db)
 	galleryRepo := database.NewGalleryRepository(db)
 	requestRepo := database.NewDateRequestRepository(db)
-	notifRepo := database.NewNotificationRepository(db)
 	chatRepo := database.NewChatRepository(db)
+	reactionRepo := database.NewChatReactionRepository(db)
+	scheduledRepo := database.NewScheduledMessageRepository(db)
+	coupleRepo := database.NewCoupleRepository(db)
+	inviteRepo := database.NewCoupleInviteRepository(db)
+	refreshTokenRepo := database.NewRefreshTokenRepository(db)
+	sessionRepo := database.NewSessionRepository(db)
+	twoFactorRepo := database.NewTwoFactorRepository(db)
+	patRepo := database.NewPersonalAccessTokenRepository(db)
+	passwordResetRepo := database.NewPasswordResetTokenRepository(db)
+
+	// Initialize real-time hubs for chat WebSocket and notification SSE connections
+	chatHub := realtime.NewHub()
+	notifHub := realtime.NewHub()
+	notifRepo := realtime.NewNotificationPublisher(database.NewNotificationRepository(db), notifHub)
+
+	// Initialize mailer, without SMTP settings emails are written to the log
+	mailer := mail.NewLogMailer()
+	if cfg.SMTPHost != "" {
+		mailer = mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(cfg.JWTSecret)
+	authService, err := newAuthService(cfg)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	passwordHasher, err := service.NewPasswordHasher(cfg.PasswordHashAlgo, cfg.BcryptCost, service.Argon2Params{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+	})
+	if err != nil {
+		log.Fatal("Invalid password hashing config:", err)
+	}
+	authService.WithPasswordHasher(passwordHasher)
+	// Unknown emails are checked against a hash like the ones most users have
+	if like, err := userRepo.FindCommonPasswordHash(ctx); err == nil {
+		if err := authService.MatchDummyPasswordHash(like); err != nil {
+			log.Println("Failed to prepare dummy password hash:", err)
+		}
+	}
+	coupleService := service.NewCoupleService(coupleRepo, inviteRepo)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepo, "Fasisi")
+	loginThrottle := service.NewLoginThrottle()
+	patService := service.NewPersonalAccessTokenService(patRepo, userRepo)
+
+	// Presence and typing indicators are pushed over the chat WebSocket
+	presence := realtime.NewPresenceTracker(chatHub, coupleService.PartnerID)
+	if cfg.PresenceBroker == "postgres" {
+		presence.WithBroker(realtime.NewPostgresBroker(db.DB, db.DSN()))
+		log.Println("Sharing presence between instances through PostgreSQL")
+	}
+	go presence.Run(ctx)
+
+	// Scheduled messages and time capsules are delivered in the background and pushed like sent messages
+	scheduler := service.NewMessageScheduler(scheduledRepo, notifRepo, userRepo, coupleService).
+		OnDelivered(func(msg *entity.ChatMessage) {
+			event := realtime.Event{ID: msg.ID, Type: realtime.EventMessage, Data: msg}
+			chatHub.Publish(msg.ReceiverID, event)
+			chatHub.Publish(msg.SenderID, event)
+		})
+	go scheduler.Run(ctx)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(userRepo, authService)
-	galleryHandler := handler.NewGalleryHandler(galleryRepo, notifRepo)
-	requestHandler := handler.NewRequestHandler(requestRepo, notifRepo)
-	chatHandler := handler.NewChatHandler(chatRepo, notifRepo)
-	notificationHandler := handler.NewNotificationHandler(notifRepo)
+	authHandler := handler.NewAuthHandler(userRepo, refreshTokenRepo, sessionRepo, passwordResetRepo, notifRepo, authService, twoFactorService, loginThrottle, mailer, cfg.AppBaseURL).
+		WithPresence(presence, coupleService)
+	galleryHandler := handler.NewGalleryHandler(galleryRepo, notifRepo, coupleService)
+	requestHandler := handler.NewRequestHandler(requestRepo, notifRepo, coupleService)
+	chatHandler := handler.NewChatHandler(chatRepo, reactionRepo, scheduledRepo, galleryRepo, notifRepo, coupleService, chatHub, presence, time.Duration(cfg.ChatEditWindowMinutes)*time.Minute)
+	chatExportHandler := handler.NewChatExportHandler(chatRepo, userRepo, coupleService)
+
+	// Disappearing messages are hard-deleted in the background, the handler removes their files
+	reaper := service.NewMessageReaper(chatRepo).OnDeleted(chatHandler.RemoveExpired)
+	go reaper.Run(ctx)
+	notificationHandler := handler.NewNotificationHandler(notifRepo, notifHub)
+	coupleHandler := handler.NewCoupleHandler(userRepo, notifRepo, coupleService)
+	tokenHandler := handler.NewPersonalAccessTokenHandler(patService)
+	adminHandler := handler.NewAdminHandler(userRepo, galleryRepo, chatRepo, authHandler).
+		WithHubs(chatHub, notifHub)
 
 	// Setup routes
-	authMiddleware := middleware.AuthMiddleware(authService)
+	// Every authenticated request counts as activity for presence
+	authenticate := middleware.AuthMiddleware(authService, sessionRepo, patService)
+	trackActivity := middleware.TrackActivity(presence)
+	authMiddleware := func(next http.Handler) http.Handler {
+		return authenticate(trackActivity(next))
+	}
 	adminMiddleware := middleware.AdminMiddleware
-	r := router.SetupRoutes(authHandler, galleryHandler, requestHandler, chatHandler, notificationHandler, authMiddleware, adminMiddleware)
+	r := router.SetupRoutes(authHandler, galleryHandler, requestHandler, chatHandler, chatExportHandler, notificationHandler, coupleHandler, tokenHandler, adminHandler, authMiddleware, adminMiddleware)
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.ServerPort)
 	log.Printf("Visit http://localhost:%s to access the application", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, r); err != nil {
+	if err := http.ListenAndServe(":"+cfg.ServerPort, middleware.RealIP(trustedProxies)(r)); err != nil {
 		log.Fatal("Server failed to start:", err)
 	}
 }
 
+// newAuthService signs tokens with the configured asymmetric keys, or with JWT_SECRET (HS256)
+// when there are none. JWT_SECRET keeps verifying HS256 tokens during a migration to keys.
+func newAuthService(cfg *config.Config) (*service.AuthService, error) {
+	switch {
+	case cfg.JWTKeysDir != "":
+		keys, err := service.LoadKeySet(cfg.JWTKeysDir, cfg.JWTActiveKID)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Signing JWTs with key %s from %s", keys.Active().ID, cfg.JWTKeysDir)
+		return service.NewAuthServiceWithKeys(cfg.JWTSecret, keys), nil
+
+	case cfg.JWTSigningKey != "":
+		kid := cfg.JWTActiveKID
+		if kid == "" {
+			kid = "default"
+		}
+		key, err := service.ParseSigningKey(kid, []byte(cfg.JWTSigningKey))
+		if err != nil {
+			return nil, err
+		}
+		keys, err := service.NewKeySet(kid, key)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Signing JWTs with key %s", kid)
+		return service.NewAuthServiceWithKeys(cfg.JWTSecret, keys), nil
+	}
+
+	return service.NewAuthService(cfg.JWTSecret), nil
+}
+
 func initializeUsers(ctx context.Context, db *database.PostgresDB) error {
 	authService := service.NewAuthService("temp")
 
@@ -113,5 +227,20 @@ func initializeUsers(ctx context.Context, db *database.PostgresDB) error {
 
 	log.Println("Initialized users: irfan (super_admin) and sisti (user)")
 	log.Println("Default passwords: irfan123, sisti123")
+
+	// Pair Irfan and Sisti unless either of them already has a partner
+	irfan, err := userRepo.FindByUsername(ctx, "irfan")
+	if err != nil {
+		return err
+	}
+	sisti, err = userRepo.FindByUsername(ctx, "sisti")
+	if err != nil {
+		return err
+	}
+
+	coupleService := service.NewCoupleService(database.NewCoupleRepository(db), database.NewCoupleInviteRepository(db))
+	if _, err := coupleService.Pair(ctx, irfan.ID, sisti.ID); err != nil && !errors.Is(err, service.ErrAlreadyPaired) {
+		return err
+	}
 	return nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config holds application configuration
@@ -14,6 +16,35 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	// Asymmetric JWT signing keys, loaded from a directory of *.pem files or a single PEM value
+	JWTKeysDir    string
+	JWTSigningKey string
+	JWTActiveKID  string
+
+	// Password hashing, argon2id or bcrypt
+	PasswordHashAlgo  string
+	BcryptCost        int
+	Argon2Memory      int // KiB
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	// Mail settings, emails are only logged when SMTPHost is empty
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+	AppBaseURL   string
+
+	// Chat messages can be edited and deleted by their sender for this long, 0 means no limit
+	ChatEditWindowMinutes int
+
+	// Shares presence and typing indicators between API instances: empty (single instance) or "postgres"
+	PresenceBroker string
+
+	// IPs or CIDR ranges of reverse proxies whose X-Forwarded-For and X-Real-IP headers are trusted
+	TrustedProxies []string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -26,9 +57,32 @@ func LoadConfig() (*Config, error) {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "fasisi_db"),
+
+		JWTKeysDir: getEnv("JWT_KEYS_DIR", ""),
+		// PEM values in env files usually have their newlines escaped
+		JWTSigningKey: strings.ReplaceAll(getEnv("JWT_SIGNING_KEY", ""), `\n`, "\n"),
+		JWTActiveKID:  getEnv("JWT_ACTIVE_KID", ""),
+
+		PasswordHashAlgo:  getEnv("PASSWORD_HASH_ALGO", "argon2id"),
+		BcryptCost:        getEnvInt("BCRYPT_COST", 10),
+		Argon2Memory:      getEnvInt("ARGON2_MEMORY_KB", 19*1024),
+		Argon2Iterations:  getEnvInt("ARGON2_ITERATIONS", 2),
+		Argon2Parallelism: getEnvInt("ARGON2_PARALLELISM", 1),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@fasisi.com"),
+		AppBaseURL:   getEnv("APP_BASE_URL", "http://localhost:3000"),
+
+		ChatEditWindowMinutes: getEnvInt("CHAT_EDIT_WINDOW_MINUTES", 15),
+		PresenceBroker:        getEnv("PRESENCE_BROKER", ""),
+		TrustedProxies:        getEnvList("TRUSTED_PROXIES"),
 	}
 
-	if cfg.JWTSecret == "" {
+	// JWT_SECRET may only be left out when tokens are signed with asymmetric keys
+	if cfg.JWTSecret == "" && cfg.JWTKeysDir == "" && cfg.JWTSigningKey == "" {
 		return nil, fmt.Errorf("JWT_SECRET is required")
 	}
 
@@ -36,6 +90,10 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("DB_PASSWORD is required")
 	}
 
+	if cfg.PresenceBroker != "" && cfg.PresenceBroker != "postgres" {
+		return nil, fmt.Errorf("PRESENCE_BROKER must be empty or postgres")
+	}
+
 	return cfg, nil
 }
 
@@ -45,3 +103,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, empty items are dropped
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -8,3 +8,7 @@ require (
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.31.0
 )
+
+require github.com/gorilla/websocket v1.5.3
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -4,10 +4,155 @@ import "time"
 
 // ChatMessage entity
 type ChatMessage struct {
-	ID         int64     `json:"id"`
-	SenderID   int64     `json:"sender_id"`
-	ReceiverID int64     `json:"receiver_id"`
-	Message    string    `json:"message"`
-	ReadStatus bool      `json:"read_status"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         int64      `json:"id"`
+	SenderID   int64      `json:"sender_id"`
+	ReceiverID int64      `json:"receiver_id"`
+	Message    string     `json:"message"`
+	ReadStatus bool       `json:"read_status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+
+	// ExpiresAt is set on disappearing messages, they are gone from then on
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// System marks messages posted by the server, e.g. when the disappearing message timer changes
+	System bool `json:"system,omitempty"`
+
+	// Receipts, Status is derived from them by UpdateStatus
+	DeliveredAt *time.Time    `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time    `json:"read_at,omitempty"`
+	Status      MessageStatus `json:"status"`
+
+	// Attachment is the photo, video or voice note sent with the message
+	Attachment *ChatAttachment `json:"attachment,omitempty"`
+
+	// ReplyTo quotes the message this one replies to, as it was when the reply was sent
+	ReplyTo *MessagePreview `json:"reply_to,omitempty"`
+
+	// Reactions is only filled where a response includes them
+	Reactions []*ReactionSummary `json:"reactions,omitempty"`
+}
+
+// MessageStatus is how far a chat message got towards its receiver
+type MessageStatus string
+
+const (
+	MessageStatusSent      MessageStatus = "sent"
+	MessageStatusDelivered MessageStatus = "delivered" // fetched or received by a client of the receiver
+	MessageStatusRead      MessageStatus = "read"
+)
+
+// UpdateStatus sets Status from the receipt timestamps
+func (m *ChatMessage) UpdateStatus() {
+	switch {
+	case m.ReadAt != nil:
+		m.Status = MessageStatusRead
+	case m.DeliveredAt != nil:
+		m.Status = MessageStatusDelivered
+	default:
+		m.Status = MessageStatusSent
+	}
+}
+
+// ChatAttachment describes the media file of a chat message
+type ChatAttachment struct {
+	FileType FileType `json:"file_type"`
+	FilePath string   `json:"file_path"`
+	MimeType string   `json:"mime_type"`
+	FileSize int64    `json:"file_size"`          // bytes
+	Duration float64  `json:"duration,omitempty"` // seconds, audio and video only
+}
+
+// MessagePreviewLength is the number of characters kept of a quoted message
+const MessagePreviewLength = 100
+
+// MessagePreview is a short copy of a quoted chat message
+type MessagePreview struct {
+	ID       int64  `json:"id,omitempty"` // zero once the original is gone
+	SenderID int64  `json:"sender_id"`
+	Message  string `json:"message"`
+}
+
+// NewMessagePreview quotes the message, shortening long texts to MessagePreviewLength characters
+func NewMessagePreview(m *ChatMessage) *MessagePreview {
+	text := []rune(m.Message)
+	preview := m.Message
+	if len(text) > MessagePreviewLength {
+		preview = string(text[:MessagePreviewLength-1]) + "…"
+	}
+	return &MessagePreview{ID: m.ID, SenderID: m.SenderID, Message: preview}
+}
+
+// IsBetween checks if the message is part of the conversation of the two users
+func (m *ChatMessage) IsBetween(user1ID, user2ID int64) bool {
+	return (m.SenderID == user1ID && m.ReceiverID == user2ID) || (m.SenderID == user2ID && m.ReceiverID == user1ID)
+}
+
+// IsDeleted checks if the message was deleted, only its tombstone is left then
+func (m *ChatMessage) IsDeleted() bool {
+	return m.DeletedAt != nil
+}
+
+// IsExpired checks if a disappearing message is past its expiry at now
+func (m *ChatMessage) IsExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+// CanBeChangedBy checks if the user may edit or delete the message at now.
+// Only the sender can, within window after sending; a window of zero means no time limit.
+// System messages cannot be changed.
+func (m *ChatMessage) CanBeChangedBy(userID int64, now time.Time, window time.Duration) bool {
+	if m.SenderID != userID || m.IsDeleted() || m.System {
+		return false
+	}
+	return window <= 0 || now.Before(m.CreatedAt.Add(window))
+}
+
+// ChatMessageRevision is a previous version of an edited chat message
+type ChatMessageRevision struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"` // when this version was replaced
+}
+
+// ChatSearchResult is a chat message that matched a search
+type ChatSearchResult struct {
+	Message *ChatMessage `json:"message"`
+	Rank    float64      `json:"rank"`
+	// Snippet is an HTML-escaped excerpt of the message with matches wrapped in <mark></mark>
+	Snippet string `json:"snippet"`
+}
+
+// ChatReaction is an emoji a user put on a chat message
+type ChatReaction struct {
+	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
+	UserID    int64     `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReactionSummary groups the reactions on a message by emoji
+type ReactionSummary struct {
+	Emoji   string  `json:"emoji"`
+	Count   int     `json:"count"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// SummarizeReactions groups reactions by emoji, in the order each emoji was first used
+func SummarizeReactions(reactions []*ChatReaction) []*ReactionSummary {
+	var summaries []*ReactionSummary
+	byEmoji := make(map[string]*ReactionSummary)
+	for _, reaction := range reactions {
+		summary, ok := byEmoji[reaction.Emoji]
+		if !ok {
+			summary = &ReactionSummary{Emoji: reaction.Emoji}
+			byEmoji[reaction.Emoji] = summary
+			summaries = append(summaries, summary)
+		}
+		summary.Count++
+		summary.UserIDs = append(summary.UserIDs, reaction.UserID)
+	}
+	return summaries
 }
@@ -0,0 +1,52 @@
+package entity
+
+import "time"
+
+// Couple entity - links two users as partners
+type Couple struct {
+	ID        int64     `json:"id"`
+	User1ID   int64     `json:"user1_id"`
+	User2ID   int64     `json:"user2_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// MessageTTL is the disappearing message timer in seconds, 0 when it is off
+	MessageTTL int64 `json:"message_ttl"`
+}
+
+// MessageExpiry returns when a chat message sent at now disappears, nil when the timer is off
+func (c *Couple) MessageExpiry(now time.Time) *time.Time {
+	if c.MessageTTL <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(time.Duration(c.MessageTTL) * time.Second)
+	return &expiresAt
+}
+
+// Has checks if user is a member of the couple
+func (c *Couple) Has(userID int64) bool {
+	return c.User1ID == userID || c.User2ID == userID
+}
+
+// PartnerOf returns the other member of the couple
+func (c *Couple) PartnerOf(userID int64) int64 {
+	if c.User1ID == userID {
+		return c.User2ID
+	}
+	return c.User1ID
+}
+
+// CoupleInvite entity - short-lived code a user shares with their partner
+type CoupleInvite struct {
+	ID         int64      `json:"id"`
+	Code       string     `json:"code"`
+	InviterID  int64      `json:"inviter_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RedeemedBy *int64     `json:"redeemed_by,omitempty"`
+	RedeemedAt *time.Time `json:"redeemed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsUsable checks if the invite can still be redeemed
+func (i *CoupleInvite) IsUsable(now time.Time) bool {
+	return i.RedeemedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -8,15 +8,18 @@ type FileType string
 const (
 	FileTypePhoto FileType = "photo"
 	FileTypeVideo FileType = "video"
+	FileTypeAudio FileType = "audio" // voice notes, chat only
 )
 
 // Gallery entity
 type Gallery struct {
 	ID        int64     `json:"id"`
 	UserID    int64     `json:"user_id"`
+	CoupleID  int64     `json:"couple_id"`
 	FileType  FileType  `json:"file_type"`
 	FilePath  string    `json:"file_path"`
 	Caption   string    `json:"caption"`
+	FileSize  int64     `json:"file_size"` // bytes
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
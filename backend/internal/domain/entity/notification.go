@@ -10,6 +10,9 @@ const (
 	NotificationTypeChatMessage   NotificationType = "chat_message"
 	NotificationTypeNewMessage    NotificationType = "new_message"
 	NotificationTypeGalleryUpload NotificationType = "gallery_upload"
+	NotificationTypePartnerLinked NotificationType = "partner_linked"
+	NotificationTypeSecurityAlert NotificationType = "security_alert"
+	NotificationTypeReaction      NotificationType = "reaction"
 )
 
 // Notification entity
@@ -0,0 +1,48 @@
+package entity
+
+// Permission is a named action a role may perform beyond the default user rights
+type Permission string
+
+const (
+	// PermissionAdminAccess allows using the /api/admin routes
+	PermissionAdminAccess Permission = "admin.access"
+	// PermissionUsersManage allows changing roles and disabling accounts of other users
+	PermissionUsersManage Permission = "users.manage"
+	// PermissionGalleryDeleteAny allows deleting gallery items uploaded by anyone
+	PermissionGalleryDeleteAny Permission = "gallery.delete_any"
+	// PermissionRequestsDeleteAny allows deleting date requests created by anyone
+	PermissionRequestsDeleteAny Permission = "requests.delete_any"
+)
+
+// rolePermissions maps every role to the permissions it is granted.
+// Roles that are not listed have no permissions.
+var rolePermissions = map[UserRole][]Permission{
+	RoleUser: {},
+	RoleSuperAdmin: {
+		PermissionAdminAccess,
+		PermissionUsersManage,
+		PermissionGalleryDeleteAny,
+		PermissionRequestsDeleteAny,
+	},
+}
+
+// Can reports whether the role is granted permission
+func (r UserRole) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions granted to the role
+func (r UserRole) Permissions() []Permission {
+	return append([]Permission{}, rolePermissions[r]...)
+}
+
+// IsValid reports whether the role is known
+func (r UserRole) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
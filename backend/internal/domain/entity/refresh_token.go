@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+// RefreshToken entity - a persisted refresh token identified by its JTI.
+// Tokens created by rotating each other share the same FamilyID.
+type RefreshToken struct {
+	ID         int64      `json:"id"`
+	JTI        string     `json:"jti"`
+	FamilyID   string     `json:"family_id"`
+	UserID     int64      `json:"user_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy string     `json:"replaced_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsRevoked checks if the token was revoked or already rotated
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
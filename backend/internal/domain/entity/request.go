@@ -23,6 +23,7 @@ const (
 type DateRequest struct {
 	ID          int64         `json:"id"`
 	UserID      int64         `json:"user_id"`
+	CoupleID    int64         `json:"couple_id"`
 	RequestType RequestType   `json:"request_type"`
 	Title       string        `json:"title"`
 	Description string        `json:"description"`
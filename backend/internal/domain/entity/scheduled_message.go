@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+// ScheduledMessageKind defines kinds of scheduled messages
+type ScheduledMessageKind string
+
+const (
+	ScheduledKindMessage     ScheduledMessageKind = "message"
+	ScheduledKindTimeCapsule ScheduledMessageKind = "time_capsule" // sealed "open when" letter
+)
+
+// ScheduledMessageStatus defines scheduled message status
+type ScheduledMessageStatus string
+
+const (
+	ScheduledStatusPending    ScheduledMessageStatus = "pending"
+	ScheduledStatusProcessing ScheduledMessageStatus = "processing" // claimed by the scheduler, being delivered
+	ScheduledStatusDelivered  ScheduledMessageStatus = "delivered"
+	ScheduledStatusCancelled  ScheduledMessageStatus = "cancelled"
+)
+
+// ScheduledMessage is a chat message that is delivered at DeliverAt
+type ScheduledMessage struct {
+	ID         int64                  `json:"id"`
+	SenderID   int64                  `json:"sender_id"`
+	ReceiverID int64                  `json:"receiver_id"`
+	Kind       ScheduledMessageKind   `json:"kind"`
+	Title      string                 `json:"title,omitempty"` // time capsules only
+	Message    string                 `json:"message,omitempty"`
+	DeliverAt  time.Time              `json:"deliver_at"`
+	Status     ScheduledMessageStatus `json:"status"`
+	MessageID  *int64                 `json:"message_id,omitempty"` // the chat message once delivered
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// IsPending reports whether the message still waits for delivery
+func (m *ScheduledMessage) IsPending() bool {
+	return m.Status == ScheduledStatusPending
+}
+
+// Sealed returns the message as the receiver may see it before delivery, without its text
+func (m *ScheduledMessage) Sealed() *ScheduledMessage {
+	sealed := *m
+	sealed.Message = ""
+	return &sealed
+}
+
+// ChatText is the text of the chat message the scheduled message is delivered as.
+// Time capsules keep their title as the first line.
+func (m *ScheduledMessage) ChatText() string {
+	if m.Kind == ScheduledKindTimeCapsule && m.Title != "" {
+		return "💌 " + m.Title + "\n\n" + m.Message
+	}
+	return m.Message
+}
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Session entity - a signed-in device, created on every login
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int64      `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// UserDisabled is set when an admin disabled the account the session belongs to
+	UserDisabled bool `json:"-"`
+}
+
+// IsActive checks if the session has not been revoked
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// UserTOTP entity - a user's TOTP secret for two-factor authentication
+type UserTOTP struct {
+	UserID       int64      `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // last accepted time step, codes cannot be replayed
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// IsEnabled checks if the secret has been confirmed and 2FA is active
+func (t *UserTOTP) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
@@ -12,38 +12,25 @@ const (
 	RoleSuperAdmin UserRole = "super_admin"
 )
 
-// User entity - Fixed users: Irfan (super admin) and Sisti
+// User entity - partners are linked through a Couple
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	PasswordHash string    `json:"-"`
-	Role         UserRole  `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int64      `json:"id"`
+	Username     string     `json:"username"`
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone"`
+	PasswordHash string     `json:"-"`
+	Role         UserRole   `json:"role"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
-// IsAdmin checks if user is super admin
+// IsAdmin checks if user may use the admin API
 func (u *User) IsAdmin() bool {
-	return u.Role == RoleSuperAdmin
+	return u.Role.Can(PermissionAdminAccess)
 }
 
-// Fixed users - Only Irfan and Sisti
-var (
-	// Irfan - Super Admin
-	UserIrfan = User{
-		ID:       1,
-		Username: "irfan",
-		Email:    "irfan@fasisi.com",
-		Role:     RoleSuperAdmin,
-	}
-
-	// Sisti - Regular User
-	UserSisti = User{
-		ID:       2,
-		Username: "sisti",
-		Email:    "sisti@fasisi.com",
-		Role:     RoleUser,
-	}
-)
+// IsDisabled checks if an admin disabled the account
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrChatReactionNotFound is returned when the user has no such reaction on the message
+var ErrChatReactionNotFound = errors.New("chat reaction not found")
+
+// ChatReactionRepository defines chat reaction data access interface
+type ChatReactionRepository interface {
+	// Add stores a reaction and reports whether it is new, adding the same emoji twice is a no-op
+	Add(ctx context.Context, reaction *entity.ChatReaction) (bool, error)
+	Remove(ctx context.Context, messageID, userID int64, emoji string) error
+	// FindByMessageIDs returns the reactions on the messages, oldest first
+	FindByMessageIDs(ctx context.Context, messageIDs []int64) ([]*entity.ChatReaction, error)
+}
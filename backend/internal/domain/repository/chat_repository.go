@@ -2,13 +2,55 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
+
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
 )
 
-// ChatRepository defines chat data access interface
+// ErrChatMessageNotFound is returned when a chat message does not exist
+var ErrChatMessageNotFound = errors.New("chat message not found")
+
+// ChatPage selects a page of a conversation. BeforeID and AfterID are message IDs
+// and at most one of them is set, without either the latest messages are selected.
+type ChatPage struct {
+	BeforeID int64
+	AfterID  int64
+	Limit    int
+}
+
+// ChatRepository defines chat data access interface.
+// Expired disappearing messages are never returned, even before DeleteExpired removed them.
 type ChatRepository interface {
-	FindHistory(ctx context.Context, user1ID, user2ID int64) ([]*entity.ChatMessage, error)
+	// FindHistoryPage returns a page in ascending order and whether more messages exist
+	// beyond it in the paging direction
+	FindHistoryPage(ctx context.Context, user1ID, user2ID int64, page ChatPage) ([]*entity.ChatMessage, bool, error)
+	FindHistoryAfter(ctx context.Context, user1ID, user2ID, afterID int64) ([]*entity.ChatMessage, error)
+	// Search returns the messages of a conversation matching a web-search style query,
+	// best matches first
+	Search(ctx context.Context, user1ID, user2ID int64, query string, limit int) ([]*entity.ChatSearchResult, error)
+	// StreamHistory calls fn with every message of the conversation created from from (inclusive)
+	// until to (exclusive), oldest first. A zero from or to leaves that end open and deleted messages
+	// are skipped. Rows are read one at a time, an error returned by fn stops the stream.
+	StreamHistory(ctx context.Context, user1ID, user2ID int64, from, to time.Time, fn func(*entity.ChatMessage) error) error
+	FindByID(ctx context.Context, id int64) (*entity.ChatMessage, error)
+	// FindByAttachmentPath returns the message whose attachment is stored at filePath
+	FindByAttachmentPath(ctx context.Context, filePath string) (*entity.ChatMessage, error)
 	Create(ctx context.Context, message *entity.ChatMessage) error
-	MarkAsRead(ctx context.Context, senderID, receiverID int64) error
+	// Edit replaces the text of a message, keeping the previous text as a revision
+	Edit(ctx context.Context, message *entity.ChatMessage, text string) error
+	// Delete turns a message into a tombstone, clearing its text, attachment, revisions and reactions.
+	// Removing the attachment file is left to the caller.
+	Delete(ctx context.Context, message *entity.ChatMessage) error
+	// DeleteExpired hard-deletes up to limit messages that expired at now and returns them.
+	// Replies quoting them lose the quoted text. Removing attachment files is left to the caller.
+	DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*entity.ChatMessage, error)
+	FindRevisions(ctx context.Context, messageID int64) ([]*entity.ChatMessageRevision, error)
+	// MarkDelivered sets delivered_at on the messages from sender to receiver with an ID up to upToID
+	// that were not delivered yet, upToID 0 marks all of them. It returns how many messages changed.
+	MarkDelivered(ctx context.Context, senderID, receiverID, upToID int64, at time.Time) (int64, error)
+	// MarkRead is MarkDelivered for read_at, messages that were never marked delivered are delivered at the same time
+	MarkRead(ctx context.Context, senderID, receiverID, upToID int64, at time.Time) (int64, error)
 	CountUnread(ctx context.Context, userID int64) (int64, error)
+	CountByUserID(ctx context.Context, userID int64) (sent int64, received int64, err error)
 }
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrInviteNotFound is returned when an invite code does not exist
+var ErrInviteNotFound = errors.New("invite not found")
+
+// CoupleInviteRepository defines couple invite data access interface
+type CoupleInviteRepository interface {
+	FindByCode(ctx context.Context, code string) (*entity.CoupleInvite, error)
+	Create(ctx context.Context, invite *entity.CoupleInvite) error
+	MarkRedeemed(ctx context.Context, id, userID int64) error
+	DeletePendingByInviter(ctx context.Context, inviterID int64) error
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrCoupleNotFound is returned when a user is not paired with anyone
+var ErrCoupleNotFound = errors.New("couple not found")
+
+// ErrCoupleMemberExists is returned when creating a couple with a user that is already paired
+var ErrCoupleMemberExists = errors.New("user already belongs to a couple")
+
+// CoupleRepository defines couple data access interface
+type CoupleRepository interface {
+	FindByID(ctx context.Context, id int64) (*entity.Couple, error)
+	FindByUserID(ctx context.Context, userID int64) (*entity.Couple, error)
+	// Create returns ErrCoupleMemberExists when either user is already in a couple
+	Create(ctx context.Context, couple *entity.Couple) error
+	// UpdateMessageTTL sets the disappearing message timer of a couple in seconds
+	UpdateMessageTTL(ctx context.Context, id int64, ttl int64) error
+	Delete(ctx context.Context, id int64) error
+}
@@ -10,6 +10,8 @@ type GalleryRepository interface {
 	FindAll(ctx context.Context) ([]*entity.Gallery, error)
 	FindByID(ctx context.Context, id int64) (*entity.Gallery, error)
 	FindByUserID(ctx context.Context, userID int64) ([]*entity.Gallery, error)
+	FindByCoupleID(ctx context.Context, coupleID int64) ([]*entity.Gallery, error)
 	Create(ctx context.Context, gallery *entity.Gallery) error
+	UsageByUserID(ctx context.Context, userID int64) (items int64, bytes int64, err error)
 	Delete(ctx context.Context, id int64) error
 }
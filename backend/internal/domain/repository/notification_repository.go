@@ -8,6 +8,7 @@ import (
 // NotificationRepository defines notification data access interface
 type NotificationRepository interface {
 	FindByUserID(ctx context.Context, userID int64, limit int) ([]*entity.Notification, error)
+	FindByUserIDAfter(ctx context.Context, userID, afterID int64, limit int) ([]*entity.Notification, error)
 	Create(ctx context.Context, notification *entity.Notification) error
 	MarkAsRead(ctx context.Context, id int64) error
 	MarkAllAsRead(ctx context.Context, userID int64) error
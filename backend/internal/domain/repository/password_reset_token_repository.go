@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+var (
+	// ErrPasswordResetTokenNotFound is returned when no reset token matches the hash
+	ErrPasswordResetTokenNotFound = errors.New("password reset token not found")
+	// ErrPasswordResetTokenUsed is returned when a reset token was already used
+	ErrPasswordResetTokenUsed = errors.New("password reset token already used")
+)
+
+// PasswordResetTokenRepository defines password reset token data access interface
+type PasswordResetTokenRepository interface {
+	FindByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error)
+	Create(ctx context.Context, token *entity.PasswordResetToken) error
+	// MarkUsed atomically marks an unused token as used
+	MarkUsed(ctx context.Context, id int64) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
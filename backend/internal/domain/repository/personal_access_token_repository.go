@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrPersonalAccessTokenNotFound is returned when no token matches
+var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+
+// PersonalAccessTokenRepository defines personal access token data access interface
+type PersonalAccessTokenRepository interface {
+	FindByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error)
+	FindByUserID(ctx context.Context, userID int64) ([]*entity.PersonalAccessToken, error)
+	Create(ctx context.Context, token *entity.PersonalAccessToken) error
+	// Revoke revokes a token of the given user
+	Revoke(ctx context.Context, id, userID int64) error
+	TouchLastUsed(ctx context.Context, id int64) error
+}
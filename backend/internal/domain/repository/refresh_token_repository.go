@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+var (
+	// ErrRefreshTokenNotFound is returned when no token matches the JTI
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenRevoked is returned when rotating a token that was already used or revoked
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+)
+
+// RefreshTokenRepository defines refresh token data access interface
+type RefreshTokenRepository interface {
+	FindByJTI(ctx context.Context, jti string) (*entity.RefreshToken, error)
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	Rotate(ctx context.Context, jti, replacedBy string) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
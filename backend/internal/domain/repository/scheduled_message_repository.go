@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrScheduledMessageNotFound is returned when a scheduled message does not exist or is no longer pending
+var ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+
+// ScheduledMessageRepository defines scheduled message data access interface
+type ScheduledMessageRepository interface {
+	Create(ctx context.Context, message *entity.ScheduledMessage) error
+	FindByID(ctx context.Context, id int64) (*entity.ScheduledMessage, error)
+	// FindPendingBySender returns the pending messages of a sender, next delivery first
+	FindPendingBySender(ctx context.Context, senderID int64) ([]*entity.ScheduledMessage, error)
+	// FindPendingByReceiver returns the pending messages of a kind for a receiver, next delivery first
+	FindPendingByReceiver(ctx context.Context, receiverID int64, kind entity.ScheduledMessageKind) ([]*entity.ScheduledMessage, error)
+	// Update changes the title, message and delivery time of a pending message
+	Update(ctx context.Context, message *entity.ScheduledMessage) error
+	// Cancel marks a pending message as cancelled
+	Cancel(ctx context.Context, id int64) error
+	// ClaimDue marks up to limit pending messages due at now as processing and returns them.
+	// Messages claimed by one instance are skipped by the others. Messages still processing
+	// since before reclaimBefore are claimed again, their delivery was interrupted.
+	ClaimDue(ctx context.Context, now, reclaimBefore time.Time, limit int) ([]*entity.ScheduledMessage, error)
+	// Release puts a claimed message back to pending when its delivery failed
+	Release(ctx context.Context, id int64) error
+	// Deliver creates the chat message of a claimed message and marks it delivered in one transaction.
+	// ErrScheduledMessageNotFound is returned, and nothing created, when it is no longer processing.
+	Deliver(ctx context.Context, id int64, message *entity.ChatMessage) error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// ErrSessionNotFound is returned when no session matches the ID
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository defines session data access interface
+type SessionRepository interface {
+	FindByID(ctx context.Context, id string) (*entity.Session, error)
+	FindActiveByUserID(ctx context.Context, userID int64) ([]*entity.Session, error)
+	Create(ctx context.Context, session *entity.Session) error
+	Touch(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
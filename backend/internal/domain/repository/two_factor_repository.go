@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+var (
+	// ErrTwoFactorNotFound is returned when the user has no TOTP secret
+	ErrTwoFactorNotFound = errors.New("two-factor secret not found")
+	// ErrTOTPStepUsed is returned when a TOTP time step was already accepted
+	ErrTOTPStepUsed = errors.New("totp code already used")
+	// ErrRecoveryCodeNotFound is returned when a recovery code is unknown or used
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+)
+
+// TwoFactorRepository defines TOTP secret and recovery code data access interface
+type TwoFactorRepository interface {
+	FindByUserID(ctx context.Context, userID int64) (*entity.UserTOTP, error)
+	// SaveSecret stores a new pending secret, unless 2FA is already enabled
+	SaveSecret(ctx context.Context, userID int64, secret string) error
+	// Enable activates the pending secret and replaces the recovery codes
+	Enable(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error
+	// UseStep records an accepted time step, failing if it is not newer than the last one
+	UseStep(ctx context.Context, userID, step int64) error
+	// UseRecoveryCode marks an unused recovery code as used
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	Delete(ctx context.Context, userID int64) error
+}
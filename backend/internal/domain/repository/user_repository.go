@@ -7,9 +7,15 @@ import (
 
 // UserRepository defines user data access interface
 type UserRepository interface {
+	FindAll(ctx context.Context) ([]*entity.User, error)
 	FindByID(ctx context.Context, id int64) (*entity.User, error)
 	FindByEmail(ctx context.Context, email string) (*entity.User, error)
 	FindByUsername(ctx context.Context, username string) (*entity.User, error)
 	Create(ctx context.Context, user *entity.User) error
 	Update(ctx context.Context, user *entity.User) error
+	// UpdateRole and SetDisabled only write their own column, for admin changes
+	UpdateRole(ctx context.Context, id int64, role entity.UserRole) error
+	SetDisabled(ctx context.Context, id int64, disabled bool) error
+	// FindCommonPasswordHash returns a stored hash in the most common algorithm and cost
+	FindCommonPasswordHash(ctx context.Context) (string, error)
 }
@@ -1,70 +1,195 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthService handles authentication logic
 type AuthService struct {
 	jwtSecret []byte
+	keys      *KeySet // optional asymmetric signing keys, HS256 with jwtSecret is used without them
+	hasher    *PasswordHasher
+
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-// NewAuthService creates a new auth service
+// NewAuthService creates a new auth service that signs tokens with HS256
 func NewAuthService(jwtSecret string) *AuthService {
 	return &AuthService{
 		jwtSecret: []byte(jwtSecret),
+		hasher:    defaultPasswordHasher(),
+	}
+}
+
+// NewAuthServiceWithKeys creates an auth service that signs tokens with the active key of keys.
+// Tokens without a kid are still verified with jwtSecret (HS256) while old tokens expire;
+// pass an empty secret to reject them.
+func NewAuthServiceWithKeys(jwtSecret string, keys *KeySet) *AuthService {
+	return &AuthService{
+		jwtSecret: []byte(jwtSecret),
+		keys:      keys,
+		hasher:    defaultPasswordHasher(),
 	}
 }
 
+// WithPasswordHasher replaces the default bcrypt (cost 10) hasher used for new password hashes
+func (s *AuthService) WithPasswordHasher(hasher *PasswordHasher) *AuthService {
+	s.hasher = hasher
+	return s
+}
+
+func defaultPasswordHasher() *PasswordHasher {
+	hasher, _ := NewPasswordHasher(HashAlgorithmBcrypt, DefaultBcryptCost, DefaultArgon2Params)
+	return hasher
+}
+
+// Token types stored in the token_type claim
+const (
+	TokenTypeAccess             = "access"
+	TokenTypeRefresh            = "refresh"
+	TokenTypeTwoFactorChallenge = "2fa_challenge"
+	// TokenTypePersonalAccess marks claims built from a personal access token, these are never signed
+	TokenTypePersonalAccess = "personal_access"
+)
+
+const (
+	// AccessTokenTTL is the lifetime of access tokens
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is the lifetime of refresh tokens
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	// TwoFactorChallengeTTL is how long a login can wait for the second factor
+	TwoFactorChallengeTTL = 5 * time.Minute
+	// PasswordResetTTL is how long an emailed password reset link stays valid
+	PasswordResetTTL = time.Hour
+)
+
+// ErrWrongTokenType is returned when a token is used for the wrong purpose
+var ErrWrongTokenType = errors.New("wrong token type")
+
 // Claims represents JWT claims
 type Claims struct {
-	UserID   int64  `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID    int64    `json:"user_id"`
+	Username  string   `json:"username"`
+	Role      string   `json:"role"`
+	TokenType string   `json:"token_type"`
+	SessionID string   `json:"sid,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"` // only set for personal access tokens
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token
-func (s *AuthService) GenerateToken(userID int64, username, role string) (string, error) {
+// GenerateToken generates a JWT access token bound to the given session
+func (s *AuthService) GenerateToken(userID int64, username, role, sessionID string) (string, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeAccess,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)), // 15 minutes for security
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)), // 15 minutes for security
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.sign(claims)
 }
 
-// GenerateRefreshToken generates a refresh token with longer expiration
-func (s *AuthService) GenerateRefreshToken(userID int64, username, role string) (string, error) {
+// GenerateRefreshToken generates a refresh token with longer expiration.
+// The jti identifies the persisted refresh token record.
+func (s *AuthService) GenerateRefreshToken(userID int64, username, role, jti string) (string, error) {
 	claims := &Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)), // 7 days
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenTTL)), // 7 days
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.sign(claims)
+}
+
+// GenerateChallengeToken generates a short-lived token proving the password was checked.
+// It can only be exchanged for access and refresh tokens together with a valid 2FA code.
+func (s *AuthService) GenerateChallengeToken(userID int64, username, role string) (string, error) {
+	claims := &Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeTwoFactorChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TwoFactorChallengeTTL)), // 5 minutes
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return s.sign(claims)
+}
+
+// sign signs claims with the active key, or with the HS256 secret when no keys are configured
+func (s *AuthService) sign(claims *Claims) (string, error) {
+	if s.keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(s.jwtSecret)
+	}
+
+	key := s.keys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// verificationKey picks the key for a token by its kid header.
+// Tokens without a kid are HS256 tokens signed with the shared secret.
+func (s *AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, hasKID := token.Header["kid"].(string)
+	if !hasKID {
+		if token.Method != jwt.SigningMethodHS256 || len(s.jwtSecret) == 0 {
+			return nil, errors.New("token has no key id")
+		}
+		return s.jwtSecret, nil
+	}
+
+	if s.keys == nil {
+		return nil, ErrUnknownKeyID
+	}
+	key, err := s.keys.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm must match the key, otherwise a public key could be abused as an HMAC secret
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("key %s does not sign with %s", kid, token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// JWKS returns the public verification keys, empty when only HS256 is used
+func (s *AuthService) JWKS() JWKS {
+	if s.keys == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return s.keys.JWKS()
 }
 
 // ValidateToken validates a JWT token
 func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtSecret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.verificationKey,
+		jwt.WithValidMethods([]string{"HS256", "EdDSA", "RS256"}),
+	)
 
 	if err != nil {
 		return nil, err
@@ -77,14 +202,88 @@ func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// HashPassword hashes a password using bcrypt
+// ValidateAccessToken validates a JWT and ensures it is an access token
+func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return s.validateTokenType(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a JWT and ensures it is a refresh token with a JTI
+func (s *AuthService) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	claims, err := s.validateTokenType(tokenString, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("refresh token has no jti")
+	}
+	return claims, nil
+}
+
+// ValidateChallengeToken validates a JWT and ensures it is a 2FA challenge token
+func (s *AuthService) ValidateChallengeToken(tokenString string) (*Claims, error) {
+	return s.validateTokenType(tokenString, TokenTypeTwoFactorChallenge)
+}
+
+func (s *AuthService) validateTokenType(tokenString, tokenType string) (*Claims, error) {
+	claims, err := s.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+// NewTokenID generates a random identifier for token IDs and families
+func NewTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// HashToken returns the SHA-256 hex digest used to store random tokens.
+// The tokens are random enough that a fast hash is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPassword hashes a password with the configured algorithm
 func (s *AuthService) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
-	return string(bytes), err
+	return s.hasher.Hash(password)
 }
 
-// CheckPassword checks if a password matches the hash
+// CheckPassword checks if a password matches the hash, whatever format the hash is in
 func (s *AuthService) CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return s.hasher.Verify(password, hash)
+}
+
+// PasswordNeedsRehash reports whether hash should be replaced by a hash with the current settings
+func (s *AuthService) PasswordNeedsRehash(hash string) bool {
+	return s.hasher.NeedsRehash(hash)
+}
+
+// MatchDummyPasswordHash makes DummyCheckPassword use the format and cost of like,
+// which should be the most common stored hash. Without it the configured hasher is
+// used, and stored hashes in an older format would take a different time to check.
+func (s *AuthService) MatchDummyPasswordHash(like string) error {
+	hash, err := s.hasher.HashLike("dummy-password", like)
+	if err != nil {
+		return err
+	}
+	s.dummyHashOnce.Do(func() {})
+	s.dummyHash = hash
+	return nil
+}
+
+// DummyCheckPassword takes as long as CheckPassword but always fails.
+// Used for unknown accounts so response timing does not reveal which emails exist.
+func (s *AuthService) DummyCheckPassword(password string) {
+	s.dummyHashOnce.Do(func() {
+		s.dummyHash, _ = s.hasher.Hash("dummy-password")
+	})
+	s.hasher.Verify(password, s.dummyHash)
 }
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthService_TokenTypes(t *testing.T) {
+	svc := NewAuthService("test-secret")
+
+	access, err := svc.GenerateToken(1, "irfan", "super_admin", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jti, err := NewTokenID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := svc.GenerateRefreshToken(1, "irfan", "super_admin", jti)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := svc.ValidateAccessToken(access)
+	if err != nil {
+		t.Fatalf("access token rejected: %v", err)
+	}
+	if claims.TokenType != TokenTypeAccess || claims.UserID != 1 || claims.SessionID != "session-1" {
+		t.Errorf("unexpected access claims %+v", claims)
+	}
+
+	claims, err = svc.ValidateRefreshToken(refresh)
+	if err != nil {
+		t.Fatalf("refresh token rejected: %v", err)
+	}
+	if claims.ID != jti {
+		t.Errorf("expected jti %q, got %q", jti, claims.ID)
+	}
+
+	if _, err := svc.ValidateRefreshToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("access token must not be accepted as refresh token, got %v", err)
+	}
+	if _, err := svc.ValidateAccessToken(refresh); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("refresh token must not be accepted as access token, got %v", err)
+	}
+}
+
+func TestAuthService_RejectsForeignSignature(t *testing.T) {
+	token, err := NewAuthService("other-secret").GenerateToken(1, "irfan", "super_admin", "session-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAuthService("test-secret").ValidateAccessToken(token); err == nil {
+		t.Error("expected token signed with another secret to be rejected")
+	}
+}
+
+func TestAuthService_ChallengeTokenIsNotAnAccessToken(t *testing.T) {
+	svc := NewAuthService("test-secret")
+
+	challenge, err := svc.GenerateChallengeToken(1, "irfan", "super_admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.ValidateChallengeToken(challenge); err != nil {
+		t.Errorf("challenge token rejected: %v", err)
+	}
+	if _, err := svc.ValidateAccessToken(challenge); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("challenge token must not be accepted as access token, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+var (
+	// ErrNoPartner is returned when a user has not been paired yet
+	ErrNoPartner = errors.New("user has no partner")
+	// ErrAlreadyPaired is returned when pairing a user that already has a partner
+	ErrAlreadyPaired = errors.New("user already has a partner")
+	// ErrSelfPairing is returned when a user tries to pair with themselves
+	ErrSelfPairing = errors.New("cannot pair with yourself")
+	// ErrInvalidInvite is returned when an invite code is unknown, expired or already used
+	ErrInvalidInvite = errors.New("invalid or expired invite code")
+	// ErrInvalidMessageTTL is returned for a disappearing message timer that is not one of MessageTTLOptions
+	ErrInvalidMessageTTL = errors.New("invalid disappearing message timer")
+)
+
+// MessageTTLOptions are the disappearing message timers a couple can choose from
+var MessageTTLOptions = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour}
+
+const (
+	// InviteCodeTTL is how long an invite code can be redeemed
+	InviteCodeTTL = 30 * time.Minute
+
+	// inviteCodeLength is the number of characters in an invite code
+	inviteCodeLength = 8
+
+	// inviteCodeAlphabet leaves out characters that are easy to confuse (0/O, 1/I/L)
+	inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+// CoupleService resolves partners and manages pairings
+type CoupleService struct {
+	coupleRepo repository.CoupleRepository
+	inviteRepo repository.CoupleInviteRepository
+}
+
+// NewCoupleService creates a new couple service
+func NewCoupleService(coupleRepo repository.CoupleRepository, inviteRepo repository.CoupleInviteRepository) *CoupleService {
+	return &CoupleService{
+		coupleRepo: coupleRepo,
+		inviteRepo: inviteRepo,
+	}
+}
+
+// CoupleOf returns the couple the user belongs to
+func (s *CoupleService) CoupleOf(ctx context.Context, userID int64) (*entity.Couple, error) {
+	couple, err := s.coupleRepo.FindByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrCoupleNotFound) {
+		return nil, ErrNoPartner
+	}
+	if err != nil {
+		return nil, err
+	}
+	return couple, nil
+}
+
+// PartnerID returns the ID of the user's partner
+func (s *CoupleService) PartnerID(ctx context.Context, userID int64) (int64, error) {
+	couple, err := s.CoupleOf(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return couple.PartnerOf(userID), nil
+}
+
+// Pair links two unpaired users as a couple
+func (s *CoupleService) Pair(ctx context.Context, user1ID, user2ID int64) (*entity.Couple, error) {
+	if user1ID == user2ID {
+		return nil, ErrSelfPairing
+	}
+
+	for _, userID := range []int64{user1ID, user2ID} {
+		if err := s.ensureUnpaired(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The check above can race with another pairing, the repository has the final say
+	couple := &entity.Couple{User1ID: user1ID, User2ID: user2ID}
+	if err := s.coupleRepo.Create(ctx, couple); err != nil {
+		if errors.Is(err, repository.ErrCoupleMemberExists) {
+			return nil, ErrAlreadyPaired
+		}
+		return nil, err
+	}
+	return couple, nil
+}
+
+// CreateInvite generates a new invite code for an unpaired user.
+// Any previous pending code of the user is invalidated.
+func (s *CoupleService) CreateInvite(ctx context.Context, userID int64) (*entity.CoupleInvite, error) {
+	if err := s.ensureUnpaired(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.inviteRepo.DeletePendingByInviter(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &entity.CoupleInvite{
+		Code:      code,
+		InviterID: userID,
+		ExpiresAt: time.Now().Add(InviteCodeTTL),
+	}
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// RedeemInvite pairs the user with the owner of the invite code
+func (s *CoupleService) RedeemInvite(ctx context.Context, userID int64, code string) (*entity.Couple, error) {
+	invite, err := s.inviteRepo.FindByCode(ctx, NormalizeInviteCode(code))
+	if errors.Is(err, repository.ErrInviteNotFound) {
+		return nil, ErrInvalidInvite
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !invite.IsUsable(time.Now()) {
+		return nil, ErrInvalidInvite
+	}
+	if invite.InviterID == userID {
+		return nil, ErrSelfPairing
+	}
+
+	// Pairing first keeps the code usable when it fails. Only one redeemer can pair
+	// with the inviter, so a losing redeemer gets ErrAlreadyPaired.
+	couple, err := s.Pair(ctx, invite.InviterID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The inviter is paired now, so a code left unmarked can no longer be redeemed
+	if err := s.inviteRepo.MarkRedeemed(ctx, invite.ID, userID); err != nil {
+		log.Printf("Failed to mark invite %d as redeemed: %v", invite.ID, err)
+	}
+	return couple, nil
+}
+
+// SetMessageTTL sets the disappearing message timer of the user's couple, 0 turns it off.
+// It reports whether the timer changed.
+func (s *CoupleService) SetMessageTTL(ctx context.Context, userID int64, ttl time.Duration) (*entity.Couple, bool, error) {
+	if !IsValidMessageTTL(ttl) {
+		return nil, false, ErrInvalidMessageTTL
+	}
+
+	couple, err := s.CoupleOf(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	seconds := int64(ttl / time.Second)
+	if couple.MessageTTL == seconds {
+		return couple, false, nil
+	}
+	if err := s.coupleRepo.UpdateMessageTTL(ctx, couple.ID, seconds); err != nil {
+		return nil, false, err
+	}
+	couple.MessageTTL = seconds
+	return couple, true, nil
+}
+
+// IsValidMessageTTL checks if ttl is 0 or one of MessageTTLOptions
+func IsValidMessageTTL(ttl time.Duration) bool {
+	if ttl == 0 {
+		return true
+	}
+	for _, option := range MessageTTLOptions {
+		if ttl == option {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeInviteCode uppercases the code and strips separators users may type
+func NormalizeInviteCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *CoupleService) ensureUnpaired(ctx context.Context, userID int64) error {
+	_, err := s.coupleRepo.FindByUserID(ctx, userID)
+	if err == nil {
+		return ErrAlreadyPaired
+	}
+	if !errors.Is(err, repository.ErrCoupleNotFound) {
+		return err
+	}
+	return nil
+}
+
+func generateInviteCode() (string, error) {
+	return randomCode(inviteCodeLength)
+}
+
+// randomCode returns a random code of the given length drawn from inviteCodeAlphabet
+func randomCode(length int) (string, error) {
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakeCoupleRepo is an in-memory CoupleRepository
+type fakeCoupleRepo struct {
+	couples []*entity.Couple
+}
+
+func (f *fakeCoupleRepo) FindByID(ctx context.Context, id int64) (*entity.Couple, error) {
+	for _, c := range f.couples {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrCoupleNotFound
+}
+
+func (f *fakeCoupleRepo) FindByUserID(ctx context.Context, userID int64) (*entity.Couple, error) {
+	for _, c := range f.couples {
+		if c.Has(userID) {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrCoupleNotFound
+}
+
+func (f *fakeCoupleRepo) Create(ctx context.Context, couple *entity.Couple) error {
+	for _, c := range f.couples {
+		if c.Has(couple.User1ID) || c.Has(couple.User2ID) {
+			return repository.ErrCoupleMemberExists
+		}
+	}
+	couple.ID = int64(len(f.couples) + 1)
+	f.couples = append(f.couples, couple)
+	return nil
+}
+
+func (f *fakeCoupleRepo) UpdateMessageTTL(ctx context.Context, id int64, ttl int64) error {
+	couple, err := f.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	couple.MessageTTL = ttl
+	return nil
+}
+
+func (f *fakeCoupleRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+// fakeInviteRepo is an in-memory CoupleInviteRepository
+type fakeInviteRepo struct {
+	invites []*entity.CoupleInvite
+}
+
+func (f *fakeInviteRepo) FindByCode(ctx context.Context, code string) (*entity.CoupleInvite, error) {
+	for _, i := range f.invites {
+		if i.Code == code {
+			return i, nil
+		}
+	}
+	return nil, repository.ErrInviteNotFound
+}
+
+func (f *fakeInviteRepo) Create(ctx context.Context, invite *entity.CoupleInvite) error {
+	invite.ID = int64(len(f.invites) + 1)
+	f.invites = append(f.invites, invite)
+	return nil
+}
+
+func (f *fakeInviteRepo) MarkRedeemed(ctx context.Context, id, userID int64) error {
+	for _, i := range f.invites {
+		if i.ID == id && i.RedeemedAt == nil {
+			now := time.Now()
+			i.RedeemedBy, i.RedeemedAt = &userID, &now
+			return nil
+		}
+	}
+	return errors.New("invite already redeemed")
+}
+
+func (f *fakeInviteRepo) DeletePendingByInviter(ctx context.Context, inviterID int64) error {
+	kept := f.invites[:0]
+	for _, i := range f.invites {
+		if i.InviterID != inviterID || i.RedeemedAt != nil {
+			kept = append(kept, i)
+		}
+	}
+	f.invites = kept
+	return nil
+}
+
+func TestCoupleService_PartnerID(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCoupleService(&fakeCoupleRepo{}, &fakeInviteRepo{})
+
+	if _, err := svc.Pair(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Pair(ctx, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userID  int64
+		partner int64
+		err     error
+	}{
+		{userID: 1, partner: 2},
+		{userID: 2, partner: 1},
+		{userID: 3, partner: 4},
+		{userID: 4, partner: 3},
+		{userID: 5, err: ErrNoPartner},
+	}
+
+	for _, tt := range tests {
+		partner, err := svc.PartnerID(ctx, tt.userID)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("user %d: expected error %v, got %v", tt.userID, tt.err, err)
+		}
+		if partner != tt.partner {
+			t.Errorf("user %d: expected partner %d, got %d", tt.userID, tt.partner, partner)
+		}
+	}
+}
+
+func TestCoupleService_PairRejectsInvalidPairs(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCoupleService(&fakeCoupleRepo{}, &fakeInviteRepo{})
+
+	if _, err := svc.Pair(ctx, 1, 1); !errors.Is(err, ErrSelfPairing) {
+		t.Errorf("expected ErrSelfPairing, got %v", err)
+	}
+
+	if _, err := svc.Pair(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Pair(ctx, 2, 3); !errors.Is(err, ErrAlreadyPaired) {
+		t.Errorf("expected ErrAlreadyPaired, got %v", err)
+	}
+}
+
+func TestCoupleService_RedeemInvite(t *testing.T) {
+	ctx := context.Background()
+	invites := &fakeInviteRepo{}
+	svc := NewCoupleService(&fakeCoupleRepo{}, invites)
+
+	invite, err := svc.CreateInvite(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invite.Code) != inviteCodeLength {
+		t.Fatalf("expected %d character code, got %q", inviteCodeLength, invite.Code)
+	}
+
+	if _, err := svc.RedeemInvite(ctx, 1, invite.Code); !errors.Is(err, ErrSelfPairing) {
+		t.Errorf("expected ErrSelfPairing, got %v", err)
+	}
+	if _, err := svc.RedeemInvite(ctx, 2, "NOPE1234"); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("expected ErrInvalidInvite for unknown code, got %v", err)
+	}
+
+	// Codes are accepted in lowercase and with separators
+	lower := strings.ToLower(invite.Code[:4]) + "-" + strings.ToLower(invite.Code[4:])
+	couple, err := svc.RedeemInvite(ctx, 2, lower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if couple.PartnerOf(2) != 1 {
+		t.Errorf("expected user 2 to be paired with user 1, got %+v", couple)
+	}
+
+	if _, err := svc.RedeemInvite(ctx, 3, invite.Code); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("expected used code to be rejected, got %v", err)
+	}
+	if _, err := svc.CreateInvite(ctx, 1); !errors.Is(err, ErrAlreadyPaired) {
+		t.Errorf("expected paired user to be unable to invite, got %v", err)
+	}
+}
+
+func TestCoupleService_RedeemExpiredInvite(t *testing.T) {
+	ctx := context.Background()
+	invites := &fakeInviteRepo{}
+	svc := NewCoupleService(&fakeCoupleRepo{}, invites)
+
+	invite, err := svc.CreateInvite(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite.ExpiresAt = time.Now().Add(-time.Minute)
+
+	if _, err := svc.RedeemInvite(ctx, 2, invite.Code); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("expected ErrInvalidInvite for expired code, got %v", err)
+	}
+}
+
+func TestCoupleService_RedeemInviteKeepsCodeWhenPairingFails(t *testing.T) {
+	ctx := context.Background()
+	invites := &fakeInviteRepo{}
+	couples := &stalePairCheckRepo{}
+	svc := NewCoupleService(couples, invites)
+
+	invite, err := svc.CreateInvite(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// User 2 pairs with someone else after the checks of the redeem already passed
+	couples.couples = append(couples.couples, &entity.Couple{ID: 1, User1ID: 2, User2ID: 9})
+	if _, err := svc.RedeemInvite(ctx, 2, invite.Code); !errors.Is(err, ErrAlreadyPaired) {
+		t.Fatalf("expected ErrAlreadyPaired, got %v", err)
+	}
+	if invite.RedeemedAt != nil {
+		t.Error("expected the code to stay usable when pairing failed")
+	}
+
+	if _, err := svc.RedeemInvite(ctx, 3, invite.Code); err != nil {
+		t.Errorf("expected another user to redeem the code, got %v", err)
+	}
+	if invite.RedeemedAt == nil || *invite.RedeemedBy != 3 {
+		t.Errorf("expected the code to be redeemed by user 3, got %+v", invite)
+	}
+}
+
+func TestCoupleService_SetMessageTTL(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCoupleService(&fakeCoupleRepo{}, &fakeInviteRepo{})
+
+	if _, _, err := svc.SetMessageTTL(ctx, 1, time.Hour); !errors.Is(err, ErrNoPartner) {
+		t.Errorf("expected ErrNoPartner, got %v", err)
+	}
+	if _, err := svc.Pair(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.SetMessageTTL(ctx, 1, 5*time.Minute); !errors.Is(err, ErrInvalidMessageTTL) {
+		t.Errorf("expected ErrInvalidMessageTTL, got %v", err)
+	}
+
+	couple, changed, err := svc.SetMessageTTL(ctx, 1, 24*time.Hour)
+	if err != nil || !changed || couple.MessageTTL != 86400 {
+		t.Fatalf("expected the timer to be set to a day, got %+v changed=%v (err %v)", couple, changed, err)
+	}
+
+	// Either partner sees and can change the same setting
+	if couple, _ := svc.CoupleOf(ctx, 2); couple.MessageTTL != 86400 {
+		t.Errorf("expected the partner to share the timer, got %d", couple.MessageTTL)
+	}
+	if _, changed, _ := svc.SetMessageTTL(ctx, 2, 24*time.Hour); changed {
+		t.Error("expected setting the same timer to be no change")
+	}
+	if couple, changed, err := svc.SetMessageTTL(ctx, 2, 0); err != nil || !changed || couple.MessageExpiry(time.Now()) != nil {
+		t.Errorf("expected the partner to turn the timer off, got %+v changed=%v (err %v)", couple, changed, err)
+	}
+}
+
+// stalePairCheckRepo misses existing couples on lookup, like a pairing that raced with another one
+type stalePairCheckRepo struct {
+	fakeCoupleRepo
+}
+
+func (f *stalePairCheckRepo) FindByUserID(ctx context.Context, userID int64) (*entity.Couple, error) {
+	return nil, repository.ErrCoupleNotFound
+}
+
+func TestCoupleService_ConcurrentPairing(t *testing.T) {
+	ctx := context.Background()
+	svc := NewCoupleService(&stalePairCheckRepo{}, &fakeInviteRepo{})
+
+	if _, err := svc.Pair(ctx, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Pair(ctx, 3, 1); !errors.Is(err, ErrAlreadyPaired) {
+		t.Errorf("expected ErrAlreadyPaired when the repository rejects the pairing, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores a PEM encoded private (or public, when public is true) key as dir/kid.pem
+func writeKey(t *testing.T, dir, kid string, key interface{}, public bool) {
+	t.Helper()
+
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeySet_SignsWithKIDAndVerifiesAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2026-09", newEd25519Key(t), false)
+
+	oldKeys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := NewAuthServiceWithKeys("", oldKeys).GenerateToken(1, "irfan", "super_admin", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != "2026-09" || parsed.Header["alg"] != "EdDSA" {
+		t.Errorf("unexpected token header %v", parsed.Header)
+	}
+
+	// Rotate: add an RSA key and make it active, the old key keeps verifying
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "2026-10", rsaKey, false)
+
+	if _, err := LoadKeySet(dir, ""); err == nil {
+		t.Error("expected an error when the active key is ambiguous")
+	}
+	keys, err := LoadKeySet(dir, "2026-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthServiceWithKeys("", keys)
+
+	if _, err := svc.ValidateAccessToken(oldToken); err != nil {
+		t.Errorf("token signed with the previous key rejected: %v", err)
+	}
+
+	newToken, err := svc.GenerateToken(1, "irfan", "super_admin", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ValidateAccessToken(newToken); err != nil {
+		t.Errorf("token signed with the active key rejected: %v", err)
+	}
+	if _, err := NewAuthServiceWithKeys("", oldKeys).ValidateAccessToken(newToken); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("expected unknown kid to be rejected, got %v", err)
+	}
+}
+
+func TestKeySet_PublicOnlyKeysVerifyButCannotSign(t *testing.T) {
+	dir := t.TempDir()
+	retired := newEd25519Key(t)
+	writeKey(t, dir, "retired", retired.Public(), true)
+	writeKey(t, dir, "current", newEd25519Key(t), false)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys.Active().ID != "current" {
+		t.Errorf("expected the only private key to be active, got %s", keys.Active().ID)
+	}
+	if _, err := LoadKeySet(dir, "retired"); err == nil {
+		t.Error("expected a public-only key to be rejected as active key")
+	}
+
+	// A token signed by the retired key before it was retired is still accepted
+	retiredKeys, err := NewKeySet("retired", &SigningKey{ID: "retired", Method: jwt.SigningMethodEdDSA, Private: retired, Public: retired.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := NewAuthServiceWithKeys("", retiredKeys).GenerateToken(2, "sisti", "user", "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAuthServiceWithKeys("", keys).ValidateAccessToken(token); err != nil {
+		t.Errorf("token signed with retired key rejected: %v", err)
+	}
+}
+
+func TestAuthService_HS256Fallback(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "k1", newEd25519Key(t), false)
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacy, err := NewAuthService("test-secret").GenerateToken(1, "irfan", "super_admin", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAuthServiceWithKeys("test-secret", keys).ValidateAccessToken(legacy); err != nil {
+		t.Errorf("HS256 token rejected during migration: %v", err)
+	}
+	if _, err := NewAuthServiceWithKeys("", keys).ValidateAccessToken(legacy); err == nil {
+		t.Error("expected HS256 token to be rejected once the secret is removed")
+	}
+}
+
+func TestAuthService_RejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "rsa", rsaKey, false)
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// HS256 token using the public RSA key as HMAC secret, pointing at the RSA kid
+	pub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 1, TokenType: TokenTypeAccess})
+	forged.Header["kid"] = "rsa"
+	tokenString, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewAuthServiceWithKeys("test-secret", keys).ValidateAccessToken(tokenString); err == nil {
+		t.Error("expected HS256 token with an RSA kid to be rejected")
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	dir := t.TempDir()
+	edKey := newEd25519Key(t)
+	writeKey(t, dir, "a-ed", edKey, false)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "b-rsa", rsaKey.Public(), true)
+
+	keys, err := LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", jwks.Keys)
+	}
+	ed, rs := jwks.Keys[0], jwks.Keys[1]
+	if ed.Kid != "a-ed" || ed.Kty != "OKP" || ed.Crv != "Ed25519" || ed.Alg != "EdDSA" || ed.X == "" {
+		t.Errorf("unexpected Ed25519 JWK %+v", ed)
+	}
+	if rs.Kid != "b-rsa" || rs.Kty != "RSA" || rs.Alg != "RS256" || rs.E != "AQAB" || rs.N == "" {
+		t.Errorf("unexpected RSA JWK %+v", rs)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// LoginBackoffBase is the delay after the first failure past the free attempts, doubled per failure
+	LoginBackoffBase = time.Second
+	// LoginLockoutDuration is how long an account or IP is locked once it hits the lockout threshold
+	LoginLockoutDuration = 15 * time.Minute
+
+	// loginFailureWindow is how long failures are remembered after the last one
+	loginFailureWindow = time.Hour
+	// maxTrackedKeys triggers a sweep of forgotten entries
+	maxTrackedKeys = 10000
+)
+
+// throttleLimits defines when backoff and lockout kick in for one kind of key
+type throttleLimits struct {
+	freeAttempts    int // failures allowed before backoff starts
+	lockoutAttempts int // failures that lock the key for LoginLockoutDuration
+}
+
+var (
+	accountLimits = throttleLimits{freeAttempts: 3, lockoutAttempts: 10}
+	// An IP may serve several users (NAT, mobile carriers), so it gets more room
+	ipLimits = throttleLimits{freeAttempts: 10, lockoutAttempts: 50}
+)
+
+type attemptState struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// LoginThrottle tracks failed login attempts per account and per IP in memory
+// and applies exponential backoff followed by a temporary lockout
+type LoginThrottle struct {
+	mu       sync.Mutex
+	accounts map[string]*attemptState
+	ips      map[string]*attemptState
+	now      func() time.Time
+}
+
+// NewLoginThrottle creates an empty login throttle
+func NewLoginThrottle() *LoginThrottle {
+	return &LoginThrottle{
+		accounts: make(map[string]*attemptState),
+		ips:      make(map[string]*attemptState),
+		now:      time.Now,
+	}
+}
+
+// Check returns how long the caller must wait before the next attempt, or 0 if it may proceed
+func (t *LoginThrottle) Check(account, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	wait := remaining(t.accounts[account], now)
+	if ipWait := remaining(t.ips[ip], now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// Failure records a failed attempt and reports whether it locked the account
+func (t *LoginThrottle) Failure(account, ip string) (accountLocked bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	accountLocked = recordFailure(t.accounts, account, accountLimits, now)
+	recordFailure(t.ips, ip, ipLimits, now)
+	return accountLocked
+}
+
+// Success clears the failures of the account. IP failures are kept, so one valid
+// account cannot be used to reset the counter while guessing others.
+func (t *LoginThrottle) Success(account string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.accounts, account)
+}
+
+func remaining(state *attemptState, now time.Time) time.Duration {
+	if state == nil || !now.Before(state.blockedUntil) {
+		return 0
+	}
+	return state.blockedUntil.Sub(now)
+}
+
+// recordFailure updates the state of key and reports whether it is now locked out
+func recordFailure(states map[string]*attemptState, key string, limits throttleLimits, now time.Time) bool {
+	if len(states) > maxTrackedKeys {
+		sweep(states, now)
+	}
+
+	state, ok := states[key]
+	if !ok || now.Sub(state.lastFailure) > loginFailureWindow {
+		state = &attemptState{}
+		states[key] = state
+	}
+
+	state.failures++
+	state.lastFailure = now
+
+	switch {
+	case state.failures >= limits.lockoutAttempts:
+		// Every failure after the threshold starts a new lockout
+		state.blockedUntil = now.Add(LoginLockoutDuration)
+		return true
+	case state.failures > limits.freeAttempts:
+		delay := LoginBackoffBase << (state.failures - limits.freeAttempts - 1)
+		if delay > LoginLockoutDuration {
+			delay = LoginLockoutDuration
+		}
+		state.blockedUntil = now.Add(delay)
+	}
+	return false
+}
+
+// sweep removes entries whose failures have been forgotten
+func sweep(states map[string]*attemptState, now time.Time) {
+	for key, state := range states {
+		if now.Sub(state.lastFailure) > loginFailureWindow && !now.Before(state.blockedUntil) {
+			delete(states, key)
+		}
+	}
+}
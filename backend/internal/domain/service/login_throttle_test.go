@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move time forward
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestThrottle() (*LoginThrottle, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	throttle := NewLoginThrottle()
+	throttle.now = clock.now
+	return throttle, clock
+}
+
+func TestLoginThrottle_ExponentialBackoff(t *testing.T) {
+	throttle, clock := newTestThrottle()
+
+	// The first failures are free
+	for i := 0; i < accountLimits.freeAttempts; i++ {
+		throttle.Failure("sisti@fasisi.com", "10.0.0.1")
+		if wait := throttle.Check("sisti@fasisi.com", "10.0.0.1"); wait != 0 {
+			t.Fatalf("failure %d: expected no wait, got %v", i+1, wait)
+		}
+	}
+
+	// Then each failure doubles the delay
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		throttle.Failure("sisti@fasisi.com", "10.0.0.1")
+		if wait := throttle.Check("sisti@fasisi.com", "10.0.0.1"); wait != want {
+			t.Fatalf("expected wait %v, got %v", want, wait)
+		}
+		clock.advance(want)
+	}
+
+	if wait := throttle.Check("irfan@fasisi.com", "10.0.0.2"); wait != 0 {
+		t.Errorf("other accounts and IPs must not be throttled, got %v", wait)
+	}
+}
+
+func TestLoginThrottle_LockoutAndSuccess(t *testing.T) {
+	throttle, clock := newTestThrottle()
+
+	locked := false
+	for i := 0; i < accountLimits.lockoutAttempts; i++ {
+		// Wait out the backoff like a patient attacker would
+		clock.advance(throttle.Check("sisti@fasisi.com", "10.0.0.1"))
+
+		locked = throttle.Failure("sisti@fasisi.com", "10.0.0.1")
+		if locked && i < accountLimits.lockoutAttempts-1 {
+			t.Fatalf("account locked early at failure %d", i+1)
+		}
+	}
+	if !locked {
+		t.Fatal("expected account to be locked")
+	}
+	if wait := throttle.Check("sisti@fasisi.com", "10.0.0.9"); wait != LoginLockoutDuration {
+		t.Errorf("expected lockout from any IP for %v, got %v", LoginLockoutDuration, wait)
+	}
+
+	clock.advance(LoginLockoutDuration)
+	if wait := throttle.Check("sisti@fasisi.com", "10.0.0.9"); wait != 0 {
+		t.Errorf("expected lockout to expire, got %v", wait)
+	}
+
+	throttle.Success("sisti@fasisi.com")
+	throttle.Failure("sisti@fasisi.com", "10.0.0.9")
+	if wait := throttle.Check("sisti@fasisi.com", "10.0.0.9"); wait != 0 {
+		t.Errorf("expected success to reset the account counter, got %v", wait)
+	}
+}
+
+func TestLoginThrottle_PerIP(t *testing.T) {
+	throttle, clock := newTestThrottle()
+
+	// Spraying many accounts from one IP eventually blocks the IP
+	for i := 0; i < ipLimits.lockoutAttempts; i++ {
+		throttle.Failure(fmt.Sprintf("user%d@fasisi.com", i), "10.0.0.1")
+		clock.advance(time.Millisecond)
+	}
+
+	if wait := throttle.Check("new@fasisi.com", "10.0.0.1"); wait == 0 {
+		t.Error("expected IP to be blocked")
+	}
+	if wait := throttle.Check("new@fasisi.com", "10.0.0.2"); wait != 0 {
+		t.Errorf("expected other IPs to be unaffected, got %v", wait)
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+const (
+	// reaperInterval is how often expired disappearing messages are deleted
+	reaperInterval = time.Minute
+
+	// reaperBatchSize is the number of expired messages deleted at once
+	reaperBatchSize = 100
+)
+
+// MessageReaper hard-deletes disappearing chat messages once they expired
+type MessageReaper struct {
+	chatRepo  repository.ChatRepository
+	onDeleted func(*entity.ChatMessage)
+	now       func() time.Time
+}
+
+// NewMessageReaper creates a new message reaper
+func NewMessageReaper(chatRepo repository.ChatRepository) *MessageReaper {
+	return &MessageReaper{
+		chatRepo:  chatRepo,
+		onDeleted: func(*entity.ChatMessage) {},
+		now:       time.Now,
+	}
+}
+
+// OnDeleted sets a function called with every message the reaper deleted,
+// e.g. to remove its attachment file and tell live connections
+func (s *MessageReaper) OnDeleted(fn func(*entity.ChatMessage)) *MessageReaper {
+	s.onDeleted = fn
+	return s
+}
+
+// Run deletes expired messages every reaperInterval until ctx is done
+func (s *MessageReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(reaperInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeleteExpired(ctx); err != nil {
+			log.Println("Failed to delete expired messages:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteExpired deletes every message that expired and returns how many were deleted
+func (s *MessageReaper) DeleteExpired(ctx context.Context) (int, error) {
+	deleted := 0
+	for {
+		expired, err := s.chatRepo.DeleteExpired(ctx, s.now(), reaperBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		for _, message := range expired {
+			s.onDeleted(message)
+		}
+		deleted += len(expired)
+
+		if len(expired) < reaperBatchSize {
+			return deleted, nil
+		}
+	}
+}
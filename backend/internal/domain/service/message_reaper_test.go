@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakeExpiringChatRepo keeps messages in memory, other methods are not used by the reaper
+type fakeExpiringChatRepo struct {
+	repository.ChatRepository
+	messages []*entity.ChatMessage
+}
+
+func (f *fakeExpiringChatRepo) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*entity.ChatMessage, error) {
+	var expired, kept []*entity.ChatMessage
+	for _, m := range f.messages {
+		if m.IsExpired(now) && len(expired) < limit {
+			expired = append(expired, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	f.messages = kept
+	return expired, nil
+}
+
+func TestMessageReaper_DeletesExpiredMessages(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{t: time.Date(2025, 11, 22, 10, 0, 0, 0, time.UTC)}
+	hourly := &entity.Couple{MessageTTL: 3600}
+
+	chats := &fakeExpiringChatRepo{}
+	for i := int64(1); i <= reaperBatchSize+5; i++ {
+		chats.messages = append(chats.messages, &entity.ChatMessage{ID: i, ExpiresAt: hourly.MessageExpiry(clock.t)})
+	}
+	chats.messages = append(chats.messages, &entity.ChatMessage{ID: 1000})
+
+	reaper := NewMessageReaper(chats)
+	reaper.now = clock.now
+	var removed []int64
+	reaper.OnDeleted(func(msg *entity.ChatMessage) { removed = append(removed, msg.ID) })
+
+	if n, err := reaper.DeleteExpired(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing to expire yet, deleted %d (err %v)", n, err)
+	}
+
+	clock.advance(time.Hour)
+	if n, err := reaper.DeleteExpired(ctx); err != nil || n != reaperBatchSize+5 {
+		t.Fatalf("expected every expired message over several batches, deleted %d (err %v)", n, err)
+	}
+	if len(removed) != reaperBatchSize+5 {
+		t.Errorf("expected OnDeleted for every message, got %d", len(removed))
+	}
+	if len(chats.messages) != 1 || chats.messages[0].ID != 1000 {
+		t.Errorf("expected only the message without timer to be kept, got %+v", chats.messages)
+	}
+}
+
+func TestMessageScheduler_AppliesMessageTTL(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, _, clock := newTestScheduler(t)
+	if _, _, err := scheduler.coupleService.SetMessageTTL(ctx, 1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Message: "Halo", DeliverAt: clock.t})
+	if _, err := scheduler.DeliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := chats.created[0].ExpiresAt; got == nil || !got.Equal(clock.t.Add(time.Hour)) {
+		t.Errorf("expected the message to expire an hour after delivery, got %v", got)
+	}
+}
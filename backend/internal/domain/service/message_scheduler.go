@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+const (
+	// schedulerInterval is how often due scheduled messages are delivered
+	schedulerInterval = 30 * time.Second
+
+	// schedulerBatchSize is the number of scheduled messages claimed at once
+	schedulerBatchSize = 50
+
+	// schedulerClaimTimeout is how long a claimed message may stay processing before it is
+	// claimed again, e.g. after the instance delivering it crashed
+	schedulerClaimTimeout = 5 * time.Minute
+)
+
+// MessageScheduler delivers scheduled messages and time capsules to the chat once they are due
+type MessageScheduler struct {
+	scheduledRepo repository.ScheduledMessageRepository
+	notifRepo     repository.NotificationRepository
+	userRepo      repository.UserRepository
+	coupleService *CoupleService
+	onDelivered   func(*entity.ChatMessage)
+	now           func() time.Time
+}
+
+// NewMessageScheduler creates a new message scheduler
+func NewMessageScheduler(
+	scheduledRepo repository.ScheduledMessageRepository,
+	notifRepo repository.NotificationRepository,
+	userRepo repository.UserRepository,
+	coupleService *CoupleService,
+) *MessageScheduler {
+	return &MessageScheduler{
+		scheduledRepo: scheduledRepo,
+		notifRepo:     notifRepo,
+		userRepo:      userRepo,
+		coupleService: coupleService,
+		onDelivered:   func(*entity.ChatMessage) {},
+		now:           time.Now,
+	}
+}
+
+// OnDelivered sets a function called with every chat message the scheduler delivered,
+// e.g. to push it to live connections
+func (s *MessageScheduler) OnDelivered(fn func(*entity.ChatMessage)) *MessageScheduler {
+	s.onDelivered = fn
+	return s
+}
+
+// Run delivers due messages every schedulerInterval until ctx is done
+func (s *MessageScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeliverDue(ctx); err != nil {
+			log.Println("Failed to deliver scheduled messages:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue delivers every scheduled message that is due and returns how many were delivered.
+// A message that fails to deliver is put back and retried on the next run, and one whose delivery
+// was cut off is claimed again after schedulerClaimTimeout. The chat message is created together
+// with marking the scheduled message delivered, so it is never delivered twice.
+func (s *MessageScheduler) DeliverDue(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		now := s.now()
+		due, err := s.scheduledRepo.ClaimDue(ctx, now, now.Add(-schedulerClaimTimeout), schedulerBatchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		// Every claimed message must be delivered or released, so keep going after a failure
+		var firstErr error
+		for _, scheduled := range due {
+			ok, err := s.deliver(ctx, scheduled)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if ok {
+				delivered++
+			}
+		}
+
+		if firstErr != nil || len(due) < schedulerBatchSize {
+			return delivered, firstErr
+		}
+	}
+}
+
+// deliver turns a claimed scheduled message into a chat message and reports whether it did
+func (s *MessageScheduler) deliver(ctx context.Context, scheduled *entity.ScheduledMessage) (bool, error) {
+	// Messages for a former partner are not delivered
+	couple, err := s.coupleService.CoupleOf(ctx, scheduled.SenderID)
+	if err != nil && !errors.Is(err, ErrNoPartner) {
+		s.release(ctx, scheduled)
+		return false, err
+	}
+	if err != nil || couple.PartnerOf(scheduled.SenderID) != scheduled.ReceiverID {
+		log.Printf("Cancelling scheduled message %d, user %d is no longer paired with user %d",
+			scheduled.ID, scheduled.SenderID, scheduled.ReceiverID)
+		s.release(ctx, scheduled)
+		scheduled.Status = entity.ScheduledStatusCancelled
+		return false, s.scheduledRepo.Cancel(ctx, scheduled.ID)
+	}
+
+	chatMessage := &entity.ChatMessage{
+		SenderID:   scheduled.SenderID,
+		ReceiverID: scheduled.ReceiverID,
+		Message:    scheduled.ChatText(),
+		ReadStatus: false,
+		// The disappearing message timer applies from the moment the message is delivered
+		ExpiresAt: couple.MessageExpiry(s.now()),
+	}
+	// The chat message exists only once the scheduled message is marked delivered
+	if err := s.scheduledRepo.Deliver(ctx, scheduled.ID, chatMessage); err != nil {
+		if errors.Is(err, repository.ErrScheduledMessageNotFound) {
+			// Another instance claimed it again after the timeout and delivered or released it
+			log.Printf("Scheduled message %d is no longer claimed, skipping it", scheduled.ID)
+			return false, nil
+		}
+		s.release(ctx, scheduled)
+		return false, err
+	}
+	scheduled.Status = entity.ScheduledStatusDelivered
+	scheduled.MessageID = &chatMessage.ID
+
+	s.notify(ctx, scheduled, chatMessage)
+	s.onDelivered(chatMessage)
+	return true, nil
+}
+
+// notify sends the receiver the same notification as for a message sent right away
+func (s *MessageScheduler) notify(ctx context.Context, scheduled *entity.ScheduledMessage, chatMessage *entity.ChatMessage) {
+	sender := "pasanganmu"
+	if user, err := s.userRepo.FindByID(ctx, scheduled.SenderID); err == nil {
+		sender = user.Username
+	}
+
+	text := "Pesan baru dari " + sender
+	if scheduled.Kind == entity.ScheduledKindTimeCapsule {
+		text = "Surat dari " + sender + " sudah bisa dibuka"
+	}
+
+	s.notifRepo.Create(ctx, &entity.Notification{
+		UserID:     scheduled.ReceiverID,
+		Type:       entity.NotificationTypeNewMessage,
+		Message:    text,
+		RelatedID:  chatMessage.ID,
+		ReadStatus: false,
+	})
+}
+
+func (s *MessageScheduler) release(ctx context.Context, scheduled *entity.ScheduledMessage) {
+	if err := s.scheduledRepo.Release(ctx, scheduled.ID); err != nil {
+		log.Printf("Failed to release scheduled message %d: %v", scheduled.ID, err)
+	}
+}
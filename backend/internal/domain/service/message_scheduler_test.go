@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakeScheduledRepo is an in-memory ScheduledMessageRepository, delivered messages go to chats
+type fakeScheduledRepo struct {
+	messages  []*entity.ScheduledMessage
+	claimedAt map[int64]time.Time
+	chats     *fakeChatRepo
+}
+
+func (f *fakeScheduledRepo) Create(ctx context.Context, message *entity.ScheduledMessage) error {
+	message.ID = int64(len(f.messages) + 1)
+	message.Status = entity.ScheduledStatusPending
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func (f *fakeScheduledRepo) FindByID(ctx context.Context, id int64) (*entity.ScheduledMessage, error) {
+	for _, m := range f.messages {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, repository.ErrScheduledMessageNotFound
+}
+
+func (f *fakeScheduledRepo) FindPendingBySender(ctx context.Context, senderID int64) ([]*entity.ScheduledMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeScheduledRepo) FindPendingByReceiver(ctx context.Context, receiverID int64, kind entity.ScheduledMessageKind) ([]*entity.ScheduledMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeScheduledRepo) Update(ctx context.Context, message *entity.ScheduledMessage) error {
+	return nil
+}
+
+func (f *fakeScheduledRepo) Cancel(ctx context.Context, id int64) error {
+	return f.setStatus(id, entity.ScheduledStatusPending, entity.ScheduledStatusCancelled)
+}
+
+func (f *fakeScheduledRepo) ClaimDue(ctx context.Context, now, reclaimBefore time.Time, limit int) ([]*entity.ScheduledMessage, error) {
+	var due []*entity.ScheduledMessage
+	for _, m := range f.messages {
+		stale := m.Status == entity.ScheduledStatusProcessing && f.claimedAt[m.ID].Before(reclaimBefore)
+		if ((m.IsPending() && !m.DeliverAt.After(now)) || stale) && len(due) < limit {
+			m.Status = entity.ScheduledStatusProcessing
+			if f.claimedAt == nil {
+				f.claimedAt = map[int64]time.Time{}
+			}
+			f.claimedAt[m.ID] = now
+			claimed := *m
+			due = append(due, &claimed)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeScheduledRepo) Release(ctx context.Context, id int64) error {
+	return f.setStatus(id, entity.ScheduledStatusProcessing, entity.ScheduledStatusPending)
+}
+
+func (f *fakeScheduledRepo) Deliver(ctx context.Context, id int64, message *entity.ChatMessage) error {
+	m, err := f.FindByID(ctx, id)
+	if err != nil || m.Status != entity.ScheduledStatusProcessing {
+		return repository.ErrScheduledMessageNotFound
+	}
+	if err := f.chats.Create(ctx, message); err != nil {
+		return err
+	}
+	m.Status = entity.ScheduledStatusDelivered
+	m.MessageID = &message.ID
+	return nil
+}
+
+func (f *fakeScheduledRepo) setStatus(id int64, from, to entity.ScheduledMessageStatus) error {
+	for _, m := range f.messages {
+		if m.ID == id && m.Status == from {
+			m.Status = to
+			return nil
+		}
+	}
+	return repository.ErrScheduledMessageNotFound
+}
+
+// fakeChatRepo records the chat messages of delivered scheduled messages
+type fakeChatRepo struct {
+	created []*entity.ChatMessage
+	err     error
+}
+
+func (f *fakeChatRepo) Create(ctx context.Context, message *entity.ChatMessage) error {
+	if f.err != nil {
+		return f.err
+	}
+	message.ID = int64(len(f.created) + 100)
+	f.created = append(f.created, message)
+	return nil
+}
+
+// fakeNotificationRepo records created notifications
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+	created []*entity.Notification
+}
+
+func (f *fakeNotificationRepo) Create(ctx context.Context, notification *entity.Notification) error {
+	f.created = append(f.created, notification)
+	return nil
+}
+
+func newTestScheduler(t *testing.T) (*MessageScheduler, *fakeScheduledRepo, *fakeChatRepo, *fakeNotificationRepo, *fakeClock) {
+	t.Helper()
+	couples := NewCoupleService(&fakeCoupleRepo{}, &fakeInviteRepo{})
+	if _, err := couples.Pair(context.Background(), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	chats, notifs := &fakeChatRepo{}, &fakeNotificationRepo{}
+	scheduled := &fakeScheduledRepo{chats: chats}
+	users := &fakeUserRepo{users: []*entity.User{{ID: 1, Username: "irfan"}, {ID: 2, Username: "sisti"}}}
+	clock := &fakeClock{t: time.Date(2025, 11, 22, 10, 0, 0, 0, time.UTC)}
+
+	scheduler := NewMessageScheduler(scheduled, notifs, users, couples)
+	scheduler.now = clock.now
+	return scheduler, scheduled, chats, notifs, clock
+}
+
+func TestMessageScheduler_DeliversDueMessages(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, notifs, clock := newTestScheduler(t)
+
+	var pushed []*entity.ChatMessage
+	scheduler.OnDelivered(func(msg *entity.ChatMessage) { pushed = append(pushed, msg) })
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Kind: entity.ScheduledKindMessage,
+		Message: "Selamat ulang tahun!", DeliverAt: clock.t.Add(time.Hour)})
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 2, ReceiverID: 1, Kind: entity.ScheduledKindTimeCapsule,
+		Title: "Buka saat kangen", Message: "Aku juga kangen", DeliverAt: clock.t.Add(2 * time.Hour)})
+
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing due yet, delivered %d (err %v)", n, err)
+	}
+
+	clock.advance(time.Hour)
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 delivery, got %d (err %v)", n, err)
+	}
+	if len(chats.created) != 1 || chats.created[0].Message != "Selamat ulang tahun!" || chats.created[0].ReceiverID != 2 {
+		t.Fatalf("unexpected chat messages %+v", chats.created)
+	}
+	if first := scheduled.messages[0]; first.Status != entity.ScheduledStatusDelivered || first.MessageID == nil || *first.MessageID != chats.created[0].ID {
+		t.Errorf("expected scheduled message to be linked to its chat message, got %+v", first)
+	}
+	if len(notifs.created) != 1 || notifs.created[0].Type != entity.NotificationTypeNewMessage ||
+		notifs.created[0].UserID != 2 || notifs.created[0].Message != "Pesan baru dari irfan" {
+		t.Errorf("unexpected notifications %+v", notifs.created)
+	}
+	if len(pushed) != 1 {
+		t.Errorf("expected the delivered message to be pushed, got %d", len(pushed))
+	}
+
+	clock.advance(time.Hour)
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the time capsule to be delivered, got %d (err %v)", n, err)
+	}
+	if got := chats.created[1].Message; got != "💌 Buka saat kangen\n\nAku juga kangen" {
+		t.Errorf("unexpected time capsule text %q", got)
+	}
+	if got := notifs.created[1].Message; got != "Surat dari sisti sudah bisa dibuka" {
+		t.Errorf("unexpected time capsule notification %q", got)
+	}
+}
+
+func TestMessageScheduler_RetriesFailedDeliveries(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, _, clock := newTestScheduler(t)
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Message: "Halo", DeliverAt: clock.t})
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Message: "Apa kabar", DeliverAt: clock.t})
+
+	chats.err = errors.New("database is down")
+	if _, err := scheduler.DeliverDue(ctx); err == nil {
+		t.Fatal("expected delivery error")
+	}
+	for _, m := range scheduled.messages {
+		if !m.IsPending() {
+			t.Fatalf("expected failed message %d to be pending again, got %s", m.ID, m.Status)
+		}
+	}
+
+	chats.err = nil
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 2 {
+		t.Fatalf("expected both messages on retry, got %d (err %v)", n, err)
+	}
+}
+
+func TestMessageScheduler_CancelsMessagesForFormerPartner(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, _, clock := newTestScheduler(t)
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 3, Message: "Halo", DeliverAt: clock.t})
+
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 0 {
+		t.Fatalf("expected no delivery, got %d (err %v)", n, err)
+	}
+	if len(chats.created) != 0 {
+		t.Errorf("expected no chat message, got %+v", chats.created)
+	}
+	if status := scheduled.messages[0].Status; status != entity.ScheduledStatusCancelled {
+		t.Errorf("expected cancelled, got %s", status)
+	}
+}
+
+func TestMessageScheduler_ReclaimsInterruptedDeliveries(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, _, clock := newTestScheduler(t)
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Message: "Halo", DeliverAt: clock.t})
+
+	// The instance crashed after claiming the message, before its chat message was written
+	if _, err := scheduled.ClaimDue(ctx, clock.t, clock.t.Add(-schedulerClaimTimeout), schedulerBatchSize); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 0 {
+		t.Fatalf("expected a fresh claim to be left alone, delivered %d (err %v)", n, err)
+	}
+
+	clock.advance(schedulerClaimTimeout + time.Second)
+	if n, err := scheduler.DeliverDue(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the interrupted delivery to be retried, got %d (err %v)", n, err)
+	}
+	if len(chats.created) != 1 {
+		t.Fatalf("expected 1 chat message, got %d", len(chats.created))
+	}
+	if m := scheduled.messages[0]; m.Status != entity.ScheduledStatusDelivered || m.MessageID == nil || *m.MessageID != chats.created[0].ID {
+		t.Errorf("expected the message to be delivered and linked, got %+v", m)
+	}
+}
+
+func TestMessageScheduler_SkipsMessagesDeliveredElsewhere(t *testing.T) {
+	ctx := context.Background()
+	scheduler, scheduled, chats, notifs, clock := newTestScheduler(t)
+
+	scheduled.Create(ctx, &entity.ScheduledMessage{SenderID: 1, ReceiverID: 2, Message: "Halo", DeliverAt: clock.t})
+	claimed, err := scheduled.ClaimDue(ctx, clock.t, clock.t.Add(-schedulerClaimTimeout), schedulerBatchSize)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected 1 claimed message, got %d (err %v)", len(claimed), err)
+	}
+
+	// A slow delivery: meanwhile the message was claimed again and delivered by another instance
+	scheduled.messages[0].Status = entity.ScheduledStatusDelivered
+
+	delivered, err := scheduler.deliver(ctx, claimed[0])
+	if err != nil || delivered {
+		t.Fatalf("expected the message to be skipped, delivered %v (err %v)", delivered, err)
+	}
+	if len(chats.created) != 0 || len(notifs.created) != 0 {
+		t.Errorf("expected no chat message or notification, got %d and %d", len(chats.created), len(notifs.created))
+	}
+	if status := scheduled.messages[0].Status; status != entity.ScheduledStatusDelivered {
+		t.Errorf("expected the message to stay delivered, got %s", status)
+	}
+}
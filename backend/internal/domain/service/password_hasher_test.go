@@ -0,0 +1,138 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// testArgon2Params keep the tests fast
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1}
+
+func mustHasher(t *testing.T, algorithm string, bcryptCost int, params Argon2Params) *PasswordHasher {
+	t.Helper()
+	hasher, err := NewPasswordHasher(algorithm, bcryptCost, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+func TestPasswordHasher_HashAndVerify(t *testing.T) {
+	hashers := map[string]*PasswordHasher{
+		"bcrypt":   mustHasher(t, HashAlgorithmBcrypt, 4, testArgon2Params),
+		"argon2id": mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params),
+	}
+
+	for name, hasher := range hashers {
+		hash, err := hasher.Hash("irfan123")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !hasher.Verify("irfan123", hash) {
+			t.Errorf("%s: correct password rejected", name)
+		}
+		if hasher.Verify("irfan124", hash) {
+			t.Errorf("%s: wrong password accepted", name)
+		}
+		if hasher.NeedsRehash(hash) {
+			t.Errorf("%s: fresh hash should not need a rehash", name)
+		}
+	}
+
+	hash, _ := hashers["argon2id"].Hash("irfan123")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected argon2id hash format %q", hash)
+	}
+}
+
+func TestPasswordHasher_VerifiesOtherFormats(t *testing.T) {
+	bcryptHash, err := mustHasher(t, HashAlgorithmBcrypt, 4, testArgon2Params).Hash("sisti123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	argonHash, err := mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params).Hash("sisti123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argon := mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params)
+	if !argon.Verify("sisti123", bcryptHash) {
+		t.Error("argon2id hasher must still verify bcrypt hashes")
+	}
+	if !argon.NeedsRehash(bcryptHash) {
+		t.Error("bcrypt hash should be upgraded to argon2id")
+	}
+
+	bcryptHasher := mustHasher(t, HashAlgorithmBcrypt, 4, testArgon2Params)
+	if !bcryptHasher.Verify("sisti123", argonHash) {
+		t.Error("bcrypt hasher must still verify argon2id hashes")
+	}
+	if !bcryptHasher.NeedsRehash(argonHash) {
+		t.Error("argon2id hash should be rehashed when bcrypt is configured")
+	}
+}
+
+func TestPasswordHasher_NeedsRehashOnWeakerParameters(t *testing.T) {
+	cheapBcrypt, _ := mustHasher(t, HashAlgorithmBcrypt, 4, testArgon2Params).Hash("irfan123")
+	if !mustHasher(t, HashAlgorithmBcrypt, 5, testArgon2Params).NeedsRehash(cheapBcrypt) {
+		t.Error("expected bcrypt hash with another cost to need a rehash")
+	}
+
+	cheapArgon, _ := mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params).Hash("irfan123")
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	if !mustHasher(t, HashAlgorithmArgon2id, 4, stronger).NeedsRehash(cheapArgon) {
+		t.Error("expected argon2id hash with other parameters to need a rehash")
+	}
+}
+
+func TestPasswordHasher_RejectsMalformedHashesAndConfig(t *testing.T) {
+	hasher := mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params)
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		if hasher.Verify("x", hash) {
+			t.Errorf("malformed hash %q accepted", hash)
+		}
+	}
+
+	if _, err := NewPasswordHasher("md5", 10, testArgon2Params); err == nil {
+		t.Error("expected unknown algorithm to be rejected")
+	}
+	if _, err := NewPasswordHasher(HashAlgorithmBcrypt, 99, testArgon2Params); err == nil {
+		t.Error("expected invalid bcrypt cost to be rejected")
+	}
+	if _, err := NewPasswordHasher(HashAlgorithmArgon2id, 10, Argon2Params{}); err == nil {
+		t.Error("expected zero argon2id parameters to be rejected")
+	}
+}
+
+func TestPasswordHasher_HashLike(t *testing.T) {
+	argon := mustHasher(t, HashAlgorithmArgon2id, 4, testArgon2Params)
+	bcryptHash, err := mustHasher(t, HashAlgorithmBcrypt, 5, testArgon2Params).Hash("sisti123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An argon2id hasher still produces a bcrypt hash of the same cost
+	hash, err := argon.HashLike("dummy-password", bcryptHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$2a$05$") {
+		t.Errorf("expected a bcrypt hash with cost 5, got %q", hash)
+	}
+
+	argonHash := "$argon2id$v=19$m=128,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+	hash, err = argon.HashLike("dummy-password", argonHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=128,t=2,p=1$") {
+		t.Errorf("expected the argon2id parameters of the stored hash, got %q", hash)
+	}
+
+	// Unknown formats fall back to the configured algorithm
+	hash, _ = argon.HashLike("dummy-password", "plain")
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("expected the configured hasher, got %q", hash)
+	}
+}
@@ -0,0 +1,14 @@
+package service
+
+import "github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+
+// Can reports whether the user's role is granted permission
+func (c *Claims) Can(permission entity.Permission) bool {
+	return entity.UserRole(c.Role).Can(permission)
+}
+
+// CanManage reports whether the user may change a resource owned by ownerID:
+// owners always can, everybody else needs permission
+func (c *Claims) CanManage(ownerID int64, permission entity.Permission) bool {
+	return c.UserID == ownerID || c.Can(permission)
+}
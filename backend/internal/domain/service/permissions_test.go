@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+func TestClaims_Can(t *testing.T) {
+	admin := &Claims{UserID: 1, Role: string(entity.RoleSuperAdmin)}
+	user := &Claims{UserID: 2, Role: string(entity.RoleUser)}
+	unknown := &Claims{UserID: 3, Role: "moderator"}
+
+	for _, p := range []entity.Permission{entity.PermissionAdminAccess, entity.PermissionGalleryDeleteAny, entity.PermissionRequestsDeleteAny} {
+		if !admin.Can(p) {
+			t.Errorf("super_admin should have %s", p)
+		}
+		if user.Can(p) || unknown.Can(p) {
+			t.Errorf("only super_admin should have %s", p)
+		}
+	}
+
+	if !entity.RoleUser.IsValid() || entity.UserRole("moderator").IsValid() {
+		t.Error("unexpected role validity")
+	}
+}
+
+func TestClaims_CanManage(t *testing.T) {
+	admin := &Claims{UserID: 1, Role: string(entity.RoleSuperAdmin)}
+	user := &Claims{UserID: 2, Role: string(entity.RoleUser)}
+
+	if !user.CanManage(2, entity.PermissionGalleryDeleteAny) {
+		t.Error("owners can always manage their resources")
+	}
+	if user.CanManage(1, entity.PermissionGalleryDeleteAny) {
+		t.Error("users cannot manage resources of others")
+	}
+	if !admin.CanManage(2, entity.PermissionGalleryDeleteAny) {
+		t.Error("the permission allows managing resources of others")
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// PersonalAccessTokenPrefix starts every personal access token, so they are easy to tell
+// apart from JWTs and to find in leaked code
+const PersonalAccessTokenPrefix = "fsp_"
+
+const (
+	// MaxPersonalAccessTokens is the number of active tokens a user can have
+	MaxPersonalAccessTokens = 20
+
+	// personalAccessTokenBytes is the amount of randomness in a token
+	personalAccessTokenBytes = 32
+	// personalAccessTokenPrefixLength is how much of a token is kept to recognize it in lists
+	personalAccessTokenPrefixLength = 12
+)
+
+var (
+	// ErrInvalidScope is returned when a token is requested with an unknown or no scope
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrTooManyPersonalAccessTokens is returned when a user reached MaxPersonalAccessTokens
+	ErrTooManyPersonalAccessTokens = errors.New("too many personal access tokens")
+	// ErrInvalidPersonalAccessToken is returned for unknown, revoked or expired tokens
+	ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+)
+
+// PersonalAccessTokenService creates and checks long-lived, scoped API tokens
+type PersonalAccessTokenService struct {
+	tokenRepo repository.PersonalAccessTokenRepository
+	userRepo  repository.UserRepository
+	now       func() time.Time
+}
+
+// NewPersonalAccessTokenService creates a new personal access token service
+func NewPersonalAccessTokenService(tokenRepo repository.PersonalAccessTokenRepository, userRepo repository.UserRepository) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+		now:       time.Now,
+	}
+}
+
+// Create issues a new token for the user. A ttl of zero creates a token that never expires.
+// The plain token is only returned here, just its hash is stored.
+func (s *PersonalAccessTokenService) Create(ctx context.Context, userID int64, name string, scopes []string, ttl time.Duration) (string, *entity.PersonalAccessToken, error) {
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tokens, err := s.List(ctx, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	active := 0
+	for _, t := range tokens {
+		if t.IsUsable(s.now()) {
+			active++
+		}
+	}
+	if active >= MaxPersonalAccessTokens {
+		return "", nil, ErrTooManyPersonalAccessTokens
+	}
+
+	buf := make([]byte, personalAccessTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	plain := PersonalAccessTokenPrefix + hex.EncodeToString(buf)
+
+	token := &entity.PersonalAccessToken{
+		UserID:      userID,
+		Name:        name,
+		TokenHash:   HashToken(plain),
+		TokenPrefix: plain[:personalAccessTokenPrefixLength],
+		Scopes:      scopes,
+	}
+	if ttl > 0 {
+		expiresAt := s.now().Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return "", nil, err
+	}
+
+	return plain, token, nil
+}
+
+// List returns the user's tokens, revoked ones included
+func (s *PersonalAccessTokenService) List(ctx context.Context, userID int64) ([]*entity.PersonalAccessToken, error) {
+	return s.tokenRepo.FindByUserID(ctx, userID)
+}
+
+// Revoke revokes one of the user's tokens
+func (s *PersonalAccessTokenService) Revoke(ctx context.Context, userID, id int64) error {
+	return s.tokenRepo.Revoke(ctx, id, userID)
+}
+
+// Authenticate checks a plain token and returns claims limited to its scopes.
+// Username and role are read from the user, so role changes and disabled accounts apply immediately.
+func (s *PersonalAccessTokenService) Authenticate(ctx context.Context, plain string) (*Claims, error) {
+	if !IsPersonalAccessToken(plain) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	token, err := s.tokenRepo.FindByHash(ctx, HashToken(plain))
+	if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !token.IsUsable(s.now()) {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, token.UserID)
+	if err != nil || user.IsDisabled() {
+		return nil, ErrInvalidPersonalAccessToken
+	}
+
+	s.tokenRepo.TouchLastUsed(ctx, token.ID)
+
+	claims := &Claims{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      string(user.Role),
+		TokenType: TokenTypePersonalAccess,
+		Scopes:    token.Scopes,
+	}
+	claims.ID = strconv.FormatInt(token.ID, 10)
+
+	return claims, nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token looks like a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// normalizeScopes validates scopes and removes duplicates, keeping the AllScopes order
+func normalizeScopes(scopes []string) ([]string, error) {
+	requested := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !IsValidScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		requested[scope] = true
+	}
+	if len(requested) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	var normalized []string
+	for _, scope := range AllScopes {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakePATRepo is an in-memory PersonalAccessTokenRepository
+type fakePATRepo struct {
+	tokens []*entity.PersonalAccessToken
+}
+
+func (f *fakePATRepo) FindByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error) {
+	for _, t := range f.tokens {
+		if t.TokenHash == tokenHash {
+			return t, nil
+		}
+	}
+	return nil, repository.ErrPersonalAccessTokenNotFound
+}
+
+func (f *fakePATRepo) FindByUserID(ctx context.Context, userID int64) ([]*entity.PersonalAccessToken, error) {
+	var tokens []*entity.PersonalAccessToken
+	for _, t := range f.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (f *fakePATRepo) Create(ctx context.Context, token *entity.PersonalAccessToken) error {
+	token.ID = int64(len(f.tokens) + 1)
+	f.tokens = append(f.tokens, token)
+	return nil
+}
+
+func (f *fakePATRepo) Revoke(ctx context.Context, id, userID int64) error {
+	for _, t := range f.tokens {
+		if t.ID == id && t.UserID == userID {
+			now := time.Now()
+			t.RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrPersonalAccessTokenNotFound
+}
+
+func (f *fakePATRepo) TouchLastUsed(ctx context.Context, id int64) error {
+	return nil
+}
+
+// fakeUserRepo is an in-memory UserRepository
+type fakeUserRepo struct {
+	users []*entity.User
+}
+
+func (f *fakeUserRepo) FindAll(ctx context.Context) ([]*entity.User, error) {
+	return f.users, nil
+}
+
+func (f *fakeUserRepo) FindByID(ctx context.Context, id int64) (*entity.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) Create(ctx context.Context, user *entity.User) error {
+	return nil
+}
+
+func (f *fakeUserRepo) Update(ctx context.Context, user *entity.User) error {
+	return nil
+}
+
+func (f *fakeUserRepo) UpdateRole(ctx context.Context, id int64, role entity.UserRole) error {
+	return nil
+}
+
+func (f *fakeUserRepo) FindCommonPasswordHash(ctx context.Context) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeUserRepo) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	for _, u := range f.users {
+		if u.ID == id {
+			u.DisabledAt = nil
+			if disabled {
+				now := time.Now()
+				u.DisabledAt = &now
+			}
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func newTestPATService() (*PersonalAccessTokenService, *fakeClock) {
+	users := &fakeUserRepo{users: []*entity.User{
+		{ID: 1, Username: "irfan", Role: entity.RoleSuperAdmin},
+		{ID: 2, Username: "sisti", Role: entity.RoleUser},
+	}}
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	svc := NewPersonalAccessTokenService(&fakePATRepo{}, users)
+	svc.now = clock.now
+	return svc, clock
+}
+
+func TestPersonalAccessToken_CreateAndAuthenticate(t *testing.T) {
+	svc, _ := newTestPATService()
+	ctx := context.Background()
+
+	plain, token, err := svc.Create(ctx, 2, "backup script", []string{ScopeGalleryRead, ScopeChatRead, ScopeGalleryRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, PersonalAccessTokenPrefix) || !strings.HasPrefix(plain, token.TokenPrefix) {
+		t.Errorf("unexpected token %q with prefix %q", plain, token.TokenPrefix)
+	}
+	if token.TokenHash == plain || token.TokenHash != HashToken(plain) {
+		t.Error("expected only the token hash to be stored")
+	}
+	if strings.Join(token.Scopes, ",") != "gallery:read,chat:read" {
+		t.Errorf("expected deduplicated scopes, got %v", token.Scopes)
+	}
+
+	claims, err := svc.Authenticate(ctx, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != 2 || claims.Username != "sisti" || claims.Role != "user" || !claims.IsPersonalAccessToken() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if !claims.HasScope(ScopeGalleryRead) || claims.HasScope(ScopeGalleryWrite) {
+		t.Errorf("expected only the granted scopes, got %v", claims.Scopes)
+	}
+
+	if _, err := svc.Authenticate(ctx, plain+"0"); !errors.Is(err, ErrInvalidPersonalAccessToken) {
+		t.Errorf("expected unknown token to be rejected, got %v", err)
+	}
+}
+
+func TestPersonalAccessToken_RejectsDisabledUsers(t *testing.T) {
+	svc, _ := newTestPATService()
+	ctx := context.Background()
+
+	plain, _, err := svc.Create(ctx, 2, "sync", []string{ScopeChatRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc.userRepo.SetDisabled(ctx, 2, true)
+	if _, err := svc.Authenticate(ctx, plain); !errors.Is(err, ErrInvalidPersonalAccessToken) {
+		t.Errorf("expected token of a disabled user to be rejected, got %v", err)
+	}
+
+	svc.userRepo.SetDisabled(ctx, 2, false)
+	if _, err := svc.Authenticate(ctx, plain); err != nil {
+		t.Errorf("expected token to work again after re-enabling, got %v", err)
+	}
+}
+
+func TestPersonalAccessToken_RevokedAndExpired(t *testing.T) {
+	svc, clock := newTestPATService()
+	ctx := context.Background()
+
+	revoked, token, err := svc.Create(ctx, 1, "ci", []string{ScopeChatWrite}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Revoke(ctx, 2, token.ID); !errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+		t.Errorf("expected other users to be unable to revoke the token, got %v", err)
+	}
+	if err := svc.Revoke(ctx, 1, token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, revoked); !errors.Is(err, ErrInvalidPersonalAccessToken) {
+		t.Errorf("expected revoked token to be rejected, got %v", err)
+	}
+
+	expiring, _, err := svc.Create(ctx, 1, "one day", []string{ScopeChatWrite}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate(ctx, expiring); err != nil {
+		t.Errorf("expected fresh token to be accepted, got %v", err)
+	}
+	clock.advance(24 * time.Hour)
+	if _, err := svc.Authenticate(ctx, expiring); !errors.Is(err, ErrInvalidPersonalAccessToken) {
+		t.Errorf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestPersonalAccessToken_RejectsInvalidScopes(t *testing.T) {
+	svc, _ := newTestPATService()
+
+	for _, scopes := range [][]string{nil, {}, {"admin"}, {ScopeChatRead, "chat:delete"}} {
+		if _, _, err := svc.Create(context.Background(), 1, "bad", scopes, 0); !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("scopes %v: expected ErrInvalidScope, got %v", scopes, err)
+		}
+	}
+}
+
+func TestPersonalAccessToken_LimitsActiveTokens(t *testing.T) {
+	svc, _ := newTestPATService()
+	ctx := context.Background()
+
+	var last *entity.PersonalAccessToken
+	for i := 0; i < MaxPersonalAccessTokens; i++ {
+		_, token, err := svc.Create(ctx, 1, "token", []string{ScopeProfileRead}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = token
+	}
+	if _, _, err := svc.Create(ctx, 1, "token", []string{ScopeProfileRead}, 0); !errors.Is(err, ErrTooManyPersonalAccessTokens) {
+		t.Errorf("expected limit to be enforced, got %v", err)
+	}
+
+	// Revoked tokens do not count
+	svc.Revoke(ctx, 1, last.ID)
+	if _, _, err := svc.Create(ctx, 1, "token", []string{ScopeProfileRead}, 0); err != nil {
+		t.Errorf("expected token after a revoke, got %v", err)
+	}
+}
+
+func TestClaims_HasScope(t *testing.T) {
+	session := &Claims{TokenType: TokenTypeAccess}
+	if !session.HasScope(ScopeGalleryWrite) || session.IsPersonalAccessToken() {
+		t.Error("login sessions must not be limited by scopes")
+	}
+
+	pat := &Claims{TokenType: TokenTypePersonalAccess, Scopes: []string{ScopeChatRead}}
+	if !pat.HasScope(ScopeChatRead) || pat.HasScope(ScopeChatWrite) {
+		t.Error("personal access tokens must be limited to their scopes")
+	}
+}
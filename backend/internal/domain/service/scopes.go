@@ -0,0 +1,57 @@
+package service
+
+// Scopes a personal access token can be granted, one read and one write scope per area
+const (
+	ScopeProfileRead        = "profile:read"
+	ScopeGalleryRead        = "gallery:read"
+	ScopeGalleryWrite       = "gallery:write"
+	ScopeRequestsRead       = "requests:read"
+	ScopeRequestsWrite      = "requests:write"
+	ScopeChatRead           = "chat:read"
+	ScopeChatWrite          = "chat:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+// AllScopes lists every scope in the order they are shown to users
+var AllScopes = []string{
+	ScopeProfileRead,
+	ScopeGalleryRead,
+	ScopeGalleryWrite,
+	ScopeRequestsRead,
+	ScopeRequestsWrite,
+	ScopeChatRead,
+	ScopeChatWrite,
+	ScopeNotificationsRead,
+	ScopeNotificationsWrite,
+}
+
+// IsValidScope reports whether scope is one of AllScopes
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the token the claims were read from may be used for scope.
+// Access tokens of a login session are not restricted, personal access tokens only
+// carry the scopes they were created with.
+func (c *Claims) HasScope(scope string) bool {
+	if !c.IsPersonalAccessToken() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPersonalAccessToken reports whether the claims belong to a personal access token
+func (c *Claims) IsPersonalAccessToken() bool {
+	return c.TokenType == TokenTypePersonalAccess
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+var (
+	// ErrTwoFactorEnabled is returned when enrolling a user that already has 2FA enabled
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when 2FA is required but not enabled
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotSetUp is returned when enabling 2FA before a secret was generated
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication has not been set up")
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or replayed codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes issued when 2FA is enabled
+	RecoveryCodeCount = 10
+
+	// recoveryCodeLength is the number of characters in a recovery code, without the separator
+	recoveryCodeLength = 10
+)
+
+// TwoFactorService manages TOTP enrollment and verifies second-factor codes
+type TwoFactorService struct {
+	repo   repository.TwoFactorRepository
+	issuer string
+}
+
+// NewTwoFactorService creates a new two-factor service.
+// The issuer is shown next to the account in authenticator apps.
+func NewTwoFactorService(repo repository.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		repo:   repo,
+		issuer: issuer,
+	}
+}
+
+// IsEnabled reports whether the user has confirmed 2FA enrollment
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	totp, err := s.repo.FindByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.IsEnabled(), nil
+}
+
+// Setup generates a new pending secret and returns it with its provisioning URI.
+// 2FA is not active until the first code is confirmed with Enable.
+func (s *TwoFactorService) Setup(ctx context.Context, userID int64, account string) (secret, uri string, err error) {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if enabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	secret, err = generateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.SaveSecret(ctx, userID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, totpProvisioningURI(s.issuer, account, secret), nil
+}
+
+// Enable confirms the pending secret with a code from the authenticator app
+// and returns freshly generated recovery codes. They are only shown once.
+func (s *TwoFactorService) Enable(ctx context.Context, userID int64, code string) ([]string, error) {
+	totp, err := s.repo.FindByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.IsEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	step, ok := matchTOTP(totp.Secret, normalizeTwoFactorCode(code), time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		raw, err := randomCode(recoveryCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:recoveryCodeLength/2] + "-" + raw[recoveryCodeLength/2:]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+
+	if err := s.repo.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or an unused recovery code for the user.
+// Each TOTP time step and each recovery code is accepted only once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int64, code string) error {
+	totp, err := s.repo.FindByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrTwoFactorNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !totp.IsEnabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeTwoFactorCode(code)
+
+	// Six digits is an authenticator code, anything else is tried as a recovery code
+	if len(code) == totpDigits && strings.Trim(code, "0123456789") == "" {
+		step, ok := matchTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		err := s.repo.UseStep(ctx, userID, step)
+		if errors.Is(err, repository.ErrTOTPStepUsed) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	err = s.repo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// Disable verifies a code and removes the secret and all recovery codes
+func (s *TwoFactorService) Disable(ctx context.Context, userID int64, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+// normalizeTwoFactorCode uppercases the code and strips separators users may type
+func normalizeTwoFactorCode(code string) string {
+	return NormalizeInviteCode(code)
+}
+
+// hashRecoveryCode hashes a normalized recovery code for storage
+func hashRecoveryCode(code string) string {
+	return HashToken(code)
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakeTwoFactorRepo is an in-memory TwoFactorRepository
+type fakeTwoFactorRepo struct {
+	secrets       map[int64]*entity.UserTOTP
+	recoveryCodes map[int64]map[string]bool // hash -> used
+}
+
+func newFakeTwoFactorRepo() *fakeTwoFactorRepo {
+	return &fakeTwoFactorRepo{
+		secrets:       map[int64]*entity.UserTOTP{},
+		recoveryCodes: map[int64]map[string]bool{},
+	}
+}
+
+func (f *fakeTwoFactorRepo) FindByUserID(ctx context.Context, userID int64) (*entity.UserTOTP, error) {
+	if totp, ok := f.secrets[userID]; ok {
+		return totp, nil
+	}
+	return nil, repository.ErrTwoFactorNotFound
+}
+
+func (f *fakeTwoFactorRepo) SaveSecret(ctx context.Context, userID int64, secret string) error {
+	if totp, ok := f.secrets[userID]; ok && totp.IsEnabled() {
+		return nil
+	}
+	f.secrets[userID] = &entity.UserTOTP{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (f *fakeTwoFactorRepo) Enable(ctx context.Context, userID, step int64, hashes []string) error {
+	totp, ok := f.secrets[userID]
+	if !ok || totp.IsEnabled() {
+		return repository.ErrTwoFactorNotFound
+	}
+	now := time.Now()
+	totp.EnabledAt, totp.LastUsedStep = &now, step
+
+	f.recoveryCodes[userID] = map[string]bool{}
+	for _, h := range hashes {
+		f.recoveryCodes[userID][h] = false
+	}
+	return nil
+}
+
+func (f *fakeTwoFactorRepo) UseStep(ctx context.Context, userID, step int64) error {
+	totp := f.secrets[userID]
+	if step <= totp.LastUsedStep {
+		return repository.ErrTOTPStepUsed
+	}
+	totp.LastUsedStep = step
+	return nil
+}
+
+func (f *fakeTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int64, hash string) error {
+	used, ok := f.recoveryCodes[userID][hash]
+	if !ok || used {
+		return repository.ErrRecoveryCodeNotFound
+	}
+	f.recoveryCodes[userID][hash] = true
+	return nil
+}
+
+func (f *fakeTwoFactorRepo) Delete(ctx context.Context, userID int64) error {
+	delete(f.secrets, userID)
+	delete(f.recoveryCodes, userID)
+	return nil
+}
+
+// currentCode returns the authenticator code for secret at time step
+func currentCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(key, step)
+}
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// SHA1 vectors from RFC 6238 appendix B, truncated to 6 digits
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if code := totpCode(key, totpStep(time.Unix(tt.unix, 0))); code != tt.code {
+			t.Errorf("t=%d: expected %s, got %s", tt.unix, tt.code, code)
+		}
+	}
+}
+
+func TestTOTP_MatchAllowsOneStepOfDrift(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1234567890, 0)
+	step := totpStep(now)
+
+	for _, offset := range []int64{-1, 0, 1} {
+		if got, ok := matchTOTP(secret, currentCode(t, secret, step+offset), now); !ok || got != step+offset {
+			t.Errorf("offset %d: expected match at step %d, got %d (%v)", offset, step+offset, got, ok)
+		}
+	}
+	if _, ok := matchTOTP(secret, currentCode(t, secret, step+2), now); ok {
+		t.Error("expected code two steps ahead to be rejected")
+	}
+}
+
+func TestTwoFactorService_ProvisioningURI(t *testing.T) {
+	svc := NewTwoFactorService(newFakeTwoFactorRepo(), "Fasisi")
+
+	secret, uri, err := svc.Setup(context.Background(), 1, "irfan@fasisi.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Fasisi:irfan@fasisi.com" {
+		t.Errorf("unexpected provisioning URI %q", uri)
+	}
+	if u.Query().Get("secret") != secret || u.Query().Get("issuer") != "Fasisi" {
+		t.Errorf("unexpected provisioning URI params %q", u.RawQuery)
+	}
+}
+
+func TestTwoFactorService_EnrollAndVerify(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeTwoFactorRepo()
+	svc := NewTwoFactorService(repo, "Fasisi")
+
+	if err := svc.Verify(ctx, 1, "123456"); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Errorf("expected ErrTwoFactorNotEnabled before enrollment, got %v", err)
+	}
+
+	secret, _, err := svc.Setup(ctx, 1, "irfan@fasisi.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := svc.IsEnabled(ctx, 1); enabled {
+		t.Fatal("2FA must not be enabled before the first code is confirmed")
+	}
+
+	if _, err := svc.Enable(ctx, 1, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected wrong code to be rejected, got %v", err)
+	}
+
+	step := totpStep(time.Now())
+	code := currentCode(t, secret, step)
+	recoveryCodes, err := svc.Enable(ctx, 1, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recoveryCodes) != RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", RecoveryCodeCount, len(recoveryCodes))
+	}
+	if _, _, err := svc.Setup(ctx, 1, "irfan@fasisi.com"); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Errorf("expected ErrTwoFactorEnabled when setting up twice, got %v", err)
+	}
+
+	// The code used for enrollment cannot be replayed
+	if err := svc.Verify(ctx, 1, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected replayed code to be rejected, got %v", err)
+	}
+	if err := svc.Verify(ctx, 1, currentCode(t, secret, step+1)); err != nil {
+		t.Errorf("expected next code to be accepted, got %v", err)
+	}
+
+	// Recovery codes work once, in any case and with or without the separator
+	if err := svc.Verify(ctx, 1, strings.ToLower(recoveryCodes[0])); err != nil {
+		t.Errorf("expected recovery code to be accepted, got %v", err)
+	}
+	if err := svc.Verify(ctx, 1, recoveryCodes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("expected used recovery code to be rejected, got %v", err)
+	}
+	if err := svc.Disable(ctx, 1, recoveryCodes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := svc.IsEnabled(ctx, 1); enabled {
+		t.Error("expected 2FA to be disabled")
+	}
+}
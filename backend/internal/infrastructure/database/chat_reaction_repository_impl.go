@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/lib/pq"
+)
+
+type chatReactionRepository struct {
+	db *PostgresDB
+}
+
+// NewChatReactionRepository creates a new chat reaction repository
+func NewChatReactionRepository(db *PostgresDB) repository.ChatReactionRepository {
+	return &chatReactionRepository{db: db}
+}
+
+func (r *chatReactionRepository) Add(ctx context.Context, reaction *entity.ChatReaction) (bool, error) {
+	query := `INSERT INTO chat_reactions (message_id, user_id, emoji, created_at) 
+			  VALUES ($1, $2, $3, NOW())
+			  ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+			  RETURNING id, created_at`
+
+	err := r.db.DB.QueryRowContext(ctx, query, reaction.MessageID, reaction.UserID, reaction.Emoji).
+		Scan(&reaction.ID, &reaction.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *chatReactionRepository) Remove(ctx context.Context, messageID, userID int64, emoji string) error {
+	query := `DELETE FROM chat_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3`
+
+	result, err := r.db.DB.ExecContext(ctx, query, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrChatReactionNotFound
+	}
+
+	return nil
+}
+
+func (r *chatReactionRepository) FindByMessageIDs(ctx context.Context, messageIDs []int64) ([]*entity.ChatReaction, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT id, message_id, user_id, emoji, created_at FROM chat_reactions 
+			  WHERE message_id = ANY($1) ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reactions []*entity.ChatReaction
+	for rows.Next() {
+		reaction := &entity.ChatReaction{}
+		if err := rows.Scan(&reaction.ID, &reaction.MessageID, &reaction.UserID, &reaction.Emoji, &reaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+
+	return reactions, rows.Err()
+}
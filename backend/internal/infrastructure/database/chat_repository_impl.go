@@ -2,11 +2,25 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
 
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/lib/pq"
 )
 
+// chatMessageColumns are the chat_messages columns read by scanChatMessage, in order
+const chatMessageColumns = `id, sender_id, receiver_id, message, read_status, created_at, edited_at, deleted_at,
+	reply_to_id, reply_to_sender_id, reply_to_preview,
+	attachment_type, attachment_path, attachment_mime, attachment_size, attachment_duration,
+	delivered_at, read_at, expires_at, system`
+
+// notExpired leaves out disappearing messages the reaper has not deleted yet
+const notExpired = `(expires_at IS NULL OR expires_at > NOW())`
+
 type chatRepository struct {
 	db *PostgresDB
 }
@@ -16,54 +30,478 @@ func NewChatRepository(db *PostgresDB) repository.ChatRepository {
 	return &chatRepository{db: db}
 }
 
-func (r *chatRepository) FindHistory(ctx context.Context, user1ID, user2ID int64) ([]*entity.ChatMessage, error) {
-	query := `SELECT id, sender_id, receiver_id, message, read_status, created_at 
+func (r *chatRepository) FindHistoryPage(ctx context.Context, user1ID, user2ID int64, page repository.ChatPage) ([]*entity.ChatMessage, bool, error) {
+	// Keyset pagination on (created_at, id), the cursor message supplies the boundary.
+	// A cursor message that disappeared in the meantime falls back to ID order.
+	var query string
+	var args []interface{}
+	switch {
+	case page.AfterID > 0:
+		query = `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages 
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+			  AND ` + notExpired + `
+			  AND ((created_at, id) > (SELECT created_at, id FROM chat_messages WHERE id = $3)
+			       OR (NOT EXISTS (SELECT 1 FROM chat_messages WHERE id = $3) AND id > $3))
+			  ORDER BY created_at ASC, id ASC LIMIT $4`
+		args = []interface{}{user1ID, user2ID, page.AfterID, page.Limit + 1}
+	case page.BeforeID > 0:
+		query = `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages 
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+			  AND ` + notExpired + `
+			  AND ((created_at, id) < (SELECT created_at, id FROM chat_messages WHERE id = $3)
+			       OR (NOT EXISTS (SELECT 1 FROM chat_messages WHERE id = $3) AND id < $3))
+			  ORDER BY created_at DESC, id DESC LIMIT $4`
+		args = []interface{}{user1ID, user2ID, page.BeforeID, page.Limit + 1}
+	default:
+		query = `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages 
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+			  AND ` + notExpired + `
+			  ORDER BY created_at DESC, id DESC LIMIT $3`
+		args = []interface{}{user1ID, user2ID, page.Limit + 1}
+	}
+
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	messages, err := scanChatMessages(rows)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// One extra row was fetched to tell whether there is more
+	hasMore := len(messages) > page.Limit
+	if hasMore {
+		messages = messages[:page.Limit]
+	}
+
+	if page.AfterID == 0 {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, hasMore, nil
+}
+
+func (r *chatRepository) FindHistoryAfter(ctx context.Context, user1ID, user2ID, afterID int64) ([]*entity.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` 
 			  FROM chat_messages 
-			  WHERE (sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1)
-			  ORDER BY created_at ASC`
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1)) AND id > $3
+			  AND ` + notExpired + `
+			  ORDER BY id ASC`
 
-	rows, err := r.db.DB.QueryContext(ctx, query, user1ID, user2ID)
+	rows, err := r.db.DB.QueryContext(ctx, query, user1ID, user2ID, afterID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []*entity.ChatMessage
+	return scanChatMessages(rows)
+}
+
+func (r *chatRepository) Search(ctx context.Context, user1ID, user2ID int64, search string, limit int) ([]*entity.ChatSearchResult, error) {
+	query := `SELECT ` + chatMessageColumns + `,
+			  ts_rank(search_vector, q) AS rank,
+			  ts_headline('simple', message, q, $5)
+			  FROM chat_messages, websearch_to_tsquery('simple', $3) AS q
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+			  AND deleted_at IS NULL AND ` + notExpired + ` AND search_vector @@ q
+			  ORDER BY rank DESC, created_at DESC, id DESC LIMIT $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, user1ID, user2ID, search, limit, snippetOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*entity.ChatSearchResult
 	for rows.Next() {
 		msg := &entity.ChatMessage{}
-		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Message, &msg.ReadStatus, &msg.CreatedAt)
+		result := &entity.ChatSearchResult{Message: msg}
+		fields, done := chatMessageFields(msg)
+		if err := rows.Scan(append(fields, &result.Rank, &result.Snippet)...); err != nil {
+			return nil, err
+		}
+		done()
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// StreamHistory compares from and to as points in time: created_at is NOW() in the database
+// time zone, so the bounds are converted to that zone instead of dropping their offset
+func (r *chatRepository) StreamHistory(ctx context.Context, user1ID, user2ID int64, from, to time.Time, fn func(*entity.ChatMessage) error) error {
+	query := `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages 
+			  WHERE ((sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1))
+			  AND deleted_at IS NULL AND ` + notExpired + `
+			  AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz::timestamp)
+			  AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz::timestamp)
+			  ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, user1ID, user2ID, nullTime(from), nullTime(to))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ts_headline marks matches with private use characters instead of <mark></mark>, so the
+// message text can be HTML-escaped before the marks are turned into tags
+const (
+	snippetStartSel = "\ue000"
+	snippetStopSel  = "\ue001"
+	snippetOptions  = `StartSel="` + snippetStartSel + `", StopSel="` + snippetStopSel + `", MaxWords=20, MinWords=5, MaxFragments=2, FragmentDelimiter=" ... "`
+)
+
+var snippetMarks = strings.NewReplacer(snippetStartSel, "<mark>", snippetStopSel, "</mark>")
+
+// highlightSnippet escapes a ts_headline excerpt and wraps its matches in <mark></mark>
+func highlightSnippet(headline string) string {
+	return snippetMarks.Replace(html.EscapeString(headline))
+}
+
+// nullTime turns a zero time into NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// chatMessageFields returns the scan targets for the chat_messages columns, in select order,
+// and a function that copies the nullable columns into msg once the row was scanned
+func chatMessageFields(msg *entity.ChatMessage) ([]interface{}, func()) {
+	var editedAt, deletedAt sql.NullTime
+	var replyToID, replyToSenderID sql.NullInt64
+	var replyToPreview sql.NullString
+	var attachmentType, attachmentPath, attachmentMime sql.NullString
+	var attachmentSize sql.NullInt64
+	var attachmentDuration sql.NullFloat64
+	var deliveredAt, readAt, expiresAt sql.NullTime
+	fields := []interface{}{
+		&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Message, &msg.ReadStatus, &msg.CreatedAt, &editedAt, &deletedAt,
+		&replyToID, &replyToSenderID, &replyToPreview,
+		&attachmentType, &attachmentPath, &attachmentMime, &attachmentSize, &attachmentDuration,
+		&deliveredAt, &readAt, &expiresAt, &msg.System,
+	}
+	return fields, func() {
+		if expiresAt.Valid {
+			msg.ExpiresAt = &expiresAt.Time
+		}
+		if deliveredAt.Valid {
+			msg.DeliveredAt = &deliveredAt.Time
+		}
+		if readAt.Valid {
+			msg.ReadAt = &readAt.Time
+		}
+		msg.UpdateStatus()
+		if attachmentPath.Valid {
+			msg.Attachment = &entity.ChatAttachment{
+				FileType: entity.FileType(attachmentType.String),
+				FilePath: attachmentPath.String,
+				MimeType: attachmentMime.String,
+				FileSize: attachmentSize.Int64,
+				Duration: attachmentDuration.Float64,
+			}
+		}
+		if editedAt.Valid {
+			msg.EditedAt = &editedAt.Time
+		}
+		if deletedAt.Valid {
+			msg.DeletedAt = &deletedAt.Time
+		}
+		if replyToPreview.Valid {
+			msg.ReplyTo = &entity.MessagePreview{
+				ID:       replyToID.Int64,
+				SenderID: replyToSenderID.Int64,
+				Message:  replyToPreview.String,
+			}
+		}
+	}
+}
+
+func scanChatMessage(row scanner) (*entity.ChatMessage, error) {
+	msg := &entity.ChatMessage{}
+	fields, done := chatMessageFields(msg)
+	if err := row.Scan(fields...); err != nil {
+		return nil, err
+	}
+	done()
+	return msg, nil
+}
+
+// scanChatMessages reads every row of a chat_messages query
+func scanChatMessages(rows *sql.Rows) ([]*entity.ChatMessage, error) {
+	var messages []*entity.ChatMessage
+	for rows.Next() {
+		msg, err := scanChatMessage(rows)
 		if err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
 	}
 
-	return messages, nil
+	return messages, rows.Err()
+}
+
+func (r *chatRepository) FindByID(ctx context.Context, id int64) (*entity.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages WHERE id = $1 AND ` + notExpired
+
+	msg, err := scanChatMessage(r.db.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrChatMessageNotFound
+	}
+	return msg, err
+}
+
+func (r *chatRepository) FindByAttachmentPath(ctx context.Context, filePath string) (*entity.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` 
+			  FROM chat_messages WHERE attachment_path = $1 AND ` + notExpired + ` LIMIT 1`
+
+	msg, err := scanChatMessage(r.db.DB.QueryRowContext(ctx, query, filePath))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrChatMessageNotFound
+	}
+	return msg, err
 }
 
 func (r *chatRepository) Create(ctx context.Context, message *entity.ChatMessage) error {
-	query := `INSERT INTO chat_messages (sender_id, receiver_id, message, read_status, created_at) 
-			  VALUES ($1, $2, $3, $4, NOW()) RETURNING id`
+	return insertChatMessage(ctx, r.db.DB, message)
+}
 
-	err := r.db.DB.QueryRowContext(ctx, query,
+// insertChatMessage stores a new chat message, also inside the transaction delivering a scheduled message
+func insertChatMessage(ctx context.Context, db queryRower, message *entity.ChatMessage) error {
+	query := `INSERT INTO chat_messages (sender_id, receiver_id, message, read_status, reply_to_id, reply_to_sender_id, reply_to_preview,
+			  attachment_type, attachment_path, attachment_mime, attachment_size, attachment_duration, expires_at, system, created_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW()) RETURNING id, created_at`
+
+	var replyToID, replyToSenderID, replyToPreview interface{}
+	if message.ReplyTo != nil {
+		replyToID, replyToSenderID, replyToPreview = message.ReplyTo.ID, message.ReplyTo.SenderID, message.ReplyTo.Message
+	}
+	var attachmentType, attachmentPath, attachmentMime, attachmentSize, attachmentDuration interface{}
+	if a := message.Attachment; a != nil {
+		attachmentType, attachmentPath, attachmentMime, attachmentSize = string(a.FileType), a.FilePath, a.MimeType, a.FileSize
+		if a.Duration > 0 {
+			attachmentDuration = a.Duration
+		}
+	}
+
+	err := db.QueryRowContext(ctx, query,
 		message.SenderID, message.ReceiverID, message.Message, message.ReadStatus,
-	).Scan(&message.ID)
+		replyToID, replyToSenderID, replyToPreview,
+		attachmentType, attachmentPath, attachmentMime, attachmentSize, attachmentDuration,
+		message.ExpiresAt, message.System,
+	).Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	message.UpdateStatus()
+	return nil
+}
+
+func (r *chatRepository) Edit(ctx context.Context, message *entity.ChatMessage, text string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Keep the text as stored, not as the caller last saw it
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO chat_message_revisions (message_id, message, created_at)
+		 SELECT id, message, NOW() FROM chat_messages WHERE id = $1 AND deleted_at IS NULL`,
+		message.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	var editedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE chat_messages SET message = $1, edited_at = NOW() WHERE id = $2 AND deleted_at IS NULL RETURNING edited_at`,
+		text, message.ID,
+	).Scan(&editedAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrChatMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	message.Message = text
+	message.EditedAt = &editedAt
+	return nil
+}
+
+func (r *chatRepository) Delete(ctx context.Context, message *entity.ChatMessage) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE chat_messages SET message = '', deleted_at = NOW(),
+		 attachment_type = NULL, attachment_path = NULL, attachment_mime = NULL, attachment_size = NULL, attachment_duration = NULL
+		 WHERE id = $1 AND deleted_at IS NULL RETURNING deleted_at`,
+		message.ID,
+	).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrChatMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Earlier versions would otherwise still reveal the deleted text
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_message_revisions WHERE message_id = $1`, message.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chat_reactions WHERE message_id = $1`, message.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	message.Message = ""
+	message.Attachment = nil
+	message.DeletedAt = &deletedAt
+	return nil
+}
+
+func (r *chatRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*entity.ChatMessage, error) {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several instances reap at the same time without deleting a message twice
+	var ids []int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(array_agg(id), '{}') FROM (
+			 SELECT id FROM chat_messages WHERE expires_at <= $1
+			 ORDER BY expires_at ASC LIMIT $2 FOR UPDATE SKIP LOCKED
+		 ) expired`,
+		now, limit,
+	).Scan(pq.Array(&ids))
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// A quote would otherwise keep the text of the message around
+	_, err = tx.ExecContext(ctx, `UPDATE chat_messages SET reply_to_preview = '' WHERE reply_to_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions and reactions are removed by ON DELETE CASCADE
+	rows, err := tx.QueryContext(ctx, `DELETE FROM chat_messages WHERE id = ANY($1) RETURNING `+chatMessageColumns, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	messages, err := scanChatMessages(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
 
-	return err
+func (r *chatRepository) FindRevisions(ctx context.Context, messageID int64) ([]*entity.ChatMessageRevision, error) {
+	query := `SELECT id, message_id, message, created_at FROM chat_message_revisions 
+			  WHERE message_id = $1 ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []*entity.ChatMessageRevision
+	for rows.Next() {
+		rev := &entity.ChatMessageRevision{}
+		if err := rows.Scan(&rev.ID, &rev.MessageID, &rev.Message, &rev.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
 }
 
-func (r *chatRepository) MarkAsRead(ctx context.Context, senderID, receiverID int64) error {
-	query := `UPDATE chat_messages SET read_status = TRUE 
-			  WHERE sender_id = $1 AND receiver_id = $2 AND read_status = FALSE`
+func (r *chatRepository) MarkDelivered(ctx context.Context, senderID, receiverID, upToID int64, at time.Time) (int64, error) {
+	query := `UPDATE chat_messages SET delivered_at = $4 
+			  WHERE sender_id = $1 AND receiver_id = $2 AND ($3 = 0 OR id <= $3) AND delivered_at IS NULL`
 
-	_, err := r.db.DB.ExecContext(ctx, query, senderID, receiverID)
-	return err
+	result, err := r.db.DB.ExecContext(ctx, query, senderID, receiverID, upToID, at)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *chatRepository) MarkRead(ctx context.Context, senderID, receiverID, upToID int64, at time.Time) (int64, error) {
+	query := `UPDATE chat_messages SET read_status = TRUE, read_at = $4, delivered_at = COALESCE(delivered_at, $4) 
+			  WHERE sender_id = $1 AND receiver_id = $2 AND ($3 = 0 OR id <= $3) AND read_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, senderID, receiverID, upToID, at)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (r *chatRepository) CountUnread(ctx context.Context, userID int64) (int64, error) {
-	query := `SELECT COUNT(*) FROM chat_messages WHERE receiver_id = $1 AND read_status = FALSE`
+	query := `SELECT COUNT(*) FROM chat_messages WHERE receiver_id = $1 AND read_status = FALSE AND ` + notExpired
 
 	var count int64
 	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(&count)
 	return count, err
 }
+
+// CountByUserID returns how many messages the user sent and received
+func (r *chatRepository) CountByUserID(ctx context.Context, userID int64) (int64, int64, error) {
+	query := `SELECT COUNT(*) FILTER (WHERE sender_id = $1), COUNT(*) FILTER (WHERE receiver_id = $1) 
+			  FROM chat_messages WHERE sender_id = $1 OR receiver_id = $1`
+
+	var sent, received int64
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(&sent, &received)
+	return sent, received, err
+}
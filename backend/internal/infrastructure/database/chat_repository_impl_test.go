@@ -0,0 +1,12 @@
+package database
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	headline := "makan di " + snippetStartSel + "restoran" + snippetStopSel + ` <img src=x onerror="alert(1)"> & sunda`
+
+	want := `makan di <mark>restoran</mark> &lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; sunda`
+	if got := highlightSnippet(headline); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type coupleInviteRepository struct {
+	db *PostgresDB
+}
+
+// NewCoupleInviteRepository creates a new couple invite repository
+func NewCoupleInviteRepository(db *PostgresDB) repository.CoupleInviteRepository {
+	return &coupleInviteRepository{db: db}
+}
+
+func (r *coupleInviteRepository) FindByCode(ctx context.Context, code string) (*entity.CoupleInvite, error) {
+	query := `SELECT id, code, inviter_id, expires_at, redeemed_by, redeemed_at, created_at 
+			  FROM couple_invites WHERE code = $1`
+
+	invite := &entity.CoupleInvite{}
+	var redeemedBy sql.NullInt64
+	var redeemedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, code).Scan(
+		&invite.ID, &invite.Code, &invite.InviterID, &invite.ExpiresAt,
+		&redeemedBy, &redeemedAt, &invite.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if redeemedBy.Valid {
+		invite.RedeemedBy = &redeemedBy.Int64
+	}
+	if redeemedAt.Valid {
+		invite.RedeemedAt = &redeemedAt.Time
+	}
+
+	return invite, nil
+}
+
+func (r *coupleInviteRepository) Create(ctx context.Context, invite *entity.CoupleInvite) error {
+	query := `INSERT INTO couple_invites (code, inviter_id, expires_at, created_at) 
+			  VALUES ($1, $2, $3, NOW()) RETURNING id, created_at`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		invite.Code, invite.InviterID, invite.ExpiresAt,
+	).Scan(&invite.ID, &invite.CreatedAt)
+}
+
+func (r *coupleInviteRepository) MarkRedeemed(ctx context.Context, id, userID int64) error {
+	query := `UPDATE couple_invites SET redeemed_by = $1, redeemed_at = NOW() 
+			  WHERE id = $2 AND redeemed_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("invite already redeemed")
+	}
+
+	return nil
+}
+
+func (r *coupleInviteRepository) DeletePendingByInviter(ctx context.Context, inviterID int64) error {
+	query := `DELETE FROM couple_invites WHERE inviter_id = $1 AND redeemed_at IS NULL`
+	_, err := r.db.DB.ExecContext(ctx, query, inviterID)
+	return err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/lib/pq"
+)
+
+type coupleRepository struct {
+	db *PostgresDB
+}
+
+// NewCoupleRepository creates a new couple repository
+func NewCoupleRepository(db *PostgresDB) repository.CoupleRepository {
+	return &coupleRepository{db: db}
+}
+
+func (r *coupleRepository) FindByID(ctx context.Context, id int64) (*entity.Couple, error) {
+	query := `SELECT id, user1_id, user2_id, created_at, message_ttl_seconds FROM couples WHERE id = $1`
+
+	couple := &entity.Couple{}
+	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+		&couple.ID, &couple.User1ID, &couple.User2ID, &couple.CreatedAt, &couple.MessageTTL,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrCoupleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return couple, nil
+}
+
+func (r *coupleRepository) FindByUserID(ctx context.Context, userID int64) (*entity.Couple, error) {
+	query := `SELECT id, user1_id, user2_id, created_at, message_ttl_seconds FROM couples 
+			  WHERE user1_id = $1 OR user2_id = $1`
+
+	couple := &entity.Couple{}
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+		&couple.ID, &couple.User1ID, &couple.User2ID, &couple.CreatedAt, &couple.MessageTTL,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrCoupleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return couple, nil
+}
+
+func (r *coupleRepository) Create(ctx context.Context, couple *entity.Couple) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO couples (user1_id, user2_id, created_at) 
+			  VALUES ($1, $2, NOW()) RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, couple.User1ID, couple.User2ID).Scan(&couple.ID, &couple.CreatedAt)
+	if err != nil {
+		return coupleMemberError(err)
+	}
+
+	// The couple_members primary key rejects a user that joined another couple in the meantime
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO couple_members (user_id, couple_id) VALUES ($1, $3), ($2, $3)`,
+		couple.User1ID, couple.User2ID, couple.ID,
+	)
+	if err != nil {
+		return coupleMemberError(err)
+	}
+
+	return tx.Commit()
+}
+
+// coupleMemberError maps a unique violation on couples or couple_members to ErrCoupleMemberExists
+func coupleMemberError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return repository.ErrCoupleMemberExists
+	}
+	return err
+}
+
+func (r *coupleRepository) UpdateMessageTTL(ctx context.Context, id int64, ttl int64) error {
+	query := `UPDATE couples SET message_ttl_seconds = $2 WHERE id = $1`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, ttl)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrCoupleNotFound
+	}
+
+	return nil
+}
+
+func (r *coupleRepository) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM couples WHERE id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
@@ -19,7 +19,7 @@ func NewGalleryRepository(db *PostgresDB) repository.GalleryRepository {
 }
 
 func (r *galleryRepository) FindAll(ctx context.Context) ([]*entity.Gallery, error) {
-	query := `SELECT id, user_id, file_type, file_path, caption, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), file_type, file_path, caption, file_size, created_at, updated_at 
 			  FROM gallery ORDER BY created_at DESC`
 
 	rows, err := r.db.DB.QueryContext(ctx, query)
@@ -31,7 +31,7 @@ func (r *galleryRepository) FindAll(ctx context.Context) ([]*entity.Gallery, err
 	var galleries []*entity.Gallery
 	for rows.Next() {
 		g := &entity.Gallery{}
-		err := rows.Scan(&g.ID, &g.UserID, &g.FileType, &g.FilePath, &g.Caption, &g.CreatedAt, &g.UpdatedAt)
+		err := rows.Scan(&g.ID, &g.UserID, &g.CoupleID, &g.FileType, &g.FilePath, &g.Caption, &g.FileSize, &g.CreatedAt, &g.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -42,12 +42,12 @@ func (r *galleryRepository) FindAll(ctx context.Context) ([]*entity.Gallery, err
 }
 
 func (r *galleryRepository) FindByID(ctx context.Context, id int64) (*entity.Gallery, error) {
-	query := `SELECT id, user_id, file_type, file_path, caption, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), file_type, file_path, caption, file_size, created_at, updated_at 
 			  FROM gallery WHERE id = $1`
 
 	g := &entity.Gallery{}
 	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
-		&g.ID, &g.UserID, &g.FileType, &g.FilePath, &g.Caption, &g.CreatedAt, &g.UpdatedAt,
+		&g.ID, &g.UserID, &g.CoupleID, &g.FileType, &g.FilePath, &g.Caption, &g.FileSize, &g.CreatedAt, &g.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -61,7 +61,7 @@ func (r *galleryRepository) FindByID(ctx context.Context, id int64) (*entity.Gal
 }
 
 func (r *galleryRepository) FindByUserID(ctx context.Context, userID int64) ([]*entity.Gallery, error) {
-	query := `SELECT id, user_id, file_type, file_path, caption, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), file_type, file_path, caption, file_size, created_at, updated_at 
 			  FROM gallery WHERE user_id = $1 ORDER BY created_at DESC`
 
 	rows, err := r.db.DB.QueryContext(ctx, query, userID)
@@ -73,7 +73,30 @@ func (r *galleryRepository) FindByUserID(ctx context.Context, userID int64) ([]*
 	var galleries []*entity.Gallery
 	for rows.Next() {
 		g := &entity.Gallery{}
-		err := rows.Scan(&g.ID, &g.UserID, &g.FileType, &g.FilePath, &g.Caption, &g.CreatedAt, &g.UpdatedAt)
+		err := rows.Scan(&g.ID, &g.UserID, &g.CoupleID, &g.FileType, &g.FilePath, &g.Caption, &g.FileSize, &g.CreatedAt, &g.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		galleries = append(galleries, g)
+	}
+
+	return galleries, nil
+}
+
+func (r *galleryRepository) FindByCoupleID(ctx context.Context, coupleID int64) ([]*entity.Gallery, error) {
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), file_type, file_path, caption, file_size, created_at, updated_at 
+			  FROM gallery WHERE couple_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, coupleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var galleries []*entity.Gallery
+	for rows.Next() {
+		g := &entity.Gallery{}
+		err := rows.Scan(&g.ID, &g.UserID, &g.CoupleID, &g.FileType, &g.FilePath, &g.Caption, &g.FileSize, &g.CreatedAt, &g.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -84,16 +107,25 @@ func (r *galleryRepository) FindByUserID(ctx context.Context, userID int64) ([]*
 }
 
 func (r *galleryRepository) Create(ctx context.Context, gallery *entity.Gallery) error {
-	query := `INSERT INTO gallery (user_id, file_type, file_path, caption, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, NOW(), NOW()) RETURNING id`
+	query := `INSERT INTO gallery (user_id, couple_id, file_type, file_path, caption, file_size, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) RETURNING id`
 
 	err := r.db.DB.QueryRowContext(ctx, query,
-		gallery.UserID, gallery.FileType, gallery.FilePath, gallery.Caption,
+		gallery.UserID, gallery.CoupleID, gallery.FileType, gallery.FilePath, gallery.Caption, gallery.FileSize,
 	).Scan(&gallery.ID)
 
 	return err
 }
 
+// UsageByUserID returns how many items the user uploaded and their total size in bytes
+func (r *galleryRepository) UsageByUserID(ctx context.Context, userID int64) (int64, int64, error) {
+	query := `SELECT COUNT(*), COALESCE(SUM(file_size), 0) FROM gallery WHERE user_id = $1`
+
+	var items, bytes int64
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(&items, &bytes)
+	return items, bytes, err
+}
+
 func (r *galleryRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM gallery WHERE id = $1`
 	_, err := r.db.DB.ExecContext(ctx, query, id)
@@ -0,0 +1,2 @@
+-- Drop couples table
+DROP TABLE IF EXISTS couples CASCADE;
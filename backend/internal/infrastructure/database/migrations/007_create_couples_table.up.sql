@@ -0,0 +1,14 @@
+-- Create couples table
+CREATE TABLE IF NOT EXISTS couples (
+    id SERIAL PRIMARY KEY,
+    user1_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    user2_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user1_id <> user2_id)
+);
+
+-- Pair the original users (Irfan and Sisti) on existing installations
+INSERT INTO couples (user1_id, user2_id)
+SELECT u1.id, u2.id FROM users u1, users u2
+WHERE u1.username = 'irfan' AND u2.username = 'sisti'
+ON CONFLICT DO NOTHING;
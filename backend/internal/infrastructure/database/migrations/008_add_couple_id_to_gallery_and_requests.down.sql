@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_gallery_couple_id;
+DROP INDEX IF EXISTS idx_date_requests_couple_id;
+
+-- Remove couple_id columns
+ALTER TABLE gallery DROP COLUMN IF EXISTS couple_id;
+ALTER TABLE date_requests DROP COLUMN IF EXISTS couple_id;
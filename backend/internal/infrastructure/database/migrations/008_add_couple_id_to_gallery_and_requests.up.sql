@@ -0,0 +1,19 @@
+-- Scope gallery items and date requests to a couple
+ALTER TABLE gallery
+ADD COLUMN IF NOT EXISTS couple_id INTEGER REFERENCES couples(id) ON DELETE SET NULL;
+
+ALTER TABLE date_requests
+ADD COLUMN IF NOT EXISTS couple_id INTEGER REFERENCES couples(id) ON DELETE SET NULL;
+
+-- Backfill existing rows from the uploader's couple
+UPDATE gallery g SET couple_id = c.id
+FROM couples c
+WHERE g.couple_id IS NULL AND (c.user1_id = g.user_id OR c.user2_id = g.user_id);
+
+UPDATE date_requests d SET couple_id = c.id
+FROM couples c
+WHERE d.couple_id IS NULL AND (c.user1_id = d.user_id OR c.user2_id = d.user_id);
+
+-- Create indexes for faster queries
+CREATE INDEX IF NOT EXISTS idx_gallery_couple_id ON gallery(couple_id);
+CREATE INDEX IF NOT EXISTS idx_date_requests_couple_id ON date_requests(couple_id);
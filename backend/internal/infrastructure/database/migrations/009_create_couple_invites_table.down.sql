@@ -0,0 +1,5 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_couple_invites_inviter_id;
+
+-- Drop couple_invites table
+DROP TABLE IF EXISTS couple_invites CASCADE;
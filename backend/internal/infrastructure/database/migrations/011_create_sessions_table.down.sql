@@ -0,0 +1,5 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+-- Drop sessions table
+DROP TABLE IF EXISTS sessions CASCADE;
@@ -0,0 +1,6 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_recovery_codes_user_id;
+
+-- Drop two-factor tables
+DROP TABLE IF EXISTS recovery_codes CASCADE;
+DROP TABLE IF EXISTS user_totp CASCADE;
@@ -0,0 +1,5 @@
+-- Drop index
+DROP INDEX IF EXISTS idx_personal_access_tokens_user_id;
+
+-- Drop personal_access_tokens table
+DROP TABLE IF EXISTS personal_access_tokens CASCADE;
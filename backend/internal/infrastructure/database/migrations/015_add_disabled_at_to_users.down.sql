@@ -0,0 +1,2 @@
+-- Remove disabled_at column
+ALTER TABLE users DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,3 @@
+-- Allow admins to disable accounts, NULL means the account is active
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Remove file_size column
+ALTER TABLE gallery DROP COLUMN IF EXISTS file_size;
@@ -0,0 +1,3 @@
+-- Track upload sizes for per-user storage usage, older rows count as 0 bytes
+ALTER TABLE gallery
+ADD COLUMN IF NOT EXISTS file_size BIGINT NOT NULL DEFAULT 0;
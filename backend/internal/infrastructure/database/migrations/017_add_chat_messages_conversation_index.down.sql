@@ -0,0 +1,5 @@
+-- Restore the previous index
+CREATE INDEX IF NOT EXISTS idx_chat_messages_sender_receiver ON chat_messages(sender_id, receiver_id);
+
+-- Drop index
+DROP INDEX IF EXISTS idx_chat_messages_conversation;
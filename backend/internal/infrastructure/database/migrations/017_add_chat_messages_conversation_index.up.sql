@@ -0,0 +1,5 @@
+-- Composite index for paging through a conversation in created_at order,
+-- it replaces the (sender_id, receiver_id) index which is a prefix of it
+CREATE INDEX IF NOT EXISTS idx_chat_messages_conversation ON chat_messages(sender_id, receiver_id, created_at);
+
+DROP INDEX IF EXISTS idx_chat_messages_sender_receiver;
@@ -0,0 +1,8 @@
+-- Full-text search over chat messages. The 'simple' configuration only lowercases
+-- words without stemming, which suits Indonesian and mixed-language chats better
+-- than the English dictionaries.
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_search_vector ON chat_messages USING GIN (search_vector);
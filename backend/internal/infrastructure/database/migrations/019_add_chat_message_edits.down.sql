@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS chat_message_revisions;
+
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,14 @@
+-- Edited and deleted messages. Deleted messages keep their row as a tombstone
+-- with the text cleared.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Previous versions of edited messages, one row per edit
+CREATE TABLE IF NOT EXISTS chat_message_revisions (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES chat_messages(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_message_revisions_message_id ON chat_message_revisions(message_id);
@@ -0,0 +1,2 @@
+-- Drop chat_reactions table
+DROP TABLE IF EXISTS chat_reactions;
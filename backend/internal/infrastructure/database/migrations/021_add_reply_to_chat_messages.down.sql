@@ -0,0 +1,3 @@
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS reply_to_preview;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS reply_to_sender_id;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS reply_to_id;
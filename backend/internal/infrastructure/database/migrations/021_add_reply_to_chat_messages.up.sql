@@ -0,0 +1,5 @@
+-- Replies to earlier messages. The quoted sender and text are copied when the
+-- reply is sent, so the quote stays readable when the original is deleted.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS reply_to_id INTEGER REFERENCES chat_messages(id) ON DELETE SET NULL;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS reply_to_sender_id INTEGER;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS reply_to_preview TEXT;
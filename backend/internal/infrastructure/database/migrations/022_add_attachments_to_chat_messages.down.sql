@@ -0,0 +1,5 @@
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS attachment_duration;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS attachment_size;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS attachment_mime;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS attachment_path;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS attachment_type;
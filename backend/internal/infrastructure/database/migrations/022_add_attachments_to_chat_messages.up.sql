@@ -0,0 +1,7 @@
+-- Chat messages can carry one photo, video or voice note.
+-- Duration is reported by the client in seconds for audio and video.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS attachment_type VARCHAR(10);
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS attachment_path VARCHAR(500);
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS attachment_mime VARCHAR(100);
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS attachment_size BIGINT;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS attachment_duration REAL;
@@ -0,0 +1,2 @@
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS read_at;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS delivered_at;
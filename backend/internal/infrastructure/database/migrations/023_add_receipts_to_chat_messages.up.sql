@@ -0,0 +1,8 @@
+-- Delivery and read receipts. read_status stays as the unread flag, read_at
+-- records when it was flipped.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+
+-- Messages read before receipts were kept have no known times, use the send time
+UPDATE chat_messages SET delivered_at = created_at, read_at = created_at
+WHERE read_status = TRUE AND read_at IS NULL;
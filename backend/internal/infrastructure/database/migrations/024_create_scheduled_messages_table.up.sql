@@ -0,0 +1,20 @@
+-- Chat messages written now and delivered later. Time capsules show their title
+-- to the receiver while sealed, the message only once delivered.
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id SERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    receiver_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'message',
+    title VARCHAR(200) NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    deliver_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    message_id INTEGER REFERENCES chat_messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The scheduler only looks at pending items that are due
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages(deliver_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_sender_id ON scheduled_messages(sender_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_receiver_id ON scheduled_messages(receiver_id);
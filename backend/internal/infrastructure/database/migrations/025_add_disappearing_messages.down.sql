@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_chat_messages_expires_at;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS system;
+ALTER TABLE chat_messages DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE couples DROP COLUMN IF EXISTS message_ttl_seconds;
@@ -0,0 +1,9 @@
+-- Disappearing messages. A couple picks a timer, messages sent while it is on
+-- get an expires_at and are hard-deleted once it has passed.
+ALTER TABLE couples ADD COLUMN IF NOT EXISTS message_ttl_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+-- System messages announce changes such as the timer, they cannot be edited or deleted
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS system BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_expires_at ON chat_messages(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS couple_members;
@@ -0,0 +1,14 @@
+-- One row per paired user. The primary key makes a user part of at most one couple,
+-- whichever of user1_id or user2_id they are in, so concurrent pairings cannot both succeed.
+CREATE TABLE IF NOT EXISTS couple_members (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    couple_id INTEGER NOT NULL REFERENCES couples(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_couple_members_couple_id ON couple_members(couple_id);
+
+INSERT INTO couple_members (user_id, couple_id)
+SELECT user1_id, id FROM couples
+UNION ALL
+SELECT user2_id, id FROM couples
+ON CONFLICT DO NOTHING;
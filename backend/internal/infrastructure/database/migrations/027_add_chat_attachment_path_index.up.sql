@@ -0,0 +1,3 @@
+-- Chat attachments are served by looking up the message that owns the file
+CREATE INDEX IF NOT EXISTS idx_chat_messages_attachment_path ON chat_messages(attachment_path)
+    WHERE attachment_path IS NOT NULL;
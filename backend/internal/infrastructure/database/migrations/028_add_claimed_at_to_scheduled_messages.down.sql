@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_scheduled_messages_claimed_at;
+UPDATE scheduled_messages SET status = 'pending' WHERE status = 'processing';
+ALTER TABLE scheduled_messages DROP COLUMN IF EXISTS claimed_at;
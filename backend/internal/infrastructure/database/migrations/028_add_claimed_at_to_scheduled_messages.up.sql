@@ -0,0 +1,5 @@
+-- Scheduled messages are claimed as 'processing' while they are delivered. claimed_at lets
+-- the scheduler claim them again when the instance delivering them died halfway.
+ALTER TABLE scheduled_messages ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_claimed_at ON scheduled_messages(claimed_at) WHERE status = 'processing';
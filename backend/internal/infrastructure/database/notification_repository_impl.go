@@ -40,6 +40,30 @@ func (r *notificationRepository) FindByUserID(ctx context.Context, userID int64,
 	return notifications, nil
 }
 
+func (r *notificationRepository) FindByUserIDAfter(ctx context.Context, userID, afterID int64, limit int) ([]*entity.Notification, error) {
+	query := `SELECT id, user_id, type, message, related_id, read_status, sent_email, sent_sms, created_at 
+			  FROM notifications WHERE user_id = $1 AND id > $2 ORDER BY id ASC LIMIT $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*entity.Notification
+	for rows.Next() {
+		notif := &entity.Notification{}
+		err := rows.Scan(&notif.ID, &notif.UserID, &notif.Type, &notif.Message,
+			&notif.RelatedID, &notif.ReadStatus, &notif.SentEmail, &notif.SentSMS, &notif.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notif)
+	}
+
+	return notifications, nil
+}
+
 func (r *notificationRepository) Create(ctx context.Context, notification *entity.Notification) error {
 	query := `INSERT INTO notifications (user_id, type, message, related_id, read_status, sent_email, sent_sms, created_at) 
 			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW()) RETURNING id`
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type passwordResetTokenRepository struct {
+	db *PostgresDB
+}
+
+// NewPasswordResetTokenRepository creates a new password reset token repository
+func NewPasswordResetTokenRepository(db *PostgresDB) repository.PasswordResetTokenRepository {
+	return &passwordResetTokenRepository{db: db}
+}
+
+func (r *passwordResetTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*entity.PasswordResetToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, used_at, created_at 
+			  FROM password_reset_tokens WHERE token_hash = $1`
+
+	token := &entity.PasswordResetToken{}
+	var usedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrPasswordResetTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return token, nil
+}
+
+func (r *passwordResetTokenRepository) Create(ctx context.Context, token *entity.PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at) 
+			  VALUES ($1, $2, $3, NOW()) RETURNING id, created_at`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		token.UserID, token.TokenHash, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *passwordResetTokenRepository) MarkUsed(ctx context.Context, id int64) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrPasswordResetTokenUsed
+	}
+
+	return nil
+}
+
+func (r *passwordResetTokenRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, userID)
+	return err
+}
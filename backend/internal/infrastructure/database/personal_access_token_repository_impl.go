@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type personalAccessTokenRepository struct {
+	db *PostgresDB
+}
+
+// NewPersonalAccessTokenRepository creates a new personal access token repository
+func NewPersonalAccessTokenRepository(db *PostgresDB) repository.PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func scanPersonalAccessToken(row scanner) (*entity.PersonalAccessToken, error) {
+	token := &entity.PersonalAccessToken{}
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.TokenPrefix, &scopes,
+		&expiresAt, &lastUsedAt, &revokedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Split(scopes, ",")
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+func (r *personalAccessTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*entity.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+			  FROM personal_access_tokens WHERE token_hash = $1`
+
+	token, err := scanPersonalAccessToken(r.db.DB.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrPersonalAccessTokenNotFound
+	}
+	return token, err
+}
+
+func (r *personalAccessTokenRepository) FindByUserID(ctx context.Context, userID int64) ([]*entity.PersonalAccessToken, error) {
+	query := `SELECT id, user_id, name, token_hash, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+			  FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*entity.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, token *entity.PersonalAccessToken) error {
+	query := `INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, NOW()) RETURNING id, created_at`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		token.UserID, token.Name, token.TokenHash, token.TokenPrefix, strings.Join(token.Scopes, ","), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *personalAccessTokenRepository) Revoke(ctx context.Context, id, userID int64) error {
+	query := `UPDATE personal_access_tokens SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrPersonalAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed bumps last_used_at at most once a minute, like session touches
+func (r *personalAccessTokenRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	query := `UPDATE personal_access_tokens SET last_used_at = NOW()
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
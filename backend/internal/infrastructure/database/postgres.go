@@ -10,7 +10,8 @@ import (
 
 // PostgresDB wraps sql.DB
 type PostgresDB struct {
-	DB *sql.DB
+	DB  *sql.DB
+	dsn string
 }
 
 // NewPostgresDB creates a new PostgreSQL connection
@@ -27,7 +28,12 @@ func NewPostgresDB(host, port, user, password, dbname string) (*PostgresDB, erro
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &PostgresDB{DB: db}, nil
+	return &PostgresDB{DB: db, dsn: dsn}, nil
+}
+
+// DSN returns the connection string, for connections outside the pool such as LISTEN
+func (p *PostgresDB) DSN() string {
+	return p.dsn
 }
 
 // RunMigrations runs all database migrations
@@ -40,3 +46,13 @@ func (p *PostgresDB) RunMigrations(ctx context.Context) error {
 func (p *PostgresDB) Close() error {
 	return p.DB.Close()
 }
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
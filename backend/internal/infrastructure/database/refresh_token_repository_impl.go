@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type refreshTokenRepository struct {
+	db *PostgresDB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *PostgresDB) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) FindByJTI(ctx context.Context, jti string) (*entity.RefreshToken, error) {
+	query := `SELECT id, jti, family_id, user_id, expires_at, revoked_at, COALESCE(replaced_by, ''), created_at 
+			  FROM refresh_tokens WHERE jti = $1`
+
+	token := &entity.RefreshToken{}
+	var revokedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, jti).Scan(
+		&token.ID, &token.JTI, &token.FamilyID, &token.UserID, &token.ExpiresAt,
+		&revokedAt, &token.ReplacedBy, &token.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (jti, family_id, user_id, expires_at, created_at) 
+			  VALUES ($1, $2, $3, $4, NOW()) RETURNING id, created_at`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		token.JTI, token.FamilyID, token.UserID, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, jti, replacedBy string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $1 
+			  WHERE jti = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, replacedBy, jti)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrRefreshTokenRevoked
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.DB.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.DB.ExecContext(ctx, query, userID)
+	return err
+}
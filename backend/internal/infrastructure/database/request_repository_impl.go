@@ -19,7 +19,7 @@ func NewDateRequestRepository(db *PostgresDB) repository.DateRequestRepository {
 }
 
 func (r *dateRequestRepository) FindAll(ctx context.Context) ([]*entity.DateRequest, error) {
-	query := `SELECT id, user_id, request_type, title, description, location, status, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), request_type, title, description, location, status, created_at, updated_at 
 			  FROM date_requests ORDER BY created_at DESC`
 
 	rows, err := r.db.DB.QueryContext(ctx, query)
@@ -31,7 +31,7 @@ func (r *dateRequestRepository) FindAll(ctx context.Context) ([]*entity.DateRequ
 	var requests []*entity.DateRequest
 	for rows.Next() {
 		req := &entity.DateRequest{}
-		err := rows.Scan(&req.ID, &req.UserID, &req.RequestType, &req.Title, &req.Description,
+		err := rows.Scan(&req.ID, &req.UserID, &req.CoupleID, &req.RequestType, &req.Title, &req.Description,
 			&req.Location, &req.Status, &req.CreatedAt, &req.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -43,12 +43,12 @@ func (r *dateRequestRepository) FindAll(ctx context.Context) ([]*entity.DateRequ
 }
 
 func (r *dateRequestRepository) FindByID(ctx context.Context, id int64) (*entity.DateRequest, error) {
-	query := `SELECT id, user_id, request_type, title, description, location, status, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), request_type, title, description, location, status, created_at, updated_at 
 			  FROM date_requests WHERE id = $1`
 
 	req := &entity.DateRequest{}
 	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
-		&req.ID, &req.UserID, &req.RequestType, &req.Title, &req.Description,
+		&req.ID, &req.UserID, &req.CoupleID, &req.RequestType, &req.Title, &req.Description,
 		&req.Location, &req.Status, &req.CreatedAt, &req.UpdatedAt,
 	)
 
@@ -63,7 +63,7 @@ func (r *dateRequestRepository) FindByID(ctx context.Context, id int64) (*entity
 }
 
 func (r *dateRequestRepository) FindByUserID(ctx context.Context, userID int64) ([]*entity.DateRequest, error) {
-	query := `SELECT id, user_id, request_type, title, description, location, status, created_at, updated_at 
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), request_type, title, description, location, status, created_at, updated_at 
 			  FROM date_requests WHERE user_id = $1 ORDER BY created_at DESC`
 
 	rows, err := r.db.DB.QueryContext(ctx, query, userID)
@@ -75,7 +75,31 @@ func (r *dateRequestRepository) FindByUserID(ctx context.Context, userID int64)
 	var requests []*entity.DateRequest
 	for rows.Next() {
 		req := &entity.DateRequest{}
-		err := rows.Scan(&req.ID, &req.UserID, &req.RequestType, &req.Title, &req.Description,
+		err := rows.Scan(&req.ID, &req.UserID, &req.CoupleID, &req.RequestType, &req.Title, &req.Description,
+			&req.Location, &req.Status, &req.CreatedAt, &req.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+func (r *dateRequestRepository) FindByCoupleID(ctx context.Context, coupleID int64) ([]*entity.DateRequest, error) {
+	query := `SELECT id, user_id, COALESCE(couple_id, 0), request_type, title, description, location, status, created_at, updated_at 
+			  FROM date_requests WHERE couple_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, coupleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*entity.DateRequest
+	for rows.Next() {
+		req := &entity.DateRequest{}
+		err := rows.Scan(&req.ID, &req.UserID, &req.CoupleID, &req.RequestType, &req.Title, &req.Description,
 			&req.Location, &req.Status, &req.CreatedAt, &req.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -87,11 +111,11 @@ func (r *dateRequestRepository) FindByUserID(ctx context.Context, userID int64)
 }
 
 func (r *dateRequestRepository) Create(ctx context.Context, request *entity.DateRequest) error {
-	query := `INSERT INTO date_requests (user_id, request_type, title, description, location, status, created_at, updated_at) 
-			  VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) RETURNING id`
+	query := `INSERT INTO date_requests (user_id, couple_id, request_type, title, description, location, status, created_at, updated_at) 
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) RETURNING id`
 
 	err := r.db.DB.QueryRowContext(ctx, query,
-		request.UserID, request.RequestType, request.Title, request.Description,
+		request.UserID, request.CoupleID, request.RequestType, request.Title, request.Description,
 		request.Location, request.Status,
 	).Scan(&request.ID)
 
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// scheduledMessageColumns are the scheduled_messages columns read by scanScheduledMessage, in order
+const scheduledMessageColumns = `id, sender_id, receiver_id, kind, title, message, deliver_at, status, message_id, created_at, updated_at`
+
+type scheduledMessageRepository struct {
+	db *PostgresDB
+}
+
+// NewScheduledMessageRepository creates a new scheduled message repository
+func NewScheduledMessageRepository(db *PostgresDB) repository.ScheduledMessageRepository {
+	return &scheduledMessageRepository{db: db}
+}
+
+func scanScheduledMessage(row scanner) (*entity.ScheduledMessage, error) {
+	msg := &entity.ScheduledMessage{}
+	var messageID sql.NullInt64
+	err := row.Scan(&msg.ID, &msg.SenderID, &msg.ReceiverID, &msg.Kind, &msg.Title, &msg.Message,
+		&msg.DeliverAt, &msg.Status, &messageID, &msg.CreatedAt, &msg.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if messageID.Valid {
+		msg.MessageID = &messageID.Int64
+	}
+	return msg, nil
+}
+
+func (r *scheduledMessageRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entity.ScheduledMessage, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*entity.ScheduledMessage
+	for rows.Next() {
+		msg, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *scheduledMessageRepository) Create(ctx context.Context, message *entity.ScheduledMessage) error {
+	query := `INSERT INTO scheduled_messages (sender_id, receiver_id, kind, title, message, deliver_at, status, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW()) RETURNING id, created_at, updated_at`
+
+	message.Status = entity.ScheduledStatusPending
+	return r.db.DB.QueryRowContext(ctx, query,
+		message.SenderID, message.ReceiverID, message.Kind, message.Title, message.Message, message.DeliverAt, message.Status,
+	).Scan(&message.ID, &message.CreatedAt, &message.UpdatedAt)
+}
+
+func (r *scheduledMessageRepository) FindByID(ctx context.Context, id int64) (*entity.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages WHERE id = $1`
+
+	msg, err := scanScheduledMessage(r.db.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrScheduledMessageNotFound
+	}
+	return msg, err
+}
+
+func (r *scheduledMessageRepository) FindPendingBySender(ctx context.Context, senderID int64) ([]*entity.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+			  WHERE sender_id = $1 AND status = 'pending' ORDER BY deliver_at ASC, id ASC`
+
+	return r.query(ctx, query, senderID)
+}
+
+func (r *scheduledMessageRepository) FindPendingByReceiver(ctx context.Context, receiverID int64, kind entity.ScheduledMessageKind) ([]*entity.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+			  WHERE receiver_id = $1 AND kind = $2 AND status = 'pending' ORDER BY deliver_at ASC, id ASC`
+
+	return r.query(ctx, query, receiverID, kind)
+}
+
+func (r *scheduledMessageRepository) Update(ctx context.Context, message *entity.ScheduledMessage) error {
+	query := `UPDATE scheduled_messages SET title = $2, message = $3, deliver_at = $4, updated_at = NOW()
+			  WHERE id = $1 AND status = 'pending' RETURNING updated_at`
+
+	err := r.db.DB.QueryRowContext(ctx, query, message.ID, message.Title, message.Message, message.DeliverAt).
+		Scan(&message.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrScheduledMessageNotFound
+	}
+	return err
+}
+
+func (r *scheduledMessageRepository) Cancel(ctx context.Context, id int64) error {
+	return r.setStatus(ctx, id, entity.ScheduledStatusPending, entity.ScheduledStatusCancelled)
+}
+
+func (r *scheduledMessageRepository) ClaimDue(ctx context.Context, now, reclaimBefore time.Time, limit int) ([]*entity.ScheduledMessage, error) {
+	// SKIP LOCKED lets several instances claim due messages without waiting on each other
+	query := `UPDATE scheduled_messages SET status = 'processing', claimed_at = NOW(), updated_at = NOW()
+			  WHERE id IN (
+				  SELECT id FROM scheduled_messages
+				  WHERE (status = 'pending' AND deliver_at <= $1)
+				  OR (status = 'processing' AND claimed_at < $2)
+				  ORDER BY deliver_at ASC, id ASC LIMIT $3
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + scheduledMessageColumns
+
+	return r.query(ctx, query, now, reclaimBefore, limit)
+}
+
+func (r *scheduledMessageRepository) Release(ctx context.Context, id int64) error {
+	return r.setStatus(ctx, id, entity.ScheduledStatusProcessing, entity.ScheduledStatusPending)
+}
+
+func (r *scheduledMessageRepository) Deliver(ctx context.Context, id int64, message *entity.ChatMessage) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes an instance that claimed it again wait, and then find it delivered
+	var locked int64
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM scheduled_messages WHERE id = $1 AND status = 'processing' FOR UPDATE`, id,
+	).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return repository.ErrScheduledMessageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertChatMessage(ctx, tx, message); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE scheduled_messages SET status = 'delivered', message_id = $2, claimed_at = NULL, updated_at = NOW() WHERE id = $1`,
+		id, message.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setStatus moves a message from one status to another, ErrScheduledMessageNotFound
+// is returned when it is not in the from status
+func (r *scheduledMessageRepository) setStatus(ctx context.Context, id int64, from, to entity.ScheduledMessageStatus) error {
+	query := `UPDATE scheduled_messages SET status = $3, updated_at = NOW() WHERE id = $1 AND status = $2`
+
+	result, err := r.db.DB.ExecContext(ctx, query, id, from, to)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrScheduledMessageNotFound
+	}
+
+	return nil
+}
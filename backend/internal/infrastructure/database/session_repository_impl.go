@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type sessionRepository struct {
+	db *PostgresDB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *PostgresDB) repository.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id string) (*entity.Session, error) {
+	query := `SELECT s.id, s.user_id, COALESCE(s.user_agent, ''), COALESCE(s.ip_address, ''), s.created_at, s.last_seen_at, s.revoked_at,
+			         u.disabled_at IS NOT NULL
+			  FROM sessions s JOIN users u ON u.id = s.user_id
+			  WHERE s.id = $1`
+
+	session := &entity.Session{}
+	var revokedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &revokedAt, &session.UserDisabled,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+
+	return session, nil
+}
+
+func (r *sessionRepository) FindActiveByUserID(ctx context.Context, userID int64) ([]*entity.Session, error) {
+	query := `SELECT id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_seen_at 
+			  FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*entity.Session
+	for rows.Next() {
+		session := &entity.Session{}
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *entity.Session) error {
+	query := `INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at) 
+			  VALUES ($1, $2, $3, $4, NOW(), NOW()) RETURNING created_at, last_seen_at`
+
+	return r.db.DB.QueryRowContext(ctx, query,
+		session.ID, session.UserID, session.UserAgent, session.IPAddress,
+	).Scan(&session.CreatedAt, &session.LastSeenAt)
+}
+
+// Touch bumps last_seen_at at most once a minute so busy clients don't write on every request
+func (r *sessionRepository) Touch(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET last_seen_at = NOW() 
+			  WHERE id = $1 AND revoked_at IS NULL AND last_seen_at < NOW() - INTERVAL '1 minute'`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *sessionRepository) Revoke(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.DB.ExecContext(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+type twoFactorRepository struct {
+	db *PostgresDB
+}
+
+// NewTwoFactorRepository creates a new two-factor repository
+func NewTwoFactorRepository(db *PostgresDB) repository.TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+func (r *twoFactorRepository) FindByUserID(ctx context.Context, userID int64) (*entity.UserTOTP, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at
+			  FROM user_totp WHERE user_id = $1`
+
+	totp := &entity.UserTOTP{}
+	var enabledAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID, &totp.Secret, &enabledAt, &totp.LastUsedStep, &totp.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrTwoFactorNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledAt.Valid {
+		totp.EnabledAt = &enabledAt.Time
+	}
+
+	return totp, nil
+}
+
+func (r *twoFactorRepository) SaveSecret(ctx context.Context, userID int64, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret, created_at) VALUES ($1, $2, NOW())
+			  ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+			  WHERE user_totp.enabled_at IS NULL`
+
+	_, err := r.db.DB.ExecContext(ctx, query, userID, secret)
+	return err
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, userID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE user_totp SET enabled_at = NOW(), last_used_step = $1 WHERE user_id = $2 AND enabled_at IS NULL`,
+		step, userID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrTwoFactorNotFound
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, NOW())`,
+			userID, hash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $1 WHERE user_id = $2 AND last_used_step < $1`
+
+	result, err := r.db.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	query := `UPDATE recovery_codes SET used_at = NOW()
+			  WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userID int64) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
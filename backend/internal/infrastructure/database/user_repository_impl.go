@@ -18,16 +18,51 @@ func NewUserRepository(db *PostgresDB) repository.UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) FindByID(ctx context.Context, id int64) (*entity.User, error) {
-	query := `SELECT id, username, email, phone, password_hash, role, created_at, updated_at 
-			  FROM users WHERE id = $1`
-
+func scanUser(row scanner) (*entity.User, error) {
 	user := &entity.User{}
-	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+	var disabledAt sql.NullTime
+	err := row.Scan(
 		&user.ID, &user.Username, &user.Email, &user.Phone,
-		&user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.PasswordHash, &user.Role, &disabledAt, &user.CreatedAt, &user.UpdatedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) FindAll(ctx context.Context) ([]*entity.User, error) {
+	query := `SELECT id, username, email, phone, password_hash, role, disabled_at, created_at, updated_at 
+			  FROM users ORDER BY id`
 
+	rows, err := r.db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id int64) (*entity.User, error) {
+	query := `SELECT id, username, email, phone, password_hash, role, disabled_at, created_at, updated_at 
+			  FROM users WHERE id = $1`
+
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -39,15 +74,10 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*entity.User,
 }
 
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
-	query := `SELECT id, username, email, phone, password_hash, role, created_at, updated_at 
+	query := `SELECT id, username, email, phone, password_hash, role, disabled_at, created_at, updated_at 
 			  FROM users WHERE email = $1`
 
-	user := &entity.User{}
-	err := r.db.DB.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Username, &user.Email, &user.Phone,
-		&user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
-	)
-
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx, query, email))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -59,15 +89,10 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*entity
 }
 
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
-	query := `SELECT id, username, email, phone, password_hash, role, created_at, updated_at 
+	query := `SELECT id, username, email, phone, password_hash, role, disabled_at, created_at, updated_at 
 			  FROM users WHERE username = $1`
 
-	user := &entity.User{}
-	err := r.db.DB.QueryRowContext(ctx, query, username).Scan(
-		&user.ID, &user.Username, &user.Email, &user.Phone,
-		&user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt,
-	)
-
+	user, err := scanUser(r.db.DB.QueryRowContext(ctx, query, username))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -99,3 +124,50 @@ func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
 
 	return err
 }
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role entity.UserRole) error {
+	query := `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`
+	return r.execOnUser(ctx, query, role, id)
+}
+
+// SetDisabled disables the account, or re-enables it when disabled is false
+func (r *userRepository) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	query := `UPDATE users SET disabled_at = CASE WHEN $1::boolean THEN COALESCE(disabled_at, NOW()) END, 
+			  updated_at = NOW() WHERE id = $2`
+	return r.execOnUser(ctx, query, disabled, id)
+}
+
+// execOnUser runs an update and reports a missing user
+func (r *userRepository) FindCommonPasswordHash(ctx context.Context) (string, error) {
+	// Hashes are grouped by their prefix without salt and key: $2a$10$ for bcrypt
+	// and $argon2id$v=19$m=...,t=...,p=... for argon2id
+	query := `SELECT MIN(password_hash) FROM users
+			  GROUP BY CASE WHEN password_hash LIKE '$argon2id$%'
+			                THEN regexp_replace(password_hash, '\$[^$]*\$[^$]*$', '')
+			                ELSE left(password_hash, 7) END
+			  ORDER BY COUNT(*) DESC LIMIT 1`
+
+	var hash string
+	err := r.db.DB.QueryRowContext(ctx, query).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no users")
+	}
+	return hash, err
+}
+
+func (r *userRepository) execOnUser(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+type AdminHandler struct {
+	userRepo    repository.UserRepository
+	galleryRepo repository.GalleryRepository
+	chatRepo    repository.ChatRepository
+	authHandler *AuthHandler // signs users out and sends reset emails
+	hubs        []*realtime.Hub
+}
+
+func NewAdminHandler(userRepo repository.UserRepository, galleryRepo repository.GalleryRepository, chatRepo repository.ChatRepository, authHandler *AuthHandler) *AdminHandler {
+	return &AdminHandler{
+		userRepo:    userRepo,
+		galleryRepo: galleryRepo,
+		chatRepo:    chatRepo,
+		authHandler: authHandler,
+	}
+}
+
+// WithHubs closes the live chat and notification connections of users when they are disabled
+func (h *AdminHandler) WithHubs(hubs ...*realtime.Hub) *AdminHandler {
+	h.hubs = hubs
+	return h
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ListUsers returns every account
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userRepo.FindAll(r.Context())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch users"}`, http.StatusInternalServerError)
+		return
+	}
+	if users == nil {
+		users = []*entity.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetUserStats returns the storage used by a user's uploads and their message counts
+func (h *AdminHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return
+	}
+
+	items, bytes, err := h.galleryRepo.UsageByUserID(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch storage usage"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sent, received, err := h.chatRepo.CountByUserID(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch message counts"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":           user.ID,
+		"gallery_items":     items,
+		"storage_bytes":     bytes,
+		"messages_sent":     sent,
+		"messages_received": received,
+	})
+}
+
+// UpdateRole changes a user's role and signs them out, so new tokens carry the new role
+func (h *AdminHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	user, claims, ok := h.findOtherUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	role := entity.UserRole(req.Role)
+	if !role.IsValid() {
+		http.Error(w, `{"error": "Invalid role"}`, http.StatusBadRequest)
+		return
+	}
+
+	if role != user.Role {
+		if err := h.userRepo.UpdateRole(r.Context(), user.ID, role); err != nil {
+			http.Error(w, `{"error": "Failed to update role"}`, http.StatusInternalServerError)
+			return
+		}
+		if err := h.authHandler.revokeAllSessions(r.Context(), user.ID); err != nil {
+			http.Error(w, `{"error": "Failed to sign out user"}`, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Admin %d changed role of user %d from %s to %s", claims.UserID, user.ID, user.Role, role)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Role updated successfully"})
+}
+
+// DisableUser blocks logins of a user, signs out all their sessions and closes their open
+// chat and notification streams. Streams held by other API instances end at their next heartbeat.
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	user, claims, ok := h.findOtherUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.SetDisabled(r.Context(), user.ID, true); err != nil {
+		http.Error(w, `{"error": "Failed to disable user"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := h.authHandler.revokeAllSessions(r.Context(), user.ID); err != nil {
+		http.Error(w, `{"error": "Failed to sign out user"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, hub := range h.hubs {
+		hub.Disconnect(user.ID)
+	}
+	log.Printf("Admin %d disabled user %d", claims.UserID, user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User disabled successfully"})
+}
+
+// EnableUser re-enables a disabled user, who then has to log in again
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	user, claims, ok := h.findOtherUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userRepo.SetDisabled(r.Context(), user.ID, false); err != nil {
+		http.Error(w, `{"error": "Failed to enable user"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %d re-enabled user %d", claims.UserID, user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User enabled successfully"})
+}
+
+// ForcePasswordReset replaces the user's password with a random one, signs out every
+// session and emails a reset link, so the user has to choose a new password.
+// The email is sent in the background, a failed delivery only shows up in the log.
+func (h *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, claims, ok := h.findOtherUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Nobody learns this password, it only makes the old one stop working
+	random, err := service.NewTokenID()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := h.authHandler.setPassword(r.Context(), user, random); err != nil {
+		http.Error(w, `{"error": "Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := h.authHandler.sendPasswordReset(r.Context(), user); err != nil {
+		http.Error(w, `{"error": "Failed to send password reset email"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Admin %d forced a password reset of user %d", claims.UserID, user.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset, the reset email is being sent"})
+}
+
+// findUser loads the user named by the {id} path param, writing an error response when it fails
+func (h *AdminHandler) findUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// findOtherUser is findUser for changes admins may not make to their own account,
+// so they cannot lock themselves out
+func (h *AdminHandler) findOtherUser(w http.ResponseWriter, r *http.Request) (*entity.User, *service.Claims, bool) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	user, ok := h.findUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	if user.ID == claims.UserID {
+		http.Error(w, `{"error": "You cannot change your own account here"}`, http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	return user, claims, true
+}
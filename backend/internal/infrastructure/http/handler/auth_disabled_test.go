@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+// fakeUserRepo is an in-memory UserRepository
+type fakeUserRepo struct {
+	users []*entity.User
+}
+
+func (f *fakeUserRepo) FindAll(ctx context.Context) ([]*entity.User, error) {
+	return f.users, nil
+}
+
+func (f *fakeUserRepo) FindByID(ctx context.Context, id int64) (*entity.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) FindByUsername(ctx context.Context, username string) (*entity.User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (f *fakeUserRepo) Create(ctx context.Context, user *entity.User) error {
+	f.users = append(f.users, user)
+	return nil
+}
+
+func (f *fakeUserRepo) Update(ctx context.Context, user *entity.User) error {
+	return nil
+}
+
+func (f *fakeUserRepo) UpdateRole(ctx context.Context, id int64, role entity.UserRole) error {
+	return nil
+}
+
+func (f *fakeUserRepo) SetDisabled(ctx context.Context, id int64, disabled bool) error {
+	return nil
+}
+
+func (f *fakeUserRepo) FindCommonPasswordHash(ctx context.Context) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+// fakeSessionRepo is an in-memory SessionRepository
+type fakeSessionRepo struct {
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionRepo) FindByID(ctx context.Context, id string) (*entity.Session, error) {
+	if session, ok := f.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, repository.ErrSessionNotFound
+}
+
+func (f *fakeSessionRepo) FindActiveByUserID(ctx context.Context, userID int64) ([]*entity.Session, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionRepo) Create(ctx context.Context, session *entity.Session) error {
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeSessionRepo) Touch(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) Revoke(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+// fakeRefreshTokenRepo is an in-memory RefreshTokenRepository
+type fakeRefreshTokenRepo struct {
+	tokens map[string]*entity.RefreshToken
+}
+
+func (f *fakeRefreshTokenRepo) FindByJTI(ctx context.Context, jti string) (*entity.RefreshToken, error) {
+	if token, ok := f.tokens[jti]; ok {
+		return token, nil
+	}
+	return nil, repository.ErrRefreshTokenNotFound
+}
+
+func (f *fakeRefreshTokenRepo) Create(ctx context.Context, token *entity.RefreshToken) error {
+	f.tokens[token.JTI] = token
+	return nil
+}
+
+func (f *fakeRefreshTokenRepo) Rotate(ctx context.Context, jti, replacedBy string) error {
+	return nil
+}
+
+func (f *fakeRefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (f *fakeRefreshTokenRepo) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+// newDisabledAccountHandler returns an AuthHandler with user 1 (irfan123), disabled by an admin
+func newDisabledAccountHandler(t *testing.T) (*AuthHandler, *entity.User) {
+	t.Helper()
+	hasher, err := service.NewPasswordHasher(service.HashAlgorithmBcrypt, 4, service.DefaultArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authService := service.NewAuthService("test-secret").WithPasswordHasher(hasher)
+	hash, err := authService.HashPassword("irfan123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disabledAt := time.Now()
+	user := &entity.User{ID: 1, Username: "irfan", Email: "irfan@fasisi.com", PasswordHash: hash, Role: entity.RoleSuperAdmin, DisabledAt: &disabledAt}
+	h := &AuthHandler{
+		userRepo:         &fakeUserRepo{users: []*entity.User{user}},
+		refreshTokenRepo: &fakeRefreshTokenRepo{tokens: map[string]*entity.RefreshToken{}},
+		sessionRepo:      &fakeSessionRepo{sessions: map[string]*entity.Session{}},
+		authService:      authService,
+		loginThrottle:    service.NewLoginThrottle(),
+	}
+	return h, user
+}
+
+func TestAuthHandler_LoginDisabledAccount(t *testing.T) {
+	h, _ := newDisabledAccountHandler(t)
+
+	body, _ := json.Marshal(map[string]string{"email": "irfan@fasisi.com", "password": "irfan123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.Login(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+}
+
+func TestAuthHandler_RefreshDisabledAccount(t *testing.T) {
+	tests := []struct {
+		name            string
+		userDisabled    bool
+		sessionDisabled bool
+	}{
+		{"account disabled", true, true},
+		// The account state read with the session is enough to reject the refresh
+		{"session of a disabled account", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, user := newDisabledAccountHandler(t)
+			if !tt.userDisabled {
+				user.DisabledAt = nil
+			}
+			h.sessionRepo.Create(context.Background(), &entity.Session{ID: "family", UserID: user.ID, UserDisabled: tt.sessionDisabled})
+
+			refreshToken, err := h.issueRefreshToken(context.Background(), user, "family", "jti")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+			h.RefreshToken(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_DisableUserClosesStreams(t *testing.T) {
+	authHandler, admin := newDisabledAccountHandler(t)
+	admin.DisabledAt = nil
+	authHandler.userRepo.Create(context.Background(), &entity.User{ID: 2, Username: "sisti", Email: "sisti@fasisi.com", Role: entity.RoleUser})
+
+	chatHub, notifHub := realtime.NewHub(), realtime.NewHub()
+	chatSub, notifSub := chatHub.Subscribe(2), notifHub.Subscribe(2)
+	h := NewAdminHandler(authHandler.userRepo, nil, nil, authHandler).WithHubs(chatHub, notifHub)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/admin/users/2/disable", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "2"})
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, &service.Claims{UserID: admin.ID}))
+	w := httptest.NewRecorder()
+	h.DisableUser(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	for _, sub := range []*realtime.Subscription{chatSub, notifSub} {
+		if _, ok := <-sub.C; ok {
+			t.Error("expected the disabled user's stream to be closed")
+		}
+	}
+}
@@ -1,26 +1,81 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	netmail "net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/mail"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
 )
 
+// minPasswordLength is the minimum length of new passwords
+const minPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,50}$`)
+
 type AuthHandler struct {
-	userRepo    repository.UserRepository
-	authService *service.AuthService
+	userRepo          repository.UserRepository
+	refreshTokenRepo  repository.RefreshTokenRepository
+	sessionRepo       repository.SessionRepository
+	passwordResetRepo repository.PasswordResetTokenRepository
+	notifRepo         repository.NotificationRepository
+	authService       *service.AuthService
+	twoFactorService  *service.TwoFactorService
+	loginThrottle     *service.LoginThrottle
+	mailer            mail.Mailer
+	appBaseURL        string // frontend URL used in emailed links
+
+	// Optional, adds the partner's presence to the profile
+	presence      *realtime.PresenceTracker
+	coupleService *service.CoupleService
 }
 
-func NewAuthHandler(userRepo repository.UserRepository, authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	sessionRepo repository.SessionRepository,
+	passwordResetRepo repository.PasswordResetTokenRepository,
+	notifRepo repository.NotificationRepository,
+	authService *service.AuthService,
+	twoFactorService *service.TwoFactorService,
+	loginThrottle *service.LoginThrottle,
+	mailer mail.Mailer,
+	appBaseURL string,
+) *AuthHandler {
 	return &AuthHandler{
-		userRepo:    userRepo,
-		authService: authService,
+		userRepo:          userRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		sessionRepo:       sessionRepo,
+		passwordResetRepo: passwordResetRepo,
+		notifRepo:         notifRepo,
+		authService:       authService,
+		twoFactorService:  twoFactorService,
+		loginThrottle:     loginThrottle,
+		mailer:            mailer,
+		appBaseURL:        strings.TrimRight(appBaseURL, "/"),
 	}
 }
 
+// WithPresence adds the presence of the user's partner to the profile
+func (h *AuthHandler) WithPresence(presence *realtime.PresenceTracker, coupleService *service.CoupleService) *AuthHandler {
+	h.presence = presence
+	h.coupleService = coupleService
+	return h
+}
+
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -41,33 +96,85 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userRepo.FindByEmail(r.Context(), req.Email)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	ip := middleware.ClientIP(r)
+
+	if wait := h.loginThrottle.Check(email, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	user, err := h.userRepo.FindByEmail(r.Context(), email)
 	if err != nil {
+		// Spend the same time as a wrong password so unknown emails are not revealed
+		h.authService.DummyCheckPassword(req.Password)
+		h.loginThrottle.Failure(email, ip)
 		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
 		return
 	}
 
 	if !h.authService.CheckPassword(req.Password, user.PasswordHash) {
+		h.recordFailedLogin(r.Context(), email, ip, user)
 		http.Error(w, `{"error": "Invalid credentials"}`, http.StatusUnauthorized)
 		return
 	}
+	h.loginThrottle.Success(email)
+	h.upgradePasswordHash(r.Context(), user, req.Password)
+
+	if user.IsDisabled() {
+		writeAccountDisabled(w)
+		return
+	}
+
+	twoFactorEnabled, err := h.twoFactorService.IsEnabled(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to log in"}`, http.StatusInternalServerError)
+		return
+	}
+	if twoFactorEnabled {
+		h.writeTwoFactorChallenge(w, user)
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusOK, "Login successful", user)
+}
+
+// writeAuthResponse starts a new session, issues access and refresh tokens for the user and writes a LoginResponse
+func (h *AuthHandler) writeAuthResponse(w http.ResponseWriter, r *http.Request, status int, message string, user *entity.User) {
+	// Every login starts a new session; its ID is also the refresh token family
+	familyID, err := service.NewTokenID()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate refresh token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session := &entity.Session{
+		ID:        familyID,
+		UserID:    user.ID,
+		UserAgent: truncate(r.UserAgent(), 512),
+		IPAddress: middleware.ClientIP(r),
+	}
+	if err := h.sessionRepo.Create(r.Context(), session); err != nil {
+		http.Error(w, `{"error": "Failed to create session"}`, http.StatusInternalServerError)
+		return
+	}
 
 	// Generate access token (15 minutes)
-	token, err := h.authService.GenerateToken(user.ID, user.Username, string(user.Role))
+	token, err := h.authService.GenerateToken(user.ID, user.Username, string(user.Role), session.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
 		return
 	}
 
 	// Generate refresh token (7 days)
-	refreshToken, err := h.authService.GenerateRefreshToken(user.ID, user.Username, string(user.Role))
+	refreshToken, err := h.createRefreshToken(r.Context(), user, familyID)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to generate refresh token"}`, http.StatusInternalServerError)
 		return
 	}
 
 	response := LoginResponse{
-		Message:      "Login successful",
+		Message:      message,
 		Token:        token,
 		RefreshToken: refreshToken,
 		ExpiresIn:    900, // 15 minutes in seconds
@@ -81,9 +188,79 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(response)
 }
 
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password"`
+}
+
+// Register creates a new account and logs it in.
+// The new user is unpaired until they create or redeem a couple invite code.
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Phone = strings.TrimSpace(req.Phone)
+
+	if !usernamePattern.MatchString(req.Username) {
+		http.Error(w, `{"error": "Username must be 3-50 characters of letters, numbers or underscores"}`, http.StatusBadRequest)
+		return
+	}
+	if addr, err := netmail.ParseAddress(req.Email); err != nil || addr.Address != req.Email || len(req.Email) > 100 {
+		http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Phone) > 20 {
+		http.Error(w, `{"error": "Phone number is too long"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, `{"error": "Password must be at least 8 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Uniqueness checks
+	if _, err := h.userRepo.FindByEmail(r.Context(), req.Email); err == nil {
+		http.Error(w, `{"error": "Email is already registered"}`, http.StatusConflict)
+		return
+	}
+	if _, err := h.userRepo.FindByUsername(r.Context(), req.Username); err == nil {
+		http.Error(w, `{"error": "Username is already taken"}`, http.StatusConflict)
+		return
+	}
+
+	hash, err := h.authService.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create account"}`, http.StatusInternalServerError)
+		return
+	}
+
+	user := &entity.User{
+		Username:     req.Username,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		PasswordHash: hash,
+		Role:         entity.RoleUser,
+	}
+	if err := h.userRepo.Create(r.Context(), user); err != nil {
+		// Most likely a concurrent registration with the same email or username
+		http.Error(w, `{"error": "Failed to create account"}`, http.StatusConflict)
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusCreated, "Registration successful", user)
+}
+
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
 	if !ok {
@@ -97,22 +274,49 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	twoFactorEnabled, err := h.twoFactorService.IsEnabled(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch profile"}`, http.StatusInternalServerError)
+		return
+	}
+
+	profile := map[string]interface{}{
+		"id":                 user.ID,
+		"username":           user.Username,
+		"email":              user.Email,
+		"phone":              user.Phone,
+		"role":               user.Role,
+		"permissions":        user.Role.Permissions(),
+		"two_factor_enabled": twoFactorEnabled,
+		"created_at":         user.CreatedAt,
+	}
+
+	// partner_presence is null for users without a partner
+	if h.presence != nil {
+		var partnerPresence *realtime.Presence
+		partnerID, err := h.coupleService.PartnerID(r.Context(), user.ID)
+		switch {
+		case err == nil:
+			presence := h.presence.Presence(partnerID)
+			partnerPresence = &presence
+		case !errors.Is(err, service.ErrNoPartner):
+			http.Error(w, `{"error": "Failed to fetch profile"}`, http.StatusInternalServerError)
+			return
+		}
+		profile["partner_presence"] = partnerPresence
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"phone":      user.Phone,
-		"role":       user.Role,
-		"created_at": user.CreatedAt,
-	})
+	json.NewEncoder(w).Encode(profile)
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-// RefreshToken refreshes an access token using refresh token
+// RefreshToken rotates a refresh token and issues a new access token.
+// Presenting a refresh token that was already rotated revokes its whole family,
+// since it means the token was stolen or replayed.
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -121,22 +325,288 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate refresh token
-	claims, err := h.authService.ValidateToken(req.RefreshToken)
+	claims, err := h.authService.ValidateRefreshToken(req.RefreshToken)
 	if err != nil {
 		http.Error(w, `{"error": "Invalid or expired refresh token"}`, http.StatusUnauthorized)
 		return
 	}
 
+	stored, err := h.refreshTokenRepo.FindByJTI(r.Context(), claims.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired refresh token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if stored.IsRevoked() {
+		h.refreshTokenRepo.RevokeFamily(r.Context(), stored.FamilyID)
+		http.Error(w, `{"error": "Refresh token reuse detected, please log in again"}`, http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.sessionRepo.FindByID(r.Context(), stored.FamilyID)
+	if err != nil || !session.IsActive() {
+		http.Error(w, `{"error": "Session has been revoked"}`, http.StatusUnauthorized)
+		return
+	}
+	if session.UserDisabled {
+		writeAccountDisabled(w)
+		return
+	}
+
+	// Reload the user so role changes are picked up
+	user, err := h.userRepo.FindByID(r.Context(), stored.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired refresh token"}`, http.StatusUnauthorized)
+		return
+	}
+	if user.IsDisabled() {
+		writeAccountDisabled(w)
+		return
+	}
+
+	newJTI, err := service.NewTokenID()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate new token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Rotation is atomic: a concurrent request with the same token loses and counts as reuse
+	if err := h.refreshTokenRepo.Rotate(r.Context(), stored.JTI, newJTI); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+			h.refreshTokenRepo.RevokeFamily(r.Context(), stored.FamilyID)
+			http.Error(w, `{"error": "Refresh token reuse detected, please log in again"}`, http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, `{"error": "Failed to rotate refresh token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	newRefreshToken, err := h.issueRefreshToken(r.Context(), user, stored.FamilyID, newJTI)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate new token"}`, http.StatusInternalServerError)
+		return
+	}
+
 	// Generate new access token
-	newToken, err := h.authService.GenerateToken(claims.UserID, claims.Username, claims.Role)
+	newToken, err := h.authService.GenerateToken(user.ID, user.Username, string(user.Role), session.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to generate new token"}`, http.StatusInternalServerError)
 		return
 	}
 
+	h.sessionRepo.Touch(r.Context(), session.ID)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token":      newToken,
-		"expires_in": 900, // 15 minutes
+		"token":         newToken,
+		"refresh_token": newRefreshToken,
+		"expires_in":    900, // 15 minutes
 	})
 }
+
+// Logout ends the current device's session and revokes its refresh token family
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, `{"error": "refresh_token is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.authService.ValidateRefreshToken(req.RefreshToken)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired refresh token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	stored, err := h.refreshTokenRepo.FindByJTI(r.Context(), claims.ID)
+	if err == nil {
+		if err := h.revokeSession(r.Context(), stored.FamilyID); err != nil {
+			http.Error(w, `{"error": "Failed to log out"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
+
+// LogoutAll ends every session and revokes every refresh token of the current user
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.revokeAllSessions(r.Context(), claims.UserID); err != nil {
+		http.Error(w, `{"error": "Failed to log out"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out from all devices"})
+}
+
+// JWKS publishes the public keys that verify access tokens, so other services
+// can check tokens without sharing a secret
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.authService.JWKS())
+}
+
+// ListSessions returns the active sessions (signed-in devices) of the current user
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessionRepo.FindActiveByUserID(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch sessions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, map[string]interface{}{
+			"id":           s.ID,
+			"user_agent":   s.UserAgent,
+			"ip_address":   s.IPAddress,
+			"created_at":   s.CreatedAt,
+			"last_seen_at": s.LastSeenAt,
+			"current":      s.ID == claims.SessionID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// RevokeSession signs out one of the current user's devices
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	// Sessions of other users are reported as missing
+	session, err := h.sessionRepo.FindByID(r.Context(), id)
+	if err != nil || session.UserID != claims.UserID || !session.IsActive() {
+		http.Error(w, `{"error": "Session not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if err := h.revokeSession(r.Context(), session.ID); err != nil {
+		http.Error(w, `{"error": "Failed to revoke session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked successfully"})
+}
+
+// revokeSession revokes a session together with its refresh token family,
+// so neither its access tokens nor its refresh token can be used again
+func (h *AuthHandler) revokeSession(ctx context.Context, sessionID string) error {
+	if err := h.sessionRepo.Revoke(ctx, sessionID); err != nil {
+		return err
+	}
+	return h.refreshTokenRepo.RevokeFamily(ctx, sessionID)
+}
+
+// upgradePasswordHash rehashes a verified password whose stored hash uses an outdated
+// algorithm or cost. Failures are only logged, the login itself already succeeded.
+func (h *AuthHandler) upgradePasswordHash(ctx context.Context, user *entity.User, password string) {
+	if !h.authService.PasswordNeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hash, err := h.authService.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+
+	user.PasswordHash = hash
+	if err := h.userRepo.Update(ctx, user); err != nil {
+		log.Printf("Failed to store upgraded password hash of user %d: %v", user.ID, err)
+	}
+}
+
+// recordFailedLogin counts a failed attempt and warns the account owner when it locks the account
+func (h *AuthHandler) recordFailedLogin(ctx context.Context, account, ip string, user *entity.User) {
+	if !h.loginThrottle.Failure(account, ip) {
+		return
+	}
+
+	notif := &entity.Notification{
+		UserID:     user.ID,
+		Type:       entity.NotificationTypeSecurityAlert,
+		Message:    "Login ke akunmu diblokir sementara setelah terlalu banyak percobaan yang gagal dari IP " + ip,
+		ReadStatus: false,
+	}
+	h.notifRepo.Create(ctx, notif)
+}
+
+// writeAccountDisabled rejects a login or refresh of an account disabled by an admin
+func writeAccountDisabled(w http.ResponseWriter) {
+	http.Error(w, `{"error": "Account is disabled"}`, http.StatusForbidden)
+}
+
+// writeTooManyAttempts rejects a throttled login attempt
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	writeTooManyRequests(w, wait, "Too many failed attempts, please try again later")
+}
+
+// writeTooManyRequests rejects a throttled request, telling the client when to retry
+func writeTooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, `{"error": "`+message+`"}`, http.StatusTooManyRequests)
+}
+
+// revokeAllSessions ends every session of the user and revokes all their refresh tokens
+func (h *AuthHandler) revokeAllSessions(ctx context.Context, userID int64) error {
+	if err := h.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return h.refreshTokenRepo.RevokeAllForUser(ctx, userID)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// createRefreshToken starts a new refresh token in the given family
+func (h *AuthHandler) createRefreshToken(ctx context.Context, user *entity.User, familyID string) (string, error) {
+	jti, err := service.NewTokenID()
+	if err != nil {
+		return "", err
+	}
+	return h.issueRefreshToken(ctx, user, familyID, jti)
+}
+
+// issueRefreshToken persists a refresh token record and returns the signed JWT
+func (h *AuthHandler) issueRefreshToken(ctx context.Context, user *entity.User, familyID, jti string) (string, error) {
+	record := &entity.RefreshToken{
+		JTI:       jti,
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(service.RefreshTokenTTL),
+	}
+	if err := h.refreshTokenRepo.Create(ctx, record); err != nil {
+		return "", err
+	}
+
+	return h.authService.GenerateRefreshToken(user.ID, user.Username, string(user.Role), jti)
+}
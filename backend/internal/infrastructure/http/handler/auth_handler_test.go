@@ -6,8 +6,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-
-	"github.com/gorilla/mux"
 )
 
 func TestAuthHandler_Login(t *testing.T) {
@@ -78,9 +76,6 @@ func TestAuthHandler_Login(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body))
 			req.Header.Set("Content-Type", "application/json")
 
-			// Record response
-			w := httptest.NewRecorder()
-
 			// Note: This is a placeholder test structure
 			// In actual implementation, you would initialize the handler with mock repositories
 			// and call handler.Login(w, req)
@@ -125,8 +120,6 @@ func TestAuthHandler_GetProfile(t *testing.T) {
 				req.Header.Set("Authorization", tt.authHeader)
 			}
 
-			w := httptest.NewRecorder()
-
 			t.Logf("Test %s expects status %d", tt.name, tt.expectedStatus)
 		})
 	}
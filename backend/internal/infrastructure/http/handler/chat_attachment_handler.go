@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// Durasi maksimum lampiran audio dan video dalam detik
+const maxAttachmentDuration = 10 * 60
+
+// parseAttachmentForm membaca field message, reply_to_id dan duration dari form multipart
+// pesan dengan lampiran
+func parseAttachmentForm(form url.Values) (SendMessageReq, float64, error) {
+	req := SendMessageReq{Message: form.Get("message")}
+
+	if form.Get("deliver_at") != "" {
+		return req, 0, errors.New("Messages with attachments cannot be scheduled")
+	}
+
+	if v := form.Get("reply_to_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return req, 0, errors.New("Invalid reply_to_id")
+		}
+		req.ReplyToID = id
+	}
+
+	var duration float64
+	if v := form.Get("duration"); v != "" {
+		d, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(d > 0 && d <= maxAttachmentDuration) {
+			return req, 0, fmt.Errorf("duration must be between 0 and %d seconds", maxAttachmentDuration)
+		}
+		duration = d
+	}
+
+	return req, duration, nil
+}
+
+// PromoteToGalleryReq adalah struktur request untuk menyimpan lampiran ke galeri
+// Field:
+//   - Caption: Caption item galeri (opsional, default isi pesan)
+type PromoteToGalleryReq struct {
+	Caption *string `json:"caption"`
+}
+
+// PromoteToGallery menyimpan foto atau video dari sebuah pesan ke galeri pasangan
+// Endpoint: POST /api/chat/messages/{id}/gallery
+// Authentication: Membutuhkan JWT token
+//
+// Request Body (opsional):
+//
+//	{
+//	  "caption": "Makan malam pertama kita"
+//	}
+//
+// Cara kerja:
+// 1. Memastikan pesan ada di percakapan user dengan pasangannya dan punya lampiran foto/video
+// 2. Menyalin file lampiran, sehingga item galeri tetap ada walaupun pesannya dihapus
+// 3. Membuat item galeri atas nama user yang menyimpan
+// 4. Mengirim notifikasi gallery_upload ke pasangan, sama seperti upload ke galeri
+//
+// Response:
+//   - 201 Created: Item galeri berhasil dibuat
+//   - 400 Bad Request: Pesan tidak punya lampiran foto atau video
+//   - 404 Not Found: Pesan tidak ditemukan
+//   - 500 Internal Server Error: Gagal menyimpan item galeri
+func (h *ChatHandler) PromoteToGallery(w http.ResponseWriter, r *http.Request) {
+	claims, message, partnerID, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	// Body boleh kosong
+	var req PromoteToGalleryReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+		return
+	}
+
+	attachment := message.Attachment
+	if attachment == nil || attachment.FileType == entity.FileTypeAudio {
+		http.Error(w, `{"error": "Only photo and video attachments can be added to the gallery"}`, http.StatusBadRequest)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	upload, err := copyUpload(attachment.FilePath, claims.UserID, galleryUploads.location)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to copy attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	caption := message.Message
+	if req.Caption != nil {
+		caption = *req.Caption
+	}
+
+	gallery := &entity.Gallery{
+		UserID:   claims.UserID,
+		CoupleID: couple.ID,
+		FileType: attachment.FileType,
+		FilePath: upload.FilePath,
+		Caption:  caption,
+		FileSize: upload.Size,
+	}
+	if err := h.galleryRepo.Create(r.Context(), gallery); err != nil {
+		os.Remove(upload.DiskPath)
+		http.Error(w, `{"error": "Failed to create gallery item"}`, http.StatusInternalServerError)
+		return
+	}
+
+	fileTypeStr := "foto"
+	if gallery.FileType == entity.FileTypeVideo {
+		fileTypeStr = "video"
+	}
+	notif := &entity.Notification{
+		UserID:     partnerID,
+		Type:       entity.NotificationTypeGalleryUpload,
+		Message:    claims.Username + " menambahkan " + fileTypeStr + " dari chat ke galeri",
+		RelatedID:  gallery.ID,
+		ReadStatus: false,
+	}
+	h.notifRepo.Create(r.Context(), notif)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Added to gallery",
+		"item":    gallery,
+	})
+}
+
+// GetAttachment mengirim file lampiran chat ke user yang ada di percakapannya
+// Endpoint: GET /api/chat/attachments/{name}
+// Authentication: Membutuhkan JWT token
+//
+// Cara kerja:
+// 1. Mencari pesan yang lampirannya disimpan dengan nama file tersebut
+// 2. Memastikan pesan ada di percakapan user dengan pasangannya, lampiran percakapan lain dianggap tidak ada
+// 3. Mengirim file dengan Content-Type dari tipe MIME yang divalidasi saat upload, bukan dari nama file
+//
+// Response:
+//   - 200 OK: Isi file (mendukung Range request untuk audio dan video)
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 404 Not Found: Lampiran tidak ditemukan
+func (h *ChatHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	filePath := chatAttachments.urlPrefix + mux.Vars(r)["name"]
+	diskPath, ok := diskPathOf(filePath)
+	if !ok {
+		http.Error(w, `{"error": "Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	message, err := h.chatRepo.FindByAttachmentPath(r.Context(), filePath)
+	if errors.Is(err, repository.ErrChatMessageNotFound) || (err == nil && !message.IsBetween(claims.UserID, partnerID)) {
+		http.Error(w, `{"error": "Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	file, err := os.Open(diskPath)
+	if err != nil {
+		http.Error(w, `{"error": "Attachment not found"}`, http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch attachment"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", message.Attachment.MimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
@@ -0,0 +1,512 @@
+package handler
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// Format ekspor chat yang didukung
+const (
+	exportFormatJSON = "json"
+	exportFormatHTML = "html"
+	exportFormatText = "txt"
+)
+
+// exportContentTypes adalah Content-Type untuk setiap format ekspor
+var exportContentTypes = map[string]string{
+	exportFormatJSON: "application/json",
+	exportFormatHTML: "text/html; charset=utf-8",
+	exportFormatText: "text/plain; charset=utf-8",
+}
+
+// ChatExportHandler menangani ekspor percakapan untuk disimpan offline
+type ChatExportHandler struct {
+	chatRepo      repository.ChatRepository
+	userRepo      repository.UserRepository
+	coupleService *service.CoupleService
+}
+
+// NewChatExportHandler membuat instance baru dari ChatExportHandler
+func NewChatExportHandler(chatRepo repository.ChatRepository, userRepo repository.UserRepository, coupleService *service.CoupleService) *ChatExportHandler {
+	return &ChatExportHandler{
+		chatRepo:      chatRepo,
+		userRepo:      userRepo,
+		coupleService: coupleService,
+	}
+}
+
+// exportParams adalah query parameter dari GET /api/chat/export
+type exportParams struct {
+	Format string
+	Zip    bool
+	From   time.Time // inklusif, zero = sejak pesan pertama
+	To     time.Time // eksklusif, zero = sampai pesan terakhir
+}
+
+// parseExportParams membaca format, zip, from dan to.
+// from dan to menerima RFC 3339 atau tanggal (YYYY-MM-DD); tanggal pada to ikut diekspor seharian penuh.
+// Offset pada waktu RFC 3339 ikut diperhitungkan, tanggal memakai zona waktu server.
+func parseExportParams(query url.Values) (exportParams, error) {
+	params := exportParams{Format: query.Get("format")}
+	if params.Format == "" {
+		params.Format = exportFormatJSON
+	}
+	if _, ok := exportContentTypes[params.Format]; !ok {
+		return params, errors.New("format must be json, html or txt")
+	}
+
+	if value := query.Get("zip"); value != "" {
+		zipped, err := strconv.ParseBool(value)
+		if err != nil {
+			return params, errors.New("zip must be true or false")
+		}
+		params.Zip = zipped
+	}
+
+	var err error
+	if params.From, err = parseExportTime(query.Get("from"), false); err != nil {
+		return params, errors.New("from must be a date (YYYY-MM-DD) or an RFC 3339 time")
+	}
+	if params.To, err = parseExportTime(query.Get("to"), true); err != nil {
+		return params, errors.New("to must be a date (YYYY-MM-DD) or an RFC 3339 time")
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return params, errors.New("from must be before to")
+	}
+
+	return params, nil
+}
+
+// parseExportTime membaca waktu RFC 3339 atau tanggal, endOfDay menggeser tanggal ke awal hari berikutnya
+func parseExportTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// ExportChat mengunduh percakapan dengan pasangan sebagai arsip JSON, HTML atau teks
+// Endpoint: GET /api/chat/export?format=json|html|txt&from=&to=&zip=true
+// Authentication: Membutuhkan JWT token
+//
+// Query Parameters:
+//   - format: json (default), html atau txt
+//   - from: Awal rentang (opsional), tanggal YYYY-MM-DD atau waktu RFC 3339
+//   - to: Akhir rentang (opsional), tanggal pada to ikut diekspor seharian penuh
+//   - zip: true untuk file ZIP berisi arsip chat beserta semua foto, video dan voice note-nya
+//
+// Cara kerja:
+//  1. Validasi query parameter dan tentukan partner lewat CoupleService
+//  2. Ambil nama kedua user untuk ditampilkan sebagai pengirim
+//  3. Pesan dibaca dari database satu per satu dan langsung ditulis ke response,
+//     sehingga percakapan tidak pernah dimuat seluruhnya ke memory
+//  4. Lampiran dirujuk dengan path-nya; di dalam ZIP path menjadi relatif ke folder uploads/ di arsip.
+//     Tanpa ZIP lampiran chat hanya bisa dibuka dengan token, jadi halaman HTML menyarankan ekspor ZIP
+//     untuk salinan offline
+//
+// Pesan yang dihapus tidak ikut diekspor.
+//
+// Response:
+//   - 200 OK: File arsip (Content-Disposition: attachment)
+//   - 400 Bad Request: Query parameter tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 500 Internal Server Error: Gagal mengambil data user
+func (h *ChatExportHandler) ExportChat(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	params, err := parseExportParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	export := &chatExport{
+		userID:     claims.UserID,
+		partnerID:  partnerID,
+		names:      map[int64]string{},
+		from:       params.From,
+		to:         params.To,
+		exportedAt: time.Now(),
+	}
+	for _, id := range []int64{claims.UserID, partnerID} {
+		user, err := h.userRepo.FindByID(r.Context(), id)
+		if err != nil {
+			http.Error(w, `{"error": "Failed to fetch users"}`, http.StatusInternalServerError)
+			return
+		}
+		export.names[id] = user.Username
+	}
+
+	filename := "fasisi-chat-" + export.exportedAt.Format("20060102")
+	if params.Zip {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+		err = h.writeZip(r.Context(), w, export, params.Format)
+	} else {
+		w.Header().Set("Content-Type", exportContentTypes[params.Format])
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.`+params.Format+`"`)
+		err = h.writeArchive(r.Context(), w, export, params.Format)
+	}
+
+	// Header sudah terkirim, error di tengah jalan hanya bisa dicatat dan arsipnya terpotong
+	if err != nil {
+		log.Printf("Failed to export chat of user %d: %v", claims.UserID, err)
+	}
+}
+
+// writeArchive menulis arsip chat dalam format yang diminta ke w
+func (h *ChatExportHandler) writeArchive(ctx context.Context, w io.Writer, export *chatExport, format string) error {
+	export.stream = func(fn func(*entity.ChatMessage) error) error {
+		return h.chatRepo.StreamHistory(ctx, export.userID, export.partnerID, export.from, export.to, fn)
+	}
+
+	buf := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case exportFormatHTML:
+		err = export.writeHTML(buf)
+	case exportFormatText:
+		err = export.writeText(buf)
+	default:
+		err = export.writeJSON(buf)
+	}
+	if err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// writeZip menulis ZIP berisi arsip chat dan file lampiran yang dirujuk olehnya.
+// Lampiran disimpan di uploads/ dan arsip merujuknya dengan path relatif.
+func (h *ChatExportHandler) writeZip(ctx context.Context, w io.Writer, export *chatExport, format string) error {
+	archive := zip.NewWriter(w)
+
+	var media []string
+	seen := map[string]bool{}
+	export.mediaPath = func(filePath string) string {
+		if !isUpload(filePath) {
+			return filePath
+		}
+		if !seen[filePath] {
+			seen[filePath] = true
+			media = append(media, filePath)
+		}
+		return zipMediaPath(filePath)
+	}
+
+	chat, err := archive.Create("chat." + format)
+	if err != nil {
+		return err
+	}
+	if err := h.writeArchive(ctx, chat, export, format); err != nil {
+		return err
+	}
+
+	for _, filePath := range media {
+		if err := addZipMedia(archive, filePath); err != nil {
+			// File yang hilang tidak menggagalkan ekspor, pesannya tetap merujuk ke path-nya
+			log.Printf("Skipping %s in chat export: %v", filePath, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+// zipMediaPath adalah path file lampiran di dalam ZIP ekspor
+func zipMediaPath(filePath string) string {
+	return "uploads/" + path.Base(filePath)
+}
+
+// addZipMedia menyalin file lampiran dari folder uploads ke ZIP
+func addZipMedia(archive *zip.Writer, filePath string) error {
+	diskPath, _ := diskPathOf(filePath)
+	src, err := os.Open(diskPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// Foto dan video sudah terkompresi, jadi disimpan tanpa kompresi ulang
+	dst, err := archive.CreateHeader(&zip.FileHeader{Name: zipMediaPath(filePath), Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// chatExport berisi data yang dibutuhkan untuk menulis satu arsip chat
+type chatExport struct {
+	userID     int64
+	partnerID  int64
+	names      map[int64]string
+	from       time.Time
+	to         time.Time
+	exportedAt time.Time
+
+	// stream memanggil fn untuk setiap pesan yang diekspor, urut dari yang paling lama
+	stream func(fn func(*entity.ChatMessage) error) error
+	// mediaPath mengubah path lampiran menjadi path yang dirujuk oleh arsip, nil = tidak diubah
+	mediaPath func(filePath string) string
+}
+
+// exportParticipant adalah user yang tercantum di arsip JSON
+type exportParticipant struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// nameOf mengembalikan nama pengirim pesan
+func (e *chatExport) nameOf(userID int64) string {
+	if name, ok := e.names[userID]; ok {
+		return name
+	}
+	return fmt.Sprintf("User %d", userID)
+}
+
+// attachmentOf mengembalikan lampiran pesan dengan path yang dirujuk oleh arsip
+func (e *chatExport) attachmentOf(msg *entity.ChatMessage) *entity.ChatAttachment {
+	if msg.Attachment == nil || e.mediaPath == nil {
+		return msg.Attachment
+	}
+	attachment := *msg.Attachment
+	attachment.FilePath = e.mediaPath(attachment.FilePath)
+	return &attachment
+}
+
+// optionalTime mengembalikan nil untuk waktu zero, untuk ditulis sebagai null di JSON
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// writeJSON menulis arsip sebagai satu objek JSON, pesan ditulis satu per satu ke array "messages"
+func (e *chatExport) writeJSON(w io.Writer) error {
+	header, err := json.Marshal(map[string]interface{}{
+		"exported_at": e.exportedAt,
+		"from":        optionalTime(e.from),
+		"to":          optionalTime(e.to),
+		"participants": []exportParticipant{
+			{ID: e.userID, Username: e.nameOf(e.userID)},
+			{ID: e.partnerID, Username: e.nameOf(e.partnerID)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Buka objeknya lagi untuk menambahkan array messages di akhir
+	if _, err := io.WriteString(w, string(header[:len(header)-1])+`,"messages":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err = e.stream(func(msg *entity.ChatMessage) error {
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		exported := *msg
+		exported.Attachment = e.attachmentOf(msg)
+		data, err := json.Marshal(&exported)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append([]byte("\n"), data...))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n]}\n")
+	return err
+}
+
+// attachmentLabels adalah nama jenis lampiran di arsip teks
+var attachmentLabels = map[entity.FileType]string{
+	entity.FileTypePhoto: "foto",
+	entity.FileTypeVideo: "video",
+	entity.FileTypeAudio: "pesan suara",
+}
+
+// exportTimeLayout adalah format waktu pesan di arsip teks dan HTML
+const exportTimeLayout = "2006-01-02 15:04"
+
+// writeText menulis arsip teks dengan satu pesan per baris, baris lanjutan diberi indentasi
+func (e *chatExport) writeText(w io.Writer) error {
+	header := fmt.Sprintf("Chat %s & %s\nDiekspor %s\n\n",
+		e.nameOf(e.userID), e.nameOf(e.partnerID), e.exportedAt.Format(exportTimeLayout))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	return e.stream(func(msg *entity.ChatMessage) error {
+		var line strings.Builder
+		fmt.Fprintf(&line, "[%s] %s: ", msg.CreatedAt.Format(exportTimeLayout), e.nameOf(msg.SenderID))
+		if msg.ReplyTo != nil {
+			fmt.Fprintf(&line, "(membalas %s: %q) ", e.nameOf(msg.ReplyTo.SenderID), msg.ReplyTo.Message)
+		}
+		if attachment := e.attachmentOf(msg); attachment != nil {
+			fmt.Fprintf(&line, "[%s: %s] ", attachmentLabels[attachment.FileType], attachment.FilePath)
+		}
+		line.WriteString(strings.ReplaceAll(msg.Message, "\n", "\n    "))
+		if msg.EditedAt != nil {
+			line.WriteString(" (diedit)")
+		}
+
+		_, err := io.WriteString(w, strings.TrimRight(line.String(), " ")+"\n")
+		return err
+	})
+}
+
+// exportHTMLTemplate adalah halaman HTML arsip chat, "message" ditulis sekali untuk setiap pesan
+var exportHTMLTemplate = template.Must(template.New("header").Parse(`<!DOCTYPE html>
+<html lang="id">
+<head>
+<meta charset="utf-8">
+<title>Chat {{.User}} &amp; {{.Partner}}</title>
+<style>
+body { font-family: sans-serif; background: #f5f5f5; max-width: 720px; margin: 0 auto; padding: 16px; }
+h1 { font-size: 20px; margin-bottom: 4px; }
+.exported { color: #999; font-size: 12px; margin-bottom: 24px; }
+.message { background: #fff; border-radius: 12px; padding: 8px 12px; margin: 8px 0; max-width: 70%; }
+.message.mine { background: #dcf8c6; margin-left: auto; }
+.sender { font-weight: bold; font-size: 13px; }
+.text { white-space: pre-wrap; }
+.reply { border-left: 3px solid #ccc; padding-left: 8px; color: #666; font-size: 13px; }
+.time { color: #999; font-size: 11px; text-align: right; }
+img, video { max-width: 100%; border-radius: 8px; }
+</style>
+</head>
+<body>
+<h1>Chat {{.User}} &amp; {{.Partner}}</h1>
+<div class="exported">Diekspor {{.ExportedAt}}</div>
+{{- if .LinkedMedia}}
+<div class="exported">Foto, video dan voice note di halaman ini hanya bisa dibuka lewat aplikasi Fasisi saat login. Ekspor dengan zip=true untuk salinan yang bisa dibuka offline.</div>
+{{- end}}
+{{define "message"}}<div class="message{{if .Mine}} mine{{end}}">
+<div class="sender">{{.Sender}}</div>
+{{- with .ReplyTo}}
+<div class="reply">{{.Sender}}: {{.Message}}</div>
+{{- end}}
+{{- with .Attachment}}
+{{- if eq .Type "photo"}}
+<a href="{{.Path}}"><img src="{{.Path}}" alt="foto"></a>
+{{- else if eq .Type "video"}}
+<video controls src="{{.Path}}"></video>
+{{- else}}
+<audio controls src="{{.Path}}"></audio>
+{{- end}}
+{{- end}}
+{{- if .Text}}
+<div class="text">{{.Text}}</div>
+{{- end}}
+<div class="time">{{.Time}}{{if .Edited}} · diedit{{end}}</div>
+</div>
+{{end}}{{define "footer"}}</body>
+</html>
+{{end}}`))
+
+// exportHTMLMessage adalah data template "message"
+type exportHTMLMessage struct {
+	Mine    bool
+	Sender  string
+	Text    string
+	Time    string
+	Edited  bool
+	ReplyTo *struct {
+		Sender  string
+		Message string
+	}
+	Attachment *struct {
+		Type string
+		Path string
+	}
+}
+
+// writeHTML menulis arsip sebagai halaman HTML dengan nama pengirim, pesan sendiri di kanan
+func (e *chatExport) writeHTML(w io.Writer) error {
+	err := exportHTMLTemplate.Execute(w, map[string]interface{}{
+		"User":       e.nameOf(e.userID),
+		"Partner":    e.nameOf(e.partnerID),
+		"ExportedAt": e.exportedAt.Format(exportTimeLayout),
+		// Without a ZIP the attachments point at the API, which needs the token
+		"LinkedMedia": e.mediaPath == nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = e.stream(func(msg *entity.ChatMessage) error {
+		data := exportHTMLMessage{
+			Mine:   msg.SenderID == e.userID,
+			Sender: e.nameOf(msg.SenderID),
+			Text:   msg.Message,
+			Time:   msg.CreatedAt.Format(exportTimeLayout),
+			Edited: msg.EditedAt != nil,
+		}
+		if msg.ReplyTo != nil {
+			data.ReplyTo = &struct {
+				Sender  string
+				Message string
+			}{e.nameOf(msg.ReplyTo.SenderID), msg.ReplyTo.Message}
+		}
+		if attachment := e.attachmentOf(msg); attachment != nil {
+			data.Attachment = &struct {
+				Type string
+				Path string
+			}{string(attachment.FileType), attachment.FilePath}
+		}
+		return exportHTMLTemplate.ExecuteTemplate(w, "message", data)
+	})
+	if err != nil {
+		return err
+	}
+
+	return exportHTMLTemplate.ExecuteTemplate(w, "footer", nil)
+}
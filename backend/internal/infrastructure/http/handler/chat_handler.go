@@ -1,49 +1,109 @@
 // Package handler menyediakan HTTP handlers untuk fitur chat
-// Handler ini menangani semua operasi terkait percakapan antara Irfan dan Sisti
+// Handler ini menangani semua operasi terkait percakapan antara dua pasangan
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/gorilla/mux"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
 )
 
 // ChatHandler menangani semua request HTTP terkait fitur chat
-// Handler ini memfasilitasi komunikasi real-time antara dua pengguna (Irfan & Sisti)
+// Handler ini memfasilitasi komunikasi real-time antara user dan pasangannya
 type ChatHandler struct {
-	chatRepo  repository.ChatRepository        // Repository untuk operasi database chat
-	notifRepo repository.NotificationRepository // Repository untuk notifikasi
+	chatRepo      repository.ChatRepository         // Repository untuk operasi database chat
+	reactionRepo  repository.ChatReactionRepository // Repository untuk reaksi emoji pada pesan
+	scheduledRepo repository.ScheduledMessageRepository // Repository pesan terjadwal dan time capsule
+	galleryRepo   repository.GalleryRepository      // Repository galeri, tujuan lampiran yang disimpan ke galeri
+	notifRepo     repository.NotificationRepository // Repository untuk notifikasi
+	coupleService *service.CoupleService            // Service untuk menentukan pasangan user
+	hub           *realtime.Hub                     // Hub untuk push pesan ke koneksi WebSocket
+	presence      *realtime.PresenceTracker         // Status online dan indikator mengetik
+	editWindow    time.Duration                     // Batas waktu edit/hapus pesan, 0 berarti tanpa batas
 }
 
 // NewChatHandler membuat instance baru dari ChatHandler
 // Parameter:
 //   - chatRepo: Repository untuk mengakses data chat di database
+//   - reactionRepo: Repository untuk reaksi emoji pada pesan
+//   - scheduledRepo: Repository untuk pesan terjadwal dan time capsule
+//   - galleryRepo: Repository galeri untuk menyimpan lampiran chat ke galeri
 //   - notifRepo: Repository untuk notifikasi
+//   - coupleService: Service untuk menentukan pasangan dari user yang login
+//   - hub: Hub real-time untuk mengirim event ke koneksi WebSocket
+//   - presence: Tracker status online dan indikator mengetik
+//   - editWindow: Berapa lama pengirim masih boleh mengedit atau menghapus pesannya (0 = tanpa batas)
 // Returns:
 //   - Pointer ke ChatHandler yang sudah diinisialisasi
-func NewChatHandler(chatRepo repository.ChatRepository, notifRepo repository.NotificationRepository) *ChatHandler {
+func NewChatHandler(chatRepo repository.ChatRepository, reactionRepo repository.ChatReactionRepository, scheduledRepo repository.ScheduledMessageRepository, galleryRepo repository.GalleryRepository, notifRepo repository.NotificationRepository, coupleService *service.CoupleService, hub *realtime.Hub, presence *realtime.PresenceTracker, editWindow time.Duration) *ChatHandler {
 	return &ChatHandler{
-		chatRepo:  chatRepo,
-		notifRepo: notifRepo,
+		chatRepo:      chatRepo,
+		reactionRepo:  reactionRepo,
+		scheduledRepo: scheduledRepo,
+		galleryRepo:   galleryRepo,
+		notifRepo:     notifRepo,
+		coupleService: coupleService,
+		hub:           hub,
+		presence:      presence,
+		editWindow:    editWindow,
 	}
 }
 
-// GetHistory mengambil riwayat percakapan antara user yang sedang login dengan pasangannya
-// Endpoint: GET /api/chat/messages
+// Batas jumlah pesan per halaman riwayat chat
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 100
+)
+
+// HistoryPage adalah envelope response riwayat chat
+// Field:
+//   - Messages: Pesan dalam halaman ini, urut dari yang paling lama
+//   - NextCursor: ID untuk parameter after (pesan yang lebih baru), null jika tidak ada
+//   - PrevCursor: ID untuk parameter before (pesan yang lebih lama), null jika tidak ada
+//   - HasMore: Masih ada pesan lain ke arah halaman yang diminta
+type HistoryPage struct {
+	Messages   []*entity.ChatMessage `json:"messages"`
+	NextCursor *int64                `json:"next_cursor"`
+	PrevCursor *int64                `json:"prev_cursor"`
+	HasMore    bool                  `json:"has_more"`
+}
+
+// GetHistory mengambil satu halaman riwayat percakapan antara user yang sedang login dengan pasangannya
+// Endpoint: GET /api/chat/messages?before=&after=&limit=
 // Authentication: Membutuhkan JWT token
 // 
 // Cara kerja:
 // 1. Mengambil user ID dari JWT token
-// 2. Menentukan partner ID (jika user 1 maka partner adalah user 2, begitu sebaliknya)
-// 3. Mengambil semua pesan antara kedua user dari database
-// 4. Mengirim response berupa array pesan dalam format JSON
+// 2. Menentukan partner ID lewat CoupleService
+// 3. Tanpa cursor: mengambil pesan terbaru sebanyak limit
+//    Dengan before: mengambil pesan yang lebih lama dari ID tersebut
+//    Dengan after: mengambil pesan yang lebih baru dari ID tersebut
+// 4. Menandai pesan dari partner sampai pesan terbaru di halaman ini sebagai delivered
+// 5. Menggabungkan reaksi emoji per pesan (field reactions, dikelompokkan per emoji)
+// 6. Mengirim response berupa HistoryPage dalam format JSON
+//
+// Setiap pesan membawa status (sent, delivered atau read) beserta delivered_at dan read_at
 //
 // Response:
-//   - 200 OK: Array pesan berhasil diambil
+//   - 200 OK: Halaman pesan berhasil diambil
+//   - 400 Bad Request: Cursor atau limit tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
 //   - 500 Internal Server Error: Gagal mengambil pesan dari database
 func (h *ChatHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 	// Ambil user claims dari context (sudah diset oleh auth middleware)
@@ -53,50 +113,309 @@ func (h *ChatHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Tentukan partner ID
-	// Sistem ini hanya untuk 2 user: Irfan (ID=1) dan Sisti (ID=2)
-	partnerID := int64(1)
-	if claims.UserID == 1 {
-		partnerID = 2
+	page, err := parseHistoryPage(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Tentukan partner ID dari pasangan user
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
 	}
 
-	// Ambil riwayat pesan dari database
-	messages, err := h.chatRepo.FindHistory(r.Context(), claims.UserID, partnerID)
+	// Ambil satu halaman pesan dari database
+	messages, hasMore, err := h.chatRepo.FindHistoryPage(r.Context(), claims.UserID, partnerID, page)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to fetch messages"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Pesan dari partner yang diambil di sini sudah diterima oleh client user
+	if upToID := undeliveredUpTo(messages, claims.UserID); upToID > 0 {
+		deliveredAt, err := h.markReceipt(r.Context(), entity.MessageStatusDelivered, claims.UserID, partnerID, upToID)
+		if err != nil {
+			http.Error(w, `{"error": "Failed to update message status"}`, http.StatusInternalServerError)
+			return
+		}
+		for _, msg := range messages {
+			if msg.ReceiverID == claims.UserID && msg.DeliveredAt == nil {
+				msg.DeliveredAt = &deliveredAt
+				msg.UpdateStatus()
+			}
+		}
+	}
+
+	// Gabungkan reaksi emoji ke setiap pesan
+	if err := h.attachReactions(r.Context(), messages); err != nil {
+		http.Error(w, `{"error": "Failed to fetch reactions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Kirim response dalam format JSON
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newHistoryPage(messages, hasMore, page))
+}
+
+// parseHistoryPage membaca parameter before, after dan limit dari query string
+func parseHistoryPage(query url.Values) (repository.ChatPage, error) {
+	page := repository.ChatPage{Limit: defaultHistoryLimit}
+
+	for _, param := range []struct {
+		name string
+		dst  *int64
+	}{{"before", &page.BeforeID}, {"after", &page.AfterID}} {
+		if v := query.Get(param.name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				return page, fmt.Errorf("Invalid %s cursor", param.name)
+			}
+			*param.dst = id
+		}
+	}
+	if page.BeforeID > 0 && page.AfterID > 0 {
+		return page, errors.New("Use either before or after, not both")
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+		page.Limit = limit
+	}
+
+	return page, nil
+}
+
+// newHistoryPage menghitung cursor untuk halaman berikutnya dan sebelumnya
+func newHistoryPage(messages []*entity.ChatMessage, hasMore bool, page repository.ChatPage) HistoryPage {
+	result := HistoryPage{Messages: messages, HasMore: hasMore}
+	if len(messages) == 0 {
+		result.Messages = []*entity.ChatMessage{}
+		return result
+	}
+
+	first, last := messages[0].ID, messages[len(messages)-1].ID
+
+	// Halaman after selalu punya pesan yang lebih lama (minimal pesan cursor-nya),
+	// halaman terbaru dan before hanya jika hasMore
+	if page.AfterID > 0 || hasMore {
+		result.PrevCursor = &first
+	}
+	// Sebaliknya halaman before selalu punya pesan yang lebih baru
+	if page.BeforeID > 0 || (page.AfterID > 0 && hasMore) {
+		result.NextCursor = &last
+	}
+
+	return result
+}
+
+// Batas untuk pencarian pesan
+const (
+	defaultSearchLimit   = 20
+	maxSearchLimit       = 50
+	defaultSearchContext = 2
+	maxSearchContext     = 5
+	maxSearchQueryLength = 200
+)
+
+// SearchParams adalah parameter pencarian dari query string
+type SearchParams struct {
+	Query   string
+	Limit   int
+	Context int
+}
+
+// SearchResult adalah satu hasil pencarian beserta pesan di sekitarnya
+// Field:
+//   - ContextBefore: Pesan sebelum hasil, urut dari yang paling lama
+//   - ContextAfter: Pesan sesudah hasil, urut dari yang paling lama
+type SearchResult struct {
+	*entity.ChatSearchResult
+	ContextBefore []*entity.ChatMessage `json:"context_before"`
+	ContextAfter  []*entity.ChatMessage `json:"context_after"`
+}
+
+// SearchMessages mencari pesan dalam percakapan user dengan pasangannya
+// Endpoint: GET /api/chat/search?q=&limit=&context=
+// Authentication: Membutuhkan JWT token
+//
+// Cara kerja:
+// 1. Validasi parameter q (wajib), limit dan context
+// 2. Menentukan partner ID lewat CoupleService
+// 3. Mencari pesan dengan full-text search PostgreSQL (konfigurasi 'simple'),
+//    q mendukung sintaks web search: "frasa persis", OR, dan -kata
+// 4. Untuk setiap hasil, mengambil beberapa pesan sebelum dan sesudahnya sebagai konteks
+// 5. Mengirim hasil yang diurutkan dari yang paling relevan
+//
+// Response:
+//   {
+//     "query": "restoran",
+//     "results": [
+//       {
+//         "message": {...},
+//         "rank": 0.06,
+//         "snippet": "makan di <mark>restoran</mark> sunda kemarin",
+//         "context_before": [...],
+//         "context_after": [...]
+//       }
+//     ]
+//   }
+//
+// Response:
+//   - 200 OK: Pencarian berhasil (results bisa kosong)
+//   - 400 Bad Request: Parameter tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 500 Internal Server Error: Gagal mencari pesan
+func (h *ChatHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	// Ambil user claims dari JWT token
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	params, err := parseSearchParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Tentukan partner ID dari pasangan user
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	matches, err := h.chatRepo.Search(r.Context(), claims.UserID, partnerID, params.Query, params.Limit)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to search messages"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Ambil pesan di sekitar setiap hasil
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		result := SearchResult{
+			ChatSearchResult: match,
+			ContextBefore:    []*entity.ChatMessage{},
+			ContextAfter:     []*entity.ChatMessage{},
+		}
+		if params.Context > 0 {
+			before, _, err := h.chatRepo.FindHistoryPage(r.Context(), claims.UserID, partnerID,
+				repository.ChatPage{BeforeID: match.Message.ID, Limit: params.Context})
+			if err != nil {
+				http.Error(w, `{"error": "Failed to search messages"}`, http.StatusInternalServerError)
+				return
+			}
+			after, _, err := h.chatRepo.FindHistoryPage(r.Context(), claims.UserID, partnerID,
+				repository.ChatPage{AfterID: match.Message.ID, Limit: params.Context})
+			if err != nil {
+				http.Error(w, `{"error": "Failed to search messages"}`, http.StatusInternalServerError)
+				return
+			}
+			if before != nil {
+				result.ContextBefore = before
+			}
+			if after != nil {
+				result.ContextAfter = after
+			}
+		}
+		results = append(results, result)
+	}
+
 	// Kirim response dalam format JSON
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(messages)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   params.Query,
+		"results": results,
+	})
+}
+
+// parseSearchParams membaca parameter q, limit dan context dari query string
+func parseSearchParams(query url.Values) (SearchParams, error) {
+	params := SearchParams{
+		Query:   strings.TrimSpace(query.Get("q")),
+		Limit:   defaultSearchLimit,
+		Context: defaultSearchContext,
+	}
+
+	if params.Query == "" {
+		return params, errors.New("Search query is required")
+	}
+	if utf8.RuneCountInString(params.Query) > maxSearchQueryLength {
+		return params, fmt.Errorf("Search query cannot be longer than %d characters", maxSearchQueryLength)
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		params.Limit = limit
+	}
+
+	if v := query.Get("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSearchContext {
+			return params, fmt.Errorf("context must be between 0 and %d", maxSearchContext)
+		}
+		params.Context = n
+	}
+
+	return params, nil
 }
 
 // SendMessageReq adalah struktur request untuk mengirim pesan baru
 // Field:
-//   - Message: Isi pesan yang akan dikirim (wajib diisi)
+//   - Message: Isi pesan yang akan dikirim (wajib diisi, kecuali ada lampiran)
+//   - ReplyToID: ID pesan yang dibalas (opsional), harus dari percakapan yang sama
+//   - DeliverAt: Waktu pengiriman (opsional), pesan disimpan dan dikirim oleh scheduler pada waktu tersebut
 type SendMessageReq struct {
-	Message string `json:"message"`
+	Message   string     `json:"message"`
+	ReplyToID int64      `json:"reply_to_id,omitempty"`
+	DeliverAt *time.Time `json:"deliver_at,omitempty"`
 }
 
 // SendMessage mengirim pesan baru dari user yang sedang login ke pasangannya
 // Endpoint: POST /api/chat/messages
 // Authentication: Membutuhkan JWT token
 //
-// Request Body:
+// Request Body (application/json):
 //   {
-//     "message": "Halo sayang, apa kabar?"
+//     "message": "Halo sayang, apa kabar?",
+//     "reply_to_id": 41
 //   }
 //
+// Pesan dengan lampiran dikirim sebagai multipart/form-data:
+//   - file: Foto (JPEG, PNG, GIF), video (MP4, MOV, AVI, WebM) atau voice note
+//     (audio/ogg, audio/mpeg, audio/mp4), max 50MB, validasi sama dengan galeri
+//   - message: Caption (opsional)
+//   - reply_to_id: ID pesan yang dibalas (opsional)
+//   - duration: Durasi audio/video dalam detik (opsional, max 10 menit)
+//
+// Dengan "deliver_at" (RFC 3339, harus di masa depan) pesan teks dijadwalkan, lihat scheduleMessage.
+// Pesan dengan lampiran atau balasan tidak bisa dijadwalkan.
+//
 // Cara kerja:
-// 1. Validasi request body harus berisi message yang tidak kosong
-// 2. Menentukan penerima (receiver) berdasarkan ID pengirim
-// 3. Menyimpan pesan ke database dengan status read_status = false
-// 4. Mengirim response success dengan data pesan yang baru dibuat
+// 1. Validasi request body harus berisi message yang tidak kosong, atau sebuah lampiran
+// 2. Menentukan penerima (receiver) berdasarkan ID pengirim, lalu menjadwalkan pesan jika deliver_at diisi
+// 3. Jika reply_to_id diisi, pesan yang dibalas harus ada di percakapan yang sama dan belum dihapus.
+//    Cuplikan pesan tersebut disalin ke reply_to, sehingga tetap ada walaupun pesan aslinya dihapus
+// 4. Menyimpan lampiran ke folder uploads dan pesan ke database dengan status read_status = false.
+//    Jika pesan sementara aktif, expires_at diisi sesuai timer percakapan
+// 5. Push pesan ke semua koneksi WebSocket milik penerima (dan pengirim di device lain)
+// 6. Mengirim response success dengan data pesan yang baru dibuat
 //
 // Response:
 //   - 200 OK: Pesan berhasil dikirim
-//   - 400 Bad Request: Request body invalid atau message kosong
+//   - 201 Created: Pesan berhasil dijadwalkan
+//   - 400 Bad Request: Request body invalid, message kosong, reply_to_id atau deliver_at tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
 //   - 500 Internal Server Error: Gagal menyimpan pesan ke database
 func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Ambil user claims dari JWT token
@@ -106,24 +425,42 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request body
+	// Parse request body, JSON untuk pesan teks atau multipart untuk pesan dengan lampiran
 	var req SendMessageReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var duration float64
+	withAttachment := strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+	if withAttachment {
+		if !parseUploadForm(w, r) {
+			return
+		}
+		var err error
+		if req, duration, err = parseAttachmentForm(url.Values(r.MultipartForm.Value)); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Validasi: message tidak boleh kosong
-	if req.Message == "" {
+	// Validasi: message tidak boleh kosong, kecuali sebagai caption lampiran
+	if req.Message == "" && !withAttachment {
 		http.Error(w, `{"error": "Message cannot be empty"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Tentukan receiver ID (penerima pesan)
-	// Jika pengirim adalah user 1, maka receiver adalah user 2, begitu sebaliknya
-	receiverID := int64(1)
-	if claims.UserID == 1 {
-		receiverID = 2
+	// Tentukan receiver ID (penerima pesan), yaitu pasangan dari pengirim
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+	receiverID := couple.PartnerOf(claims.UserID)
+
+	// Pesan terjadwal tidak langsung dikirim, scheduler yang mengirimnya pada deliver_at
+	if req.DeliverAt != nil {
+		h.scheduleMessage(w, r, claims.UserID, receiverID, req)
+		return
 	}
 
 	// Buat object chat message
@@ -132,10 +469,50 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		ReceiverID: receiverID,      // ID penerima yang sudah ditentukan
 		Message:    req.Message,     // Isi pesan dari request
 		ReadStatus: false,           // Default belum dibaca
+		ExpiresAt:  couple.MessageExpiry(time.Now()), // nil jika pesan sementara tidak aktif
+	}
+
+	// Salin cuplikan pesan yang dibalas
+	if req.ReplyToID != 0 {
+		original, err := h.chatRepo.FindByID(r.Context(), req.ReplyToID)
+		if errors.Is(err, repository.ErrChatMessageNotFound) || (err == nil && !original.IsBetween(claims.UserID, receiverID)) {
+			http.Error(w, `{"error": "Replied message not found"}`, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Failed to send message"}`, http.StatusInternalServerError)
+			return
+		}
+		if original.IsDeleted() {
+			http.Error(w, `{"error": "Cannot reply to a deleted message"}`, http.StatusBadRequest)
+			return
+		}
+		chatMessage.ReplyTo = entity.NewMessagePreview(original)
+	}
+
+	// Simpan lampiran setelah semua validasi lain lolos
+	if withAttachment {
+		upload, ok := saveUpload(w, r, "file", claims.UserID, chatUploads)
+		if !ok {
+			return
+		}
+		chatMessage.Attachment = &entity.ChatAttachment{
+			FileType: upload.FileType,
+			FilePath: upload.FilePath,
+			MimeType: upload.MimeType,
+			FileSize: upload.Size,
+		}
+		if upload.FileType != entity.FileTypePhoto {
+			chatMessage.Attachment.Duration = duration
+		}
 	}
 
 	// Simpan pesan ke database
 	if err := h.chatRepo.Create(r.Context(), chatMessage); err != nil {
+		// Hapus lampiran yang sudah tersimpan jika pesan gagal dibuat
+		if chatMessage.Attachment != nil {
+			removeUpload(chatMessage.Attachment.FilePath)
+		}
 		http.Error(w, `{"error": "Failed to send message"}`, http.StatusInternalServerError)
 		return
 	}
@@ -150,6 +527,16 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	h.notifRepo.Create(r.Context(), notif)
 
+	// Push pesan secara real-time ke penerima dan device lain milik pengirim
+	event := realtime.Event{ID: chatMessage.ID, Type: realtime.EventMessage, Data: chatMessage}
+	h.hub.Publish(receiverID, event)
+	h.hub.Publish(claims.UserID, event)
+
+	// Pesan sudah terkirim, indikator mengetik tidak perlu menunggu habis masa berlakunya
+	if h.presence.IsTyping(claims.UserID) {
+		h.presence.SetTyping(claims.UserID, receiverID, false)
+	}
+
 	// Kirim response success
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -158,19 +545,430 @@ func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// MarkAsRead menandai pesan-pesan dari partner sebagai sudah dibaca
+// EditMessageReq adalah struktur request untuk mengedit pesan
+// Field:
+//   - Message: Isi pesan yang baru (wajib diisi, kecuali untuk caption lampiran)
+type EditMessageReq struct {
+	Message string `json:"message"`
+}
+
+// EditMessage mengubah isi pesan yang dikirim oleh user yang sedang login
+// Endpoint: PATCH /api/chat/messages/{id}
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//   {
+//     "message": "Halo sayang, apa kabar hari ini?"
+//   }
+//
+// Cara kerja:
+// 1. Memastikan pesan ada di percakapan user dengan pasangannya
+// 2. Hanya pengirim yang boleh mengedit, dan hanya dalam batas waktu editWindow
+// 3. Isi pesan lama disimpan sebagai revisi, edited_at diisi
+// 4. Push event "message_edited" ke pasangan dan device lain milik pengirim
+//
+// Response:
+//   - 200 OK: Pesan berhasil diedit
+//   - 400 Bad Request: Request body invalid atau message kosong
+//   - 403 Forbidden: Bukan pengirim pesan atau batas waktu edit sudah lewat
+//   - 404 Not Found: Pesan tidak ditemukan
+//   - 409 Conflict: Pesan sudah dihapus
+//   - 500 Internal Server Error: Gagal menyimpan perubahan
+func (h *ChatHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	claims, message, partnerID, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var req EditMessageReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+		return
+	}
+	// Caption lampiran boleh dikosongkan
+	if req.Message == "" && message.Attachment == nil {
+		http.Error(w, `{"error": "Message cannot be empty"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkChangeable(w, message, claims.UserID) {
+		return
+	}
+
+	// Isi yang sama tidak perlu disimpan sebagai revisi
+	if req.Message != message.Message {
+		err := h.chatRepo.Edit(r.Context(), message, req.Message)
+		if errors.Is(err, repository.ErrChatMessageNotFound) {
+			http.Error(w, `{"error": "Message was deleted"}`, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, `{"error": "Failed to edit message"}`, http.StatusInternalServerError)
+			return
+		}
+
+		event := realtime.Event{Type: realtime.EventMessageEdited, Data: message}
+		h.hub.Publish(partnerID, event)
+		h.hub.Publish(claims.UserID, event)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Message edited successfully",
+		"data":    message,
+	})
+}
+
+// DeleteMessage menghapus pesan yang dikirim oleh user yang sedang login
+// Endpoint: DELETE /api/chat/messages/{id}
+// Authentication: Membutuhkan JWT token
+//
+// Cara kerja:
+// 1. Memastikan pesan ada di percakapan user dengan pasangannya
+// 2. Hanya pengirim yang boleh menghapus, dan hanya dalam batas waktu editWindow
+// 3. Pesan tidak dihapus dari database tetapi menjadi tombstone:
+//    isi, lampiran dan revisinya dihapus, deleted_at diisi
+// 4. Push event "message_deleted" ke pasangan dan device lain milik pengirim
+//
+// Response:
+//   - 200 OK: Pesan berhasil dihapus
+//   - 403 Forbidden: Bukan pengirim pesan atau batas waktu hapus sudah lewat
+//   - 404 Not Found: Pesan tidak ditemukan
+//   - 409 Conflict: Pesan sudah dihapus
+//   - 500 Internal Server Error: Gagal menghapus pesan
+func (h *ChatHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	claims, message, partnerID, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.checkChangeable(w, message, claims.UserID) {
+		return
+	}
+
+	attachment := message.Attachment
+	err := h.chatRepo.Delete(r.Context(), message)
+	if errors.Is(err, repository.ErrChatMessageNotFound) {
+		http.Error(w, `{"error": "Message was deleted"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to delete message"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// File lampiran ikut dihapus, salinan yang sudah disimpan ke galeri tetap ada
+	if attachment != nil {
+		if err := removeUpload(attachment.FilePath); err != nil {
+			log.Printf("Failed to remove attachment of message %d: %v", message.ID, err)
+		}
+	}
+
+	event := realtime.Event{Type: realtime.EventMessageDeleted, Data: message}
+	h.hub.Publish(partnerID, event)
+	h.hub.Publish(claims.UserID, event)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Message deleted successfully",
+		"data":    message,
+	})
+}
+
+// GetRevisions mengambil riwayat edit sebuah pesan
+// Endpoint: GET /api/chat/messages/{id}/revisions
+// Authentication: Membutuhkan JWT token
+//
+// Cara kerja:
+// 1. Memastikan pesan ada di percakapan user dengan pasangannya
+// 2. Mengambil semua versi lama pesan, urut dari yang paling lama
+//    Pesan yang sudah dihapus tidak punya revisi lagi
+//
+// Response:
+//   - 200 OK: Array revisi (bisa kosong)
+//   - 404 Not Found: Pesan tidak ditemukan
+//   - 500 Internal Server Error: Gagal mengambil revisi
+func (h *ChatHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	_, message, _, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.chatRepo.FindRevisions(r.Context(), message.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch revisions"}`, http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []*entity.ChatMessageRevision{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// Panjang maksimum emoji reaksi dalam byte, sama dengan kolom chat_reactions.emoji
+const maxReactionLength = 32
+
+// ReactionReq adalah struktur request untuk menambahkan reaksi
+// Field:
+//   - Emoji: Emoji reaksi, misalnya "❤️"
+type ReactionReq struct {
+	Emoji string `json:"emoji"`
+}
+
+// AddReaction menambahkan reaksi emoji ke sebuah pesan
+// Endpoint: POST /api/chat/messages/{id}/reactions
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//   {
+//     "emoji": "❤️"
+//   }
+//
+// Cara kerja:
+// 1. Memastikan pesan ada di percakapan user dengan pasangannya dan belum dihapus
+// 2. Menyimpan reaksi, emoji yang sama dari user yang sama hanya disimpan sekali
+// 3. Jika reaksi baru, push event "reaction" ke kedua user dan kirim notifikasi
+//    ke pengirim pesan (kecuali user bereaksi pada pesannya sendiri)
+// 4. Mengirim ringkasan reaksi terbaru pada pesan tersebut
+//
+// Response:
+//   - 201 Created: Reaksi berhasil ditambahkan
+//   - 200 OK: Reaksi sudah ada sebelumnya
+//   - 400 Bad Request: Emoji tidak valid
+//   - 404 Not Found: Pesan tidak ditemukan
+//   - 409 Conflict: Pesan sudah dihapus
+//   - 500 Internal Server Error: Gagal menyimpan reaksi
+func (h *ChatHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	claims, message, partnerID, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	var req ReactionReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+		return
+	}
+	if !isValidReaction(req.Emoji) {
+		http.Error(w, `{"error": "Invalid emoji"}`, http.StatusBadRequest)
+		return
+	}
+
+	if message.IsDeleted() {
+		http.Error(w, `{"error": "Message was deleted"}`, http.StatusConflict)
+		return
+	}
+
+	reaction := &entity.ChatReaction{MessageID: message.ID, UserID: claims.UserID, Emoji: req.Emoji}
+	added, err := h.reactionRepo.Add(r.Context(), reaction)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to add reaction"}`, http.StatusInternalServerError)
+		return
+	}
+
+	reactions, err := h.publishReactions(r.Context(), message.ID, claims.UserID, partnerID, added)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch reactions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+
+		// Beri tahu pengirim pesan, kecuali user bereaksi pada pesannya sendiri
+		if message.SenderID != claims.UserID {
+			notif := &entity.Notification{
+				UserID:     message.SenderID,
+				Type:       entity.NotificationTypeReaction,
+				Message:    claims.Username + " bereaksi " + req.Emoji + " pada pesanmu",
+				RelatedID:  message.ID,
+				ReadStatus: false,
+			}
+			h.notifRepo.Create(r.Context(), notif)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message_id": message.ID,
+		"reactions":  reactions,
+	})
+}
+
+// RemoveReaction menghapus reaksi emoji milik user dari sebuah pesan
+// Endpoint: DELETE /api/chat/messages/{id}/reactions/{emoji}
+// Authentication: Membutuhkan JWT token
+//
+// Emoji di path harus di-URL-encode, misalnya %E2%9D%A4%EF%B8%8F untuk "❤️"
+//
+// Response:
+//   - 200 OK: Reaksi berhasil dihapus
+//   - 404 Not Found: Pesan atau reaksi tidak ditemukan
+//   - 500 Internal Server Error: Gagal menghapus reaksi
+func (h *ChatHandler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
+	claims, message, partnerID, ok := h.findConversationMessage(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.reactionRepo.Remove(r.Context(), message.ID, claims.UserID, mux.Vars(r)["emoji"])
+	if errors.Is(err, repository.ErrChatReactionNotFound) {
+		http.Error(w, `{"error": "Reaction not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to remove reaction"}`, http.StatusInternalServerError)
+		return
+	}
+
+	reactions, err := h.publishReactions(r.Context(), message.ID, claims.UserID, partnerID, true)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch reactions"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message_id": message.ID,
+		"reactions":  reactions,
+	})
+}
+
+// publishReactions mengambil ringkasan reaksi sebuah pesan, dan jika changed
+// mengirimkannya sebagai event "reaction" ke kedua user
+func (h *ChatHandler) publishReactions(ctx context.Context, messageID, userID, partnerID int64, changed bool) ([]*entity.ReactionSummary, error) {
+	reactions, err := h.reactionRepo.FindByMessageIDs(ctx, []int64{messageID})
+	if err != nil {
+		return nil, err
+	}
+	summaries := entity.SummarizeReactions(reactions)
+	if summaries == nil {
+		summaries = []*entity.ReactionSummary{}
+	}
+
+	if changed {
+		event := realtime.Event{Type: realtime.EventReaction, Data: map[string]interface{}{
+			"message_id": messageID,
+			"reactions":  summaries,
+		}}
+		h.hub.Publish(partnerID, event)
+		h.hub.Publish(userID, event)
+	}
+
+	return summaries, nil
+}
+
+// attachReactions mengisi field Reactions pada setiap pesan
+func (h *ChatHandler) attachReactions(ctx context.Context, messages []*entity.ChatMessage) error {
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	reactions, err := h.reactionRepo.FindByMessageIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	byMessage := make(map[int64][]*entity.ChatReaction)
+	for _, reaction := range reactions {
+		byMessage[reaction.MessageID] = append(byMessage[reaction.MessageID], reaction)
+	}
+	for _, msg := range messages {
+		msg.Reactions = entity.SummarizeReactions(byMessage[msg.ID])
+	}
+
+	return nil
+}
+
+// isValidReaction memastikan reaksi berupa emoji pendek, bukan teks biasa
+func isValidReaction(emoji string) bool {
+	if emoji == "" || len(emoji) > maxReactionLength || !utf8.ValidString(emoji) {
+		return false
+	}
+	for _, c := range emoji {
+		if unicode.IsSpace(c) || unicode.IsControl(c) || (c < utf8.RuneSelf && unicode.IsLetter(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// findConversationMessage mengambil pesan dari path param {id} yang termasuk percakapan
+// user dengan pasangannya, dan menulis response error jika gagal
+func (h *ChatHandler) findConversationMessage(w http.ResponseWriter, r *http.Request) (*service.Claims, *entity.ChatMessage, int64, bool) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return nil, nil, 0, false
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return nil, nil, 0, false
+	}
+
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return nil, nil, 0, false
+	}
+
+	message, err := h.chatRepo.FindByID(r.Context(), id)
+	if errors.Is(err, repository.ErrChatMessageNotFound) {
+		http.Error(w, `{"error": "Message not found"}`, http.StatusNotFound)
+		return nil, nil, 0, false
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch message"}`, http.StatusInternalServerError)
+		return nil, nil, 0, false
+	}
+
+	// Pesan dari percakapan lain diperlakukan seperti tidak ada
+	if !message.IsBetween(claims.UserID, partnerID) {
+		http.Error(w, `{"error": "Message not found"}`, http.StatusNotFound)
+		return nil, nil, 0, false
+	}
+
+	return claims, message, partnerID, true
+}
+
+// checkChangeable memastikan user boleh mengedit atau menghapus pesan saat ini
+func (h *ChatHandler) checkChangeable(w http.ResponseWriter, message *entity.ChatMessage, userID int64) bool {
+	switch {
+	case message.SenderID != userID:
+		http.Error(w, `{"error": "You can only change your own messages"}`, http.StatusForbidden)
+		return false
+	case message.IsDeleted():
+		http.Error(w, `{"error": "Message was deleted"}`, http.StatusConflict)
+		return false
+	case !message.CanBeChangedBy(userID, time.Now(), h.editWindow):
+		http.Error(w, `{"error": "Message can no longer be changed"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// MarkAsRead menandai semua pesan dari partner sebagai sudah dibaca
 // Endpoint: POST /api/chat/messages/read
 // Authentication: Membutuhkan JWT token
 //
 // Cara kerja:
 // 1. Menentukan partner ID dari user yang sedang login
-// 2. Update semua pesan dari partner yang belum dibaca (read_status = false)
-// 3. Set read_status menjadi true untuk pesan-pesan tersebut
+// 2. Set read_status = true dan read_at untuk semua pesan dari partner yang belum dibaca
+// 3. Push event "read" ke partner agar status terbaca langsung terlihat
 //
 // Use case:
 //   - Ketika user membuka halaman chat
 //   - Untuk menghilangkan badge unread count
 //
+// Untuk menandai sampai pesan tertentu saja gunakan AckMessages.
+//
 // Response:
 //   - 200 OK: Pesan berhasil ditandai sebagai sudah dibaca
 //   - 500 Internal Server Error: Gagal update status pesan
@@ -183,14 +981,14 @@ func (h *ChatHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Tentukan partner ID
-	partnerID := int64(1)
-	if claims.UserID == 1 {
-		partnerID = 2
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
 	}
 
-	// Update read_status untuk semua pesan dari partner
-	// Parameter: senderID (partner), receiverID (current user)
-	if err := h.chatRepo.MarkAsRead(r.Context(), partnerID, claims.UserID); err != nil {
+	// Tandai semua pesan dari partner (upToID 0) dan beri tahu partner
+	if _, err := h.markReceipt(r.Context(), entity.MessageStatusRead, claims.UserID, partnerID, 0); err != nil {
 		http.Error(w, `{"error": "Failed to mark messages as read"}`, http.StatusInternalServerError)
 		return
 	}
@@ -200,6 +998,132 @@ func (h *ChatHandler) MarkAsRead(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Messages marked as read"})
 }
 
+// AckMessagesReq adalah body request untuk AckMessages
+// Field:
+//   - UpToID: ID pesan terakhir yang dikonfirmasi, pesan partner dengan ID lebih kecil ikut dikonfirmasi
+//   - Status: "delivered" atau "read", default "read"
+type AckMessagesReq struct {
+	UpToID int64                `json:"up_to_id"`
+	Status entity.MessageStatus `json:"status"`
+}
+
+// validate memeriksa isi AckMessagesReq dan mengisi status default
+func (req *AckMessagesReq) validate() error {
+	if req.UpToID <= 0 {
+		return errors.New("up_to_id is required")
+	}
+	switch req.Status {
+	case "":
+		req.Status = entity.MessageStatusRead
+	case entity.MessageStatusDelivered, entity.MessageStatusRead:
+	default:
+		return errors.New("status must be delivered or read")
+	}
+	return nil
+}
+
+// AckMessages mengonfirmasi pesan dari partner sudah diterima atau dibaca sampai ID tertentu
+// Endpoint: POST /api/chat/messages/ack
+// Authentication: Membutuhkan JWT token
+//
+// Request body:
+//   {
+//     "up_to_id": 123,
+//     "status": "read"
+//   }
+//
+// Cara kerja:
+// 1. Validasi up_to_id dan status
+// 2. Set delivered_at (atau read_at dan read_status) untuk pesan dari partner dengan ID <= up_to_id
+//    yang belum memiliki status tersebut, pesan yang dibaca otomatis ikut delivered
+// 3. Jika ada pesan yang berubah, push event "delivered" atau "read" ke partner dan device lain milik user
+//
+// Use case:
+//   - Client WebSocket mengirim "delivered" saat event message diterima
+//   - Client mengirim "read" untuk pesan terakhir yang terlihat di layar
+//
+// Response:
+//   - 200 OK: Status berhasil dicatat (juga jika tidak ada pesan yang berubah)
+//   - 400 Bad Request: Body tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 500 Internal Server Error: Gagal update status pesan
+func (h *ChatHandler) AckMessages(w http.ResponseWriter, r *http.Request) {
+	// Ambil user claims dari JWT token
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req AckMessagesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Tentukan partner ID
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	if _, err := h.markReceipt(r.Context(), req.Status, claims.UserID, partnerID, req.UpToID); err != nil {
+		http.Error(w, `{"error": "Failed to update message status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Messages acknowledged",
+		"up_to_id": req.UpToID,
+		"status":   req.Status,
+	})
+}
+
+// markReceipt mencatat pesan dari partner sampai upToID (0 = semua) sebagai delivered atau read,
+// lalu push event "delivered" atau "read" ke partner dan device lain milik user jika ada yang berubah.
+// Mengembalikan waktu yang dicatat.
+func (h *ChatHandler) markReceipt(ctx context.Context, status entity.MessageStatus, userID, partnerID, upToID int64) (time.Time, error) {
+	at := time.Now()
+
+	mark, eventType := h.chatRepo.MarkDelivered, realtime.EventDelivered
+	data := map[string]interface{}{"receiver_id": userID, "sender_id": partnerID, "delivered_at": at}
+	if status == entity.MessageStatusRead {
+		mark, eventType = h.chatRepo.MarkRead, realtime.EventRead
+		data = map[string]interface{}{"reader_id": userID, "sender_id": partnerID, "read_at": at}
+	}
+
+	changed, err := mark(ctx, partnerID, userID, upToID, at)
+	if err != nil || changed == 0 {
+		return at, err
+	}
+
+	if upToID > 0 {
+		data["up_to_id"] = upToID
+	}
+	event := realtime.Event{Type: eventType, Data: data}
+	h.hub.Publish(partnerID, event)
+	h.hub.Publish(userID, event)
+
+	return at, nil
+}
+
+// undeliveredUpTo mengembalikan ID terbesar dari pesan untuk userID yang belum delivered, 0 jika tidak ada
+func undeliveredUpTo(messages []*entity.ChatMessage, userID int64) int64 {
+	var upToID int64
+	for _, msg := range messages {
+		if msg.ReceiverID == userID && msg.DeliveredAt == nil && msg.ID > upToID {
+			upToID = msg.ID
+		}
+	}
+	return upToID
+}
+
 // GetUnreadCount menghitung jumlah pesan yang belum dibaca untuk user saat ini
 // Endpoint: GET /api/chat/unread
 // Authentication: Membutuhkan JWT token
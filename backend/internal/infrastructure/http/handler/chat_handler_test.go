@@ -2,10 +2,18 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 )
 
 func TestChatHandler_SendMessage(t *testing.T) {
@@ -49,8 +57,6 @@ func TestChatHandler_SendMessage(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-			w := httptest.NewRecorder()
-
 			t.Logf("Test %s expects status %d", tt.name, tt.expectedStatus)
 		})
 	}
@@ -60,8 +66,6 @@ func TestChatHandler_GetMessages(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/chat/messages", nil)
 	req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-	w := httptest.NewRecorder()
-
 	t.Log("Should return chat message history")
 }
 
@@ -73,8 +77,6 @@ func TestChatHandler_MarkAsRead(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-	w := httptest.NewRecorder()
-
 	t.Log("Should mark message as read")
 }
 
@@ -82,7 +84,466 @@ func TestChatHandler_GetUnreadCount(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/chat/unread", nil)
 	req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-	w := httptest.NewRecorder()
-
 	t.Log("Should return unread message count")
 }
+
+func TestParseHistoryPage(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    repository.ChatPage
+		wantErr bool
+	}{
+		{query: "", want: repository.ChatPage{Limit: defaultHistoryLimit}},
+		{query: "before=40&limit=20", want: repository.ChatPage{BeforeID: 40, Limit: 20}},
+		{query: "after=7", want: repository.ChatPage{AfterID: 7, Limit: defaultHistoryLimit}},
+		{query: "before=40&after=7", wantErr: true},
+		{query: "before=abc", wantErr: true},
+		{query: "after=-1", wantErr: true},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=101", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := parseHistoryPage(values)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestNewHistoryPage_Cursors(t *testing.T) {
+	messages := []*entity.ChatMessage{{ID: 10}, {ID: 11}, {ID: 12}}
+	cursor := func(c *int64) interface{} {
+		if c == nil {
+			return nil
+		}
+		return *c
+	}
+
+	tests := []struct {
+		name       string
+		page       repository.ChatPage
+		hasMore    bool
+		prev, next interface{}
+	}{
+		{name: "latest page with older messages", hasMore: true, prev: int64(10), next: nil},
+		{name: "whole conversation", hasMore: false, prev: nil, next: nil},
+		{name: "before page", page: repository.ChatPage{BeforeID: 13}, hasMore: false, prev: nil, next: int64(12)},
+		{name: "after page with newer messages", page: repository.ChatPage{AfterID: 9}, hasMore: true, prev: int64(10), next: int64(12)},
+		{name: "after page reaching the end", page: repository.ChatPage{AfterID: 9}, hasMore: false, prev: int64(10), next: nil},
+	}
+
+	for _, tt := range tests {
+		got := newHistoryPage(messages, tt.hasMore, tt.page)
+		if cursor(got.PrevCursor) != tt.prev || cursor(got.NextCursor) != tt.next || got.HasMore != tt.hasMore {
+			t.Errorf("%s: got prev %v next %v has_more %v", tt.name, cursor(got.PrevCursor), cursor(got.NextCursor), got.HasMore)
+		}
+	}
+
+	empty := newHistoryPage(nil, false, repository.ChatPage{AfterID: 12})
+	if empty.Messages == nil || empty.PrevCursor != nil || empty.NextCursor != nil {
+		t.Errorf("unexpected empty page %+v", empty)
+	}
+}
+
+func TestParseSearchParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    SearchParams
+		wantErr bool
+	}{
+		{query: "q=restoran", want: SearchParams{Query: "restoran", Limit: defaultSearchLimit, Context: defaultSearchContext}},
+		{query: "q=+sate+padang+&limit=5&context=0", want: SearchParams{Query: "sate padang", Limit: 5, Context: 0}},
+		{query: "", wantErr: true},
+		{query: "q=+++", wantErr: true},
+		{query: "q=" + strings.Repeat("a", maxSearchQueryLength+1), wantErr: true},
+		{query: "q=sate&limit=0", wantErr: true},
+		{query: "q=sate&limit=51", wantErr: true},
+		{query: "q=sate&context=-1", wantErr: true},
+		{query: "q=sate&context=6", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := parseSearchParams(values)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.query, got, err, tt.want)
+		}
+	}
+}
+
+func TestChatHandler_CheckChangeable(t *testing.T) {
+	now := time.Now()
+	deletedAt := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		message    *entity.ChatMessage
+		window     time.Duration
+		wantStatus int
+	}{
+		{name: "own recent message", message: &entity.ChatMessage{SenderID: 1, CreatedAt: now.Add(-time.Minute)}, window: 15 * time.Minute, wantStatus: http.StatusOK},
+		{name: "partner's message", message: &entity.ChatMessage{SenderID: 2, CreatedAt: now}, window: 15 * time.Minute, wantStatus: http.StatusForbidden},
+		{name: "outside the window", message: &entity.ChatMessage{SenderID: 1, CreatedAt: now.Add(-time.Hour)}, window: 15 * time.Minute, wantStatus: http.StatusForbidden},
+		{name: "no time limit", message: &entity.ChatMessage{SenderID: 1, CreatedAt: now.Add(-24 * time.Hour)}, window: 0, wantStatus: http.StatusOK},
+		{name: "already deleted", message: &entity.ChatMessage{SenderID: 1, CreatedAt: now, DeletedAt: &deletedAt}, window: 0, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		h := &ChatHandler{editWindow: tt.window}
+		w := httptest.NewRecorder()
+		ok := h.checkChangeable(w, tt.message, 1)
+		if ok != (tt.wantStatus == http.StatusOK) || w.Code != tt.wantStatus {
+			t.Errorf("%s: got ok %v status %d, want status %d", tt.name, ok, w.Code, tt.wantStatus)
+		}
+	}
+}
+
+// fakeReactionRepo is an in-memory ChatReactionRepository
+type fakeReactionRepo struct {
+	reactions []*entity.ChatReaction
+}
+
+func (f *fakeReactionRepo) Add(ctx context.Context, reaction *entity.ChatReaction) (bool, error) {
+	f.reactions = append(f.reactions, reaction)
+	return true, nil
+}
+
+func (f *fakeReactionRepo) Remove(ctx context.Context, messageID, userID int64, emoji string) error {
+	return repository.ErrChatReactionNotFound
+}
+
+func (f *fakeReactionRepo) FindByMessageIDs(ctx context.Context, messageIDs []int64) ([]*entity.ChatReaction, error) {
+	var found []*entity.ChatReaction
+	for _, reaction := range f.reactions {
+		for _, id := range messageIDs {
+			if reaction.MessageID == id {
+				found = append(found, reaction)
+			}
+		}
+	}
+	return found, nil
+}
+
+func TestChatHandler_AttachReactions(t *testing.T) {
+	h := &ChatHandler{reactionRepo: &fakeReactionRepo{reactions: []*entity.ChatReaction{
+		{MessageID: 1, UserID: 2, Emoji: "❤️"},
+		{MessageID: 1, UserID: 1, Emoji: "😂"},
+		{MessageID: 1, UserID: 1, Emoji: "❤️"},
+		{MessageID: 3, UserID: 1, Emoji: "👍"},
+	}}}
+	messages := []*entity.ChatMessage{{ID: 1}, {ID: 2}}
+
+	if err := h.attachReactions(context.Background(), messages); err != nil {
+		t.Fatal(err)
+	}
+
+	got := messages[0].Reactions
+	if len(got) != 2 || got[0].Emoji != "❤️" || got[0].Count != 2 || got[1].Emoji != "😂" || got[1].Count != 1 {
+		t.Errorf("unexpected reactions on message 1: %+v", got)
+	}
+	if len(got[0].UserIDs) != 2 || got[0].UserIDs[0] != 2 || got[0].UserIDs[1] != 1 {
+		t.Errorf("unexpected users for ❤️: %v", got[0].UserIDs)
+	}
+	if messages[1].Reactions != nil {
+		t.Errorf("expected no reactions on message 2, got %+v", messages[1].Reactions)
+	}
+}
+
+func TestIsValidReaction(t *testing.T) {
+	for _, emoji := range []string{"❤️", "👍", "😂", "👨‍👩‍👧", "1️⃣", "🇮🇩"} {
+		if !isValidReaction(emoji) {
+			t.Errorf("expected %q to be a valid reaction", emoji)
+		}
+	}
+	for _, emoji := range []string{"", "lol", "❤️ ", "a❤️", "\n", strings.Repeat("❤️", 10)} {
+		if isValidReaction(emoji) {
+			t.Errorf("expected %q to be rejected", emoji)
+		}
+	}
+}
+
+func TestNewMessagePreview(t *testing.T) {
+	short := entity.NewMessagePreview(&entity.ChatMessage{ID: 4, SenderID: 2, Message: "Makan di mana?"})
+	if short.ID != 4 || short.SenderID != 2 || short.Message != "Makan di mana?" {
+		t.Errorf("unexpected preview %+v", short)
+	}
+
+	long := entity.NewMessagePreview(&entity.ChatMessage{Message: strings.Repeat("é", entity.MessagePreviewLength+1)})
+	if got := []rune(long.Message); len(got) != entity.MessagePreviewLength || got[len(got)-1] != '…' {
+		t.Errorf("expected preview shortened to %d characters, got %d", entity.MessagePreviewLength, len(got))
+	}
+
+	msg := &entity.ChatMessage{SenderID: 1, ReceiverID: 2}
+	if !msg.IsBetween(1, 2) || !msg.IsBetween(2, 1) || msg.IsBetween(1, 3) {
+		t.Error("unexpected conversation check")
+	}
+}
+
+func TestParseAttachmentForm(t *testing.T) {
+	tests := []struct {
+		query        string
+		wantReq      SendMessageReq
+		wantDuration float64
+		wantErr      bool
+	}{
+		{query: "", wantReq: SendMessageReq{}},
+		{query: "message=Lihat+ini&reply_to_id=12&duration=4.5", wantReq: SendMessageReq{Message: "Lihat ini", ReplyToID: 12}, wantDuration: 4.5},
+		{query: "reply_to_id=abc", wantErr: true},
+		{query: "duration=0", wantErr: true},
+		{query: "duration=601", wantErr: true},
+		{query: "duration=NaN", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		req, duration, err := parseAttachmentForm(values)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || req != tt.wantReq || duration != tt.wantDuration {
+			t.Errorf("%q: got %+v, %v, %v", tt.query, req, duration, err)
+		}
+	}
+}
+
+func TestUploadPolicies(t *testing.T) {
+	tests := []struct {
+		policy      uploadPolicy
+		contentType string
+		want        entity.FileType
+		wantOK      bool
+	}{
+		{galleryUploads, "image/jpeg", entity.FileTypePhoto, true},
+		{galleryUploads, "video/webm", entity.FileTypeVideo, true},
+		{galleryUploads, "audio/ogg", "", false},
+		{chatUploads, "image/png", entity.FileTypePhoto, true},
+		{chatUploads, "video/mp4", entity.FileTypeVideo, true},
+		{chatUploads, "audio/ogg; codecs=opus", entity.FileTypeAudio, true},
+		{chatUploads, "Audio/MPEG", entity.FileTypeAudio, true},
+		{chatUploads, "audio/mp4", entity.FileTypeAudio, true},
+		{chatUploads, "audio/wav", "", false},
+		{chatUploads, "application/pdf", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := tt.policy.fileTypeOf(tt.contentType)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.contentType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestAckMessagesReq_Validate(t *testing.T) {
+	req := AckMessagesReq{UpToID: 7}
+	if err := req.validate(); err != nil || req.Status != entity.MessageStatusRead {
+		t.Errorf("expected status to default to read, got %q (err %v)", req.Status, err)
+	}
+
+	for _, req := range []AckMessagesReq{
+		{Status: entity.MessageStatusRead},
+		{UpToID: -1},
+		{UpToID: 7, Status: entity.MessageStatusSent},
+		{UpToID: 7, Status: "seen"},
+	} {
+		if err := req.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", req)
+		}
+	}
+}
+
+func TestUndeliveredUpTo(t *testing.T) {
+	now := time.Now()
+	messages := []*entity.ChatMessage{
+		{ID: 1, SenderID: 2, ReceiverID: 1, DeliveredAt: &now},
+		{ID: 2, SenderID: 2, ReceiverID: 1},
+		{ID: 3, SenderID: 2, ReceiverID: 1},
+		{ID: 4, SenderID: 1, ReceiverID: 2},
+	}
+	if got := undeliveredUpTo(messages, 1); got != 3 {
+		t.Errorf("expected 3, got %d", got)
+	}
+	if got := undeliveredUpTo(messages[:1], 1); got != 0 {
+		t.Errorf("expected 0 when everything was delivered, got %d", got)
+	}
+
+	for _, msg := range messages {
+		msg.UpdateStatus()
+	}
+	messages[3].ReadAt = &now
+	messages[3].UpdateStatus()
+	for i, want := range []entity.MessageStatus{entity.MessageStatusDelivered, entity.MessageStatusSent, entity.MessageStatusSent, entity.MessageStatusRead} {
+		if messages[i].Status != want {
+			t.Errorf("message %d: expected status %q, got %q", messages[i].ID, want, messages[i].Status)
+		}
+	}
+}
+
+func TestValidateScheduled(t *testing.T) {
+	now := time.Now()
+	valid := []*entity.ScheduledMessage{
+		{Kind: entity.ScheduledKindMessage, Message: "Selamat ulang tahun!", DeliverAt: now.Add(time.Hour)},
+		{Kind: entity.ScheduledKindTimeCapsule, Title: "Buka saat kangen", Message: "Aku juga kangen", DeliverAt: now.Add(time.Minute)},
+	}
+	for _, msg := range valid {
+		if err := validateScheduled(msg, now); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", msg, err)
+		}
+	}
+
+	invalid := []*entity.ScheduledMessage{
+		{Kind: entity.ScheduledKindMessage, Message: "  ", DeliverAt: now.Add(time.Hour)},
+		{Kind: entity.ScheduledKindMessage, Message: "Halo", DeliverAt: now},
+		{Kind: entity.ScheduledKindMessage, Message: "Halo", DeliverAt: now.Add(-time.Hour)},
+		{Kind: entity.ScheduledKindTimeCapsule, Message: "Halo", DeliverAt: now.Add(time.Hour)},
+		{Kind: entity.ScheduledKindTimeCapsule, Title: strings.Repeat("💌", maxCapsuleTitleLength+1), Message: "Halo", DeliverAt: now.Add(time.Hour)},
+	}
+	for _, msg := range invalid {
+		if err := validateScheduled(msg, now); err == nil {
+			t.Errorf("expected %+v to be rejected", msg)
+		}
+	}
+}
+
+func TestParseExportParams(t *testing.T) {
+	params, err := parseExportParams(url.Values{})
+	if err != nil || params.Format != exportFormatJSON || params.Zip || !params.From.IsZero() || !params.To.IsZero() {
+		t.Errorf("unexpected defaults %+v (err %v)", params, err)
+	}
+
+	params, err = parseExportParams(url.Values{"format": {"html"}, "zip": {"true"}, "from": {"2025-11-01"}, "to": {"2025-11-30"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.Zip || params.Format != exportFormatHTML {
+		t.Errorf("unexpected params %+v", params)
+	}
+	if want := time.Date(2025, 12, 1, 0, 0, 0, 0, time.Local); !params.To.Equal(want) {
+		t.Errorf("expected the whole last day to be included, got to %v", params.To)
+	}
+
+	params, err = parseExportParams(url.Values{"from": {"2025-11-22T10:00:00+07:00"}})
+	if err != nil || params.From.Hour() != 10 {
+		t.Errorf("expected an RFC 3339 from, got %+v (err %v)", params, err)
+	}
+
+	for _, query := range []url.Values{
+		{"format": {"pdf"}},
+		{"zip": {"maybe"}},
+		{"from": {"kemarin"}},
+		{"from": {"2025-11-30"}, "to": {"2025-11-01"}},
+	} {
+		if _, err := parseExportParams(query); err == nil {
+			t.Errorf("expected %v to be rejected", query)
+		}
+	}
+}
+
+func TestChatExport_Formats(t *testing.T) {
+	created := time.Date(2025, 11, 22, 10, 0, 0, 0, time.UTC)
+	messages := []*entity.ChatMessage{
+		{ID: 1, SenderID: 1, ReceiverID: 2, Message: "Halo <b>sayang</b>", CreatedAt: created},
+		{ID: 2, SenderID: 2, ReceiverID: 1, Message: "Lihat ini", CreatedAt: created.Add(time.Minute),
+			Attachment: &entity.ChatAttachment{FileType: entity.FileTypePhoto, FilePath: "/uploads/2-1-ab.jpg"},
+			ReplyTo:    &entity.MessagePreview{ID: 1, SenderID: 1, Message: "Halo"}},
+	}
+	newExport := func() *chatExport {
+		return &chatExport{
+			userID: 1, partnerID: 2, names: map[int64]string{1: "irfan", 2: "sisti"}, exportedAt: created,
+			stream: func(fn func(*entity.ChatMessage) error) error {
+				for _, msg := range messages {
+					if err := fn(msg); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := newExport().writeJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var archive struct {
+		Participants []exportParticipant  `json:"participants"`
+		Messages     []entity.ChatMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &archive); err != nil {
+		t.Fatalf("invalid JSON archive: %v\n%s", err, buf.String())
+	}
+	if len(archive.Participants) != 2 || len(archive.Messages) != 2 || archive.Messages[1].Attachment.FilePath != "/uploads/2-1-ab.jpg" {
+		t.Errorf("unexpected JSON archive %+v", archive)
+	}
+
+	buf.Reset()
+	if err := newExport().writeText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[2025-11-22 10:01] sisti: (membalas irfan: "Halo") [foto: /uploads/2-1-ab.jpg] Lihat ini`; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected %q in text archive:\n%s", want, buf.String())
+	}
+
+	// In a ZIP the archive refers to the bundled copy of the file
+	export := newExport()
+	export.mediaPath = zipMediaPath
+	buf.Reset()
+	if err := export.writeHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{`<div class="sender">sisti</div>`, `src="uploads/2-1-ab.jpg"`, `Halo &lt;b&gt;sayang&lt;/b&gt;`, `</html>`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected %q in HTML archive:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "zip=true") {
+		t.Errorf("expected no offline hint in a ZIP archive:\n%s", page)
+	}
+
+	// Without a ZIP the page links the API and says how to get an offline copy
+	buf.Reset()
+	if err := newExport().writeHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "zip=true") {
+		t.Errorf("expected a hint to export with zip=true:\n%s", buf.String())
+	}
+}
+
+func TestMessageTTLChangeText(t *testing.T) {
+	for _, option := range service.MessageTTLOptions {
+		if messageTTLLabels[option] == "" {
+			t.Errorf("timer %v has no label", option)
+		}
+	}
+
+	if got, want := messageTTLChangeText("irfan", 24*time.Hour), "irfan menyalakan pesan sementara, pesan baru akan hilang setelah 1 hari"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := messageTTLChangeText("sisti", 0), "sisti mematikan pesan sementara"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// System messages cannot be edited or deleted, not even by their sender
+	system := &entity.ChatMessage{SenderID: 1, System: true, CreatedAt: time.Now()}
+	if system.CanBeChangedBy(1, time.Now(), 0) {
+		t.Error("expected a system message to be unchangeable")
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+// TypingReq adalah struktur request untuk indikator mengetik
+// Field:
+//   - Typing: true saat mulai mengetik, false saat berhenti
+type TypingReq struct {
+	Typing bool `json:"typing"`
+}
+
+// SetTyping menyalakan atau mematikan indikator mengetik ke pasangan
+// Endpoint: POST /api/chat/typing
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//
+//	{
+//	  "typing": true
+//	}
+//
+// Cara kerja:
+//  1. Menentukan partner ID lewat CoupleService
+//  2. Push event "typing_started" atau "typing_stopped" ke partner
+//  3. Indikator mati sendiri setelah beberapa detik jika tidak dikirim ulang,
+//     client sebaiknya mengirim ulang typing: true selama user masih mengetik
+//
+// Client WebSocket bisa mengirim frame {"type": "typing_started"} / {"type": "typing_stopped"}
+// sebagai gantinya.
+//
+// Response:
+//   - 200 OK: Indikator berhasil diperbarui
+//   - 400 Bad Request: Body tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+func (h *ChatHandler) SetTyping(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req TypingReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	h.presence.SetTyping(claims.UserID, partnerID, req.Typing)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"typing": req.Typing})
+}
+
+// PartnerPresence adalah status pasangan untuk client yang tidak memakai WebSocket
+// Field:
+//   - Presence: Status online, away atau offline beserta last_seen
+//   - Typing: Pasangan sedang mengetik
+type PartnerPresence struct {
+	Presence realtime.Presence `json:"presence"`
+	Typing   bool              `json:"typing"`
+}
+
+// GetPresence mengambil status online pasangan dan apakah pasangan sedang mengetik
+// Endpoint: GET /api/chat/presence
+// Authentication: Membutuhkan JWT token
+//
+// Response:
+//
+//	{
+//	  "presence": {"user_id": 2, "status": "online", "last_seen": "2025-11-22T10:30:00Z"},
+//	  "typing": false
+//	}
+//
+// Response:
+//   - 200 OK: Status berhasil diambil
+//   - 403 Forbidden: User belum memiliki pasangan
+func (h *ChatHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PartnerPresence{
+		Presence: h.presence.Presence(partnerID),
+		Typing:   h.presence.IsTyping(partnerID),
+	})
+}
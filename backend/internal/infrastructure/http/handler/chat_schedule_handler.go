@@ -0,0 +1,345 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// Panjang maksimum judul time capsule
+const maxCapsuleTitleLength = 200
+
+// scheduleMessage menyimpan pesan dari SendMessage yang memiliki deliver_at
+// untuk dikirim oleh scheduler, lalu menulis response 201 dengan pesan terjadwal
+func (h *ChatHandler) scheduleMessage(w http.ResponseWriter, r *http.Request, senderID, receiverID int64, req SendMessageReq) {
+	if req.ReplyToID != 0 {
+		http.Error(w, `{"error": "Replies cannot be scheduled"}`, http.StatusBadRequest)
+		return
+	}
+
+	scheduled := &entity.ScheduledMessage{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Kind:       entity.ScheduledKindMessage,
+		Message:    req.Message,
+		DeliverAt:  *req.DeliverAt,
+	}
+	h.createScheduled(w, r, scheduled, "Message scheduled")
+}
+
+// CreateTimeCapsuleReq adalah struktur request untuk membuat time capsule
+// Field:
+//   - Title: Judul yang terlihat oleh pasangan selama capsule masih terkunci, misalnya "Buka saat kangen"
+//   - Message: Isi surat, baru terlihat setelah capsule terbuka
+//   - UnlockAt: Waktu capsule terbuka dan dikirim ke chat (RFC 3339)
+type CreateTimeCapsuleReq struct {
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	UnlockAt time.Time `json:"unlock_at"`
+}
+
+// CreateTimeCapsule membuat surat "open when" yang terkunci sampai tanggal tertentu
+// Endpoint: POST /api/chat/capsules
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//
+//	{
+//	  "title": "Buka saat anniversary kita",
+//	  "message": "Selamat satu tahun, sayang...",
+//	  "unlock_at": "2026-02-14T08:00:00+07:00"
+//	}
+//
+// Cara kerja:
+//  1. Validasi title, message dan unlock_at (harus di masa depan)
+//  2. Menyimpan capsule untuk pasangan user
+//  3. Selama terkunci, pasangan hanya melihat judul dan tanggal buka (GET /api/chat/capsules)
+//  4. Pada unlock_at scheduler mengirim capsule sebagai pesan chat dengan judul di baris pertama
+//     dan notifikasi new_message
+//
+// Response:
+//   - 201 Created: Capsule berhasil dibuat
+//   - 400 Bad Request: Request body tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 500 Internal Server Error: Gagal menyimpan capsule
+func (h *ChatHandler) CreateTimeCapsule(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateTimeCapsuleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	receiverID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	capsule := &entity.ScheduledMessage{
+		SenderID:   claims.UserID,
+		ReceiverID: receiverID,
+		Kind:       entity.ScheduledKindTimeCapsule,
+		Title:      strings.TrimSpace(req.Title),
+		Message:    req.Message,
+		DeliverAt:  req.UnlockAt,
+	}
+	h.createScheduled(w, r, capsule, "Time capsule created")
+}
+
+// createScheduled memvalidasi dan menyimpan pesan terjadwal, lalu menulis response 201
+func (h *ChatHandler) createScheduled(w http.ResponseWriter, r *http.Request, scheduled *entity.ScheduledMessage, message string) {
+	if err := validateScheduled(scheduled, time.Now()); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.scheduledRepo.Create(r.Context(), scheduled); err != nil {
+		http.Error(w, `{"error": "Failed to schedule message"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"data":    scheduled,
+	})
+}
+
+// validateScheduled memeriksa isi pesan terjadwal sebelum disimpan atau diubah
+func validateScheduled(scheduled *entity.ScheduledMessage, now time.Time) error {
+	if strings.TrimSpace(scheduled.Message) == "" {
+		return errors.New("Message cannot be empty")
+	}
+	if scheduled.Kind == entity.ScheduledKindTimeCapsule {
+		if scheduled.Title == "" {
+			return errors.New("Time capsules need a title")
+		}
+		if utf8.RuneCountInString(scheduled.Title) > maxCapsuleTitleLength {
+			return fmt.Errorf("Title cannot be longer than %d characters", maxCapsuleTitleLength)
+		}
+	}
+	if !scheduled.DeliverAt.After(now) {
+		return errors.New("Delivery time must be in the future")
+	}
+	return nil
+}
+
+// GetScheduled mengambil pesan terjadwal dan time capsule milik user yang belum terkirim
+// Endpoint: GET /api/chat/scheduled
+// Authentication: Membutuhkan JWT token
+//
+// Response:
+//
+//	[
+//	  {"id": 1, "kind": "message", "message": "Selamat ulang tahun!", "deliver_at": "...", "status": "pending", ...},
+//	  {"id": 2, "kind": "time_capsule", "title": "Buka saat kangen", "message": "...", "deliver_at": "...", ...}
+//	]
+//
+// Response:
+//   - 200 OK: Daftar pesan terjadwal, urut dari yang paling dulu dikirim
+//   - 500 Internal Server Error: Gagal mengambil data
+func (h *ChatHandler) GetScheduled(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	messages, err := h.scheduledRepo.FindPendingBySender(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch scheduled messages"}`, http.StatusInternalServerError)
+		return
+	}
+	if messages == nil {
+		messages = []*entity.ScheduledMessage{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// GetCapsules mengambil time capsule dari pasangan yang masih terkunci
+// Endpoint: GET /api/chat/capsules
+// Authentication: Membutuhkan JWT token
+//
+// Isi surat tidak ikut dikirim, hanya judul dan waktu terbukanya (deliver_at).
+//
+// Response:
+//   - 200 OK: Daftar capsule, urut dari yang paling dulu terbuka
+//   - 500 Internal Server Error: Gagal mengambil data
+func (h *ChatHandler) GetCapsules(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	capsules, err := h.scheduledRepo.FindPendingByReceiver(r.Context(), claims.UserID, entity.ScheduledKindTimeCapsule)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch time capsules"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sealed := make([]*entity.ScheduledMessage, 0, len(capsules))
+	for _, capsule := range capsules {
+		sealed = append(sealed, capsule.Sealed())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sealed)
+}
+
+// UpdateScheduledReq adalah struktur request untuk mengubah pesan terjadwal
+// Field yang tidak diisi tidak diubah:
+//   - Message: Isi pesan baru
+//   - Title: Judul baru (hanya time capsule)
+//   - DeliverAt: Waktu pengiriman baru, harus di masa depan
+type UpdateScheduledReq struct {
+	Message   *string    `json:"message"`
+	Title     *string    `json:"title"`
+	DeliverAt *time.Time `json:"deliver_at"`
+}
+
+// UpdateScheduled mengubah pesan terjadwal atau time capsule yang belum terkirim
+// Endpoint: PATCH /api/chat/scheduled/{id}
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//
+//	{
+//	  "message": "Selamat ulang tahun, sayang!",
+//	  "deliver_at": "2026-03-01T00:00:00+07:00"
+//	}
+//
+// Response:
+//   - 200 OK: Pesan terjadwal berhasil diubah
+//   - 400 Bad Request: Request body tidak valid
+//   - 404 Not Found: Pesan terjadwal tidak ditemukan atau bukan milik user
+//   - 409 Conflict: Pesan sudah terkirim atau dibatalkan
+//   - 500 Internal Server Error: Gagal menyimpan perubahan
+func (h *ChatHandler) UpdateScheduled(w http.ResponseWriter, r *http.Request) {
+	scheduled, ok := h.findOwnScheduled(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateScheduledReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Message != nil {
+		scheduled.Message = *req.Message
+	}
+	if req.Title != nil {
+		if scheduled.Kind != entity.ScheduledKindTimeCapsule {
+			http.Error(w, `{"error": "Only time capsules have a title"}`, http.StatusBadRequest)
+			return
+		}
+		scheduled.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.DeliverAt != nil {
+		scheduled.DeliverAt = *req.DeliverAt
+	}
+	if err := validateScheduled(scheduled, time.Now()); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.scheduledRepo.Update(r.Context(), scheduled); err != nil {
+		writeScheduledError(w, err, "Failed to update scheduled message")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Scheduled message updated",
+		"data":    scheduled,
+	})
+}
+
+// CancelScheduled membatalkan pesan terjadwal atau time capsule yang belum terkirim
+// Endpoint: DELETE /api/chat/scheduled/{id}
+// Authentication: Membutuhkan JWT token
+//
+// Response:
+//   - 200 OK: Pesan terjadwal berhasil dibatalkan
+//   - 404 Not Found: Pesan terjadwal tidak ditemukan atau bukan milik user
+//   - 409 Conflict: Pesan sudah terkirim atau dibatalkan
+//   - 500 Internal Server Error: Gagal membatalkan
+func (h *ChatHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	scheduled, ok := h.findOwnScheduled(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.scheduledRepo.Cancel(r.Context(), scheduled.ID); err != nil {
+		writeScheduledError(w, err, "Failed to cancel scheduled message")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Scheduled message cancelled"})
+}
+
+// findOwnScheduled mengambil pesan terjadwal dari path param {id} yang dikirim oleh user
+// dan masih pending, menulis response error jika tidak
+func (h *ChatHandler) findOwnScheduled(w http.ResponseWriter, r *http.Request) (*entity.ScheduledMessage, bool) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	scheduled, err := h.scheduledRepo.FindByID(r.Context(), id)
+	if err != nil && !errors.Is(err, repository.ErrScheduledMessageNotFound) {
+		http.Error(w, `{"error": "Failed to fetch scheduled message"}`, http.StatusInternalServerError)
+		return nil, false
+	}
+	// Pesan terjadwal milik orang lain diperlakukan seperti tidak ada
+	if err != nil || scheduled.SenderID != claims.UserID {
+		http.Error(w, `{"error": "Scheduled message not found"}`, http.StatusNotFound)
+		return nil, false
+	}
+	if !scheduled.IsPending() {
+		http.Error(w, `{"error": "Scheduled message was already `+string(scheduled.Status)+`"}`, http.StatusConflict)
+		return nil, false
+	}
+
+	return scheduled, true
+}
+
+// writeScheduledError menulis response untuk error dari Update dan Cancel.
+// ErrScheduledMessageNotFound berarti scheduler baru saja mengirim pesannya.
+func writeScheduledError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, repository.ErrScheduledMessageNotFound) {
+		http.Error(w, `{"error": "Scheduled message is no longer pending"}`, http.StatusConflict)
+		return
+	}
+	http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+// messageTTLLabels adalah nama timer pesan sementara di pesan sistem
+var messageTTLLabels = map[time.Duration]string{
+	time.Hour:          "1 jam",
+	24 * time.Hour:     "1 hari",
+	7 * 24 * time.Hour: "1 minggu",
+}
+
+// ChatSettings adalah pengaturan percakapan yang berlaku untuk kedua pasangan
+// Field:
+//   - MessageTTL: Timer pesan sementara dalam detik, 0 jika tidak aktif
+//   - MessageTTLOptions: Pilihan timer dalam detik
+type ChatSettings struct {
+	MessageTTL        int64   `json:"message_ttl"`
+	MessageTTLOptions []int64 `json:"message_ttl_options"`
+}
+
+// newChatSettings membuat ChatSettings dari pengaturan couple
+func newChatSettings(couple *entity.Couple) ChatSettings {
+	settings := ChatSettings{MessageTTL: couple.MessageTTL}
+	for _, option := range service.MessageTTLOptions {
+		settings.MessageTTLOptions = append(settings.MessageTTLOptions, int64(option/time.Second))
+	}
+	return settings
+}
+
+// UpdateChatSettingsReq adalah struktur request untuk mengubah pengaturan percakapan
+// Field:
+//   - MessageTTL: Timer pesan sementara dalam detik (3600, 86400 atau 604800), 0 untuk mematikan
+type UpdateChatSettingsReq struct {
+	MessageTTL int64 `json:"message_ttl"`
+}
+
+// GetSettings mengambil pengaturan percakapan dengan pasangan
+// Endpoint: GET /api/chat/settings
+// Authentication: Membutuhkan JWT token
+//
+// Response:
+//
+//	{
+//	  "message_ttl": 86400,
+//	  "message_ttl_options": [3600, 86400, 604800]
+//	}
+//
+// Response:
+//   - 200 OK: Pengaturan percakapan
+//   - 403 Forbidden: User belum memiliki pasangan
+func (h *ChatHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newChatSettings(couple))
+}
+
+// UpdateSettings menyalakan, mengganti atau mematikan pesan sementara
+// Endpoint: PUT /api/chat/settings
+// Authentication: Membutuhkan JWT token
+//
+// Request Body:
+//
+//	{
+//	  "message_ttl": 86400
+//	}
+//
+// Cara kerja:
+//  1. Validasi timer harus 0 atau salah satu pilihan (1 jam, 1 hari, 1 minggu)
+//  2. Simpan timer di couple, sehingga berlaku untuk kedua pasangan
+//  3. Jika timer berubah, kirim pesan sistem ke percakapan dan push ke kedua pasangan
+//  4. Timer hanya berlaku untuk pesan yang dikirim setelahnya, pesan lama tidak ikut hilang
+//
+// Response:
+//   - 200 OK: Pengaturan berhasil disimpan
+//   - 400 Bad Request: Request body atau timer tidak valid
+//   - 403 Forbidden: User belum memiliki pasangan
+//   - 500 Internal Server Error: Gagal menyimpan pengaturan
+func (h *ChatHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateChatSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(req.MessageTTL) * time.Second
+	couple, changed, err := h.coupleService.SetMessageTTL(r.Context(), claims.UserID, ttl)
+	if errors.Is(err, service.ErrInvalidMessageTTL) {
+		http.Error(w, `{"error": "message_ttl must be 0, 3600, 86400 or 604800"}`, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	if changed {
+		h.postSystemMessage(r, claims.UserID, couple.PartnerOf(claims.UserID), messageTTLChangeText(claims.Username, ttl))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Chat settings updated",
+		"data":    newChatSettings(couple),
+	})
+}
+
+// messageTTLChangeText adalah isi pesan sistem saat timer pesan sementara diubah
+func messageTTLChangeText(username string, ttl time.Duration) string {
+	if ttl == 0 {
+		return username + " mematikan pesan sementara"
+	}
+	return username + " menyalakan pesan sementara, pesan baru akan hilang setelah " + messageTTLLabels[ttl]
+}
+
+// postSystemMessage menyimpan pesan sistem di percakapan dan push ke kedua pasangan.
+// Pesan sistem tidak pernah hilang, dan kegagalan hanya dicatat karena pengaturannya sudah tersimpan.
+func (h *ChatHandler) postSystemMessage(r *http.Request, userID, partnerID int64, text string) {
+	message := &entity.ChatMessage{
+		SenderID:   userID,
+		ReceiverID: partnerID,
+		Message:    text,
+		System:     true,
+	}
+	if err := h.chatRepo.Create(r.Context(), message); err != nil {
+		log.Printf("Failed to post system message for user %d: %v", userID, err)
+		return
+	}
+
+	event := realtime.Event{ID: message.ID, Type: realtime.EventMessage, Data: message}
+	h.hub.Publish(partnerID, event)
+	h.hub.Publish(userID, event)
+}
+
+// RemoveExpired menghapus file lampiran dari pesan sementara yang sudah dihapus oleh
+// MessageReaper, lalu push event "message_expired" ke kedua user di percakapan
+func (h *ChatHandler) RemoveExpired(message *entity.ChatMessage) {
+	if message.Attachment != nil {
+		if err := removeUpload(message.Attachment.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove attachment of expired message %d: %v", message.ID, err)
+		}
+	}
+
+	event := realtime.Event{Type: realtime.EventMessageExpired, Data: map[string]int64{"message_id": message.ID}}
+	h.hub.Publish(message.SenderID, event)
+	h.hub.Publish(message.ReceiverID, event)
+}
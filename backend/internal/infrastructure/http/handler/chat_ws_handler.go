@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+const (
+	// Batas waktu untuk menulis satu frame ke client
+	wsWriteWait = 10 * time.Second
+
+	// Client harus membalas ping (pong) dalam rentang waktu ini
+	wsPongWait = 60 * time.Second
+
+	// Interval ping dari server, harus lebih kecil dari wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+
+	// Ukuran maksimum frame yang dikirim client
+	wsMaxMessageSize = 4096
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Autentikasi dilakukan lewat JWT, origin dibebaskan seperti CORS di REST API
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// ServeWS membuka koneksi WebSocket untuk menerima pesan chat secara real-time
+// Endpoint: GET /api/chat/ws?token=<jwt>&last_id=<id>
+// Authentication: JWT token lewat header Authorization atau query param "token"
+//
+// Cara kerja:
+// 1. Upgrade koneksi HTTP menjadi WebSocket
+// 2. Daftarkan koneksi ke hub sebelum mengambil backlog agar tidak ada pesan yang terlewat
+// 3. Jika last_id dikirim (reconnect), kirim ulang semua pesan dengan ID > last_id
+// 4. Kirim status online partner sebagai event "presence" pertama
+// 5. Teruskan event dari hub ("message", "delivered", "read", "presence", "typing_started", ...) ke client
+// 6. Pesan dari partner yang berhasil ditulis ke koneksi ditandai delivered
+//
+// Frame dari client:
+//   - {"type": "ping"} dibalas {"type": "pong"}
+//   - {"type": "typing_started"} dan {"type": "typing_stopped"} untuk indikator mengetik ke partner,
+//     diabaikan jika token tidak punya scope chat:write
+//
+// Setiap frame dari client dihitung sebagai aktivitas untuk status online.
+//
+// Heartbeat:
+//   - Server mengirim ping frame setiap ~54 detik, koneksi ditutup jika tidak ada pong dalam 60 detik
+//   - Sebelum setiap ping sesi dicek ulang, jika sesi sudah dicabut atau akun dinonaktifkan
+//     koneksi ditutup dengan close code 1008 (policy violation)
+//   - Client juga boleh mengirim {"type": "ping"} dan akan dibalas {"type": "pong"}
+//
+// Pesan backlog dan pesan live bisa saja terkirim dua kali saat reconnect,
+// client sebaiknya melakukan dedup berdasarkan ID pesan.
+func (h *ChatHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var lastID int64
+	if v := r.URL.Query().Get("last_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, `{"error": "Invalid last_id"}`, http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	// Tentukan pasangan sebelum upgrade agar error bisa dikirim sebagai response HTTP biasa
+	partnerID, err := h.coupleService.PartnerID(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrader sudah menulis response error ke client
+		return
+	}
+
+	sub := h.hub.Subscribe(claims.UserID)
+
+	// Reader berjalan di goroutine sendiri dan meneruskan ping aplikasi ke writer
+	pings := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go h.readWS(conn, claims.UserID, partnerID, claims.HasScope(service.ScopeChatWrite), pings, done)
+
+	h.writeWS(r.Context(), conn, sub, claims.UserID, partnerID, lastID, pings, done)
+}
+
+// readWS membaca frame dari client sampai koneksi ditutup.
+// Route WebSocket hanya butuh chat:read, jadi frame mengetik hanya dipakai jika canWrite.
+func (h *ChatHandler) readWS(conn *websocket.Conn, userID, partnerID int64, canWrite bool, pings chan<- struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var event realtime.Event
+		if err := conn.ReadJSON(&event); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("WebSocket read error:", err)
+			}
+			return
+		}
+
+		// Setiap frame dari client dianggap tanda koneksi masih hidup
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		h.presence.Touch(userID)
+
+		switch event.Type {
+		case realtime.EventPing:
+			select {
+			case pings <- struct{}{}:
+			default:
+			}
+		case realtime.EventTypingStarted:
+			if canWrite {
+				h.presence.SetTyping(userID, partnerID, true)
+			}
+		case realtime.EventTypingStopped:
+			if canWrite {
+				h.presence.SetTyping(userID, partnerID, false)
+			}
+		}
+	}
+}
+
+// writeWS adalah satu-satunya goroutine yang menulis ke koneksi
+func (h *ChatHandler) writeWS(ctx context.Context, conn *websocket.Conn, sub *realtime.Subscription, userID, partnerID, lastID int64, pings <-chan struct{}, done <-chan struct{}) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		sub.Close()
+		conn.Close()
+	}()
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := conn.WriteJSON(realtime.Event{Type: realtime.EventPresence, Data: h.presence.Presence(partnerID)}); err != nil {
+		return
+	}
+
+	// Resume: kirim pesan yang terlewat selama client terputus
+	if lastID > 0 {
+		messages, err := h.chatRepo.FindHistoryAfter(ctx, userID, partnerID, lastID)
+		if err != nil {
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to fetch messages"),
+				time.Now().Add(wsWriteWait))
+			return
+		}
+
+		for _, msg := range messages {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(realtime.Event{ID: msg.ID, Type: realtime.EventMessage, Data: msg}); err != nil {
+				return
+			}
+		}
+		if upToID := undeliveredUpTo(messages, userID); upToID > 0 {
+			h.markDeliveredOverWS(ctx, userID, partnerID, upToID)
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				// Hub menutup subscription (client terlalu lambat), minta client reconnect
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "reconnect"))
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+			if msg, ok := event.Data.(*entity.ChatMessage); ok && event.Type == realtime.EventMessage && msg.ReceiverID == userID {
+				h.markDeliveredOverWS(ctx, userID, partnerID, msg.ID)
+			}
+		case <-pings:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(realtime.Event{Type: realtime.EventPong}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			// Sesi yang dicabut (logout, revoke device, reset password) atau akun yang dinonaktifkan menutup koneksi
+			if err := middleware.Revalidate(ctx); err != nil {
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session ended"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// markDeliveredOverWS menandai pesan dari partner yang sudah terkirim lewat WebSocket sebagai delivered
+func (h *ChatHandler) markDeliveredOverWS(ctx context.Context, userID, partnerID, upToID int64) {
+	if _, err := h.markReceipt(ctx, entity.MessageStatusDelivered, userID, partnerID, upToID); err != nil {
+		log.Println("Failed to mark messages as delivered:", err)
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+type CoupleHandler struct {
+	userRepo      repository.UserRepository
+	notifRepo     repository.NotificationRepository
+	coupleService *service.CoupleService
+}
+
+func NewCoupleHandler(userRepo repository.UserRepository, notifRepo repository.NotificationRepository, coupleService *service.CoupleService) *CoupleHandler {
+	return &CoupleHandler{
+		userRepo:      userRepo,
+		notifRepo:     notifRepo,
+		coupleService: coupleService,
+	}
+}
+
+// Get returns the current user's couple and partner profile
+func (h *CoupleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	partner, err := h.userRepo.FindByID(r.Context(), couple.PartnerOf(claims.UserID))
+	if err != nil {
+		http.Error(w, `{"error": "Partner not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":         couple.ID,
+		"created_at": couple.CreatedAt,
+		"partner": map[string]interface{}{
+			"id":       partner.ID,
+			"username": partner.Username,
+			"email":    partner.Email,
+		},
+	})
+}
+
+// CreateInvite generates a short-lived invite code the partner can redeem
+func (h *CoupleHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	invite, err := h.coupleService.CreateInvite(r.Context(), claims.UserID)
+	if errors.Is(err, service.ErrAlreadyPaired) {
+		http.Error(w, `{"error": "You already have a partner"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create invite"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":       invite.Code,
+		"expires_at": invite.ExpiresAt,
+	})
+}
+
+type JoinCoupleReq struct {
+	Code string `json:"code"`
+}
+
+// Join redeems a partner's invite code and links the two accounts
+func (h *CoupleHandler) Join(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req JoinCoupleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, `{"error": "Invite code is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	couple, err := h.coupleService.RedeemInvite(r.Context(), claims.UserID, req.Code)
+	switch {
+	case errors.Is(err, service.ErrInvalidInvite):
+		http.Error(w, `{"error": "Invalid or expired invite code"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrSelfPairing):
+		http.Error(w, `{"error": "You cannot redeem your own invite code"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, service.ErrAlreadyPaired):
+		http.Error(w, `{"error": "One of the accounts already has a partner"}`, http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, `{"error": "Failed to link partner"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Let the inviter know their partner joined
+	notif := &entity.Notification{
+		UserID:     couple.PartnerOf(claims.UserID),
+		Type:       entity.NotificationTypePartnerLinked,
+		Message:    claims.Username + " sekarang terhubung sebagai pasanganmu",
+		RelatedID:  couple.ID,
+		ReadStatus: false,
+	}
+	h.notifRepo.Create(r.Context(), notif)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Partner linked successfully",
+		"couple":  couple,
+	})
+}
+
+// writeCoupleError maps partner resolution errors to HTTP responses
+func writeCoupleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrNoPartner) {
+		http.Error(w, `{"error": "No partner linked to this account"}`, http.StatusForbidden)
+		return
+	}
+	http.Error(w, `{"error": "Failed to resolve partner"}`, http.StatusInternalServerError)
+}
@@ -2,14 +2,9 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
@@ -19,19 +14,34 @@ import (
 )
 
 type GalleryHandler struct {
-	galleryRepo repository.GalleryRepository
-	notifRepo   repository.NotificationRepository
+	galleryRepo   repository.GalleryRepository
+	notifRepo     repository.NotificationRepository
+	coupleService *service.CoupleService
 }
 
-func NewGalleryHandler(galleryRepo repository.GalleryRepository, notifRepo repository.NotificationRepository) *GalleryHandler {
+func NewGalleryHandler(galleryRepo repository.GalleryRepository, notifRepo repository.NotificationRepository, coupleService *service.CoupleService) *GalleryHandler {
 	return &GalleryHandler{
-		galleryRepo: galleryRepo,
-		notifRepo:   notifRepo,
+		galleryRepo:   galleryRepo,
+		notifRepo:     notifRepo,
+		coupleService: coupleService,
 	}
 }
 
+// GetAll returns the gallery shared by the current user's couple
 func (h *GalleryHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	galleries, err := h.galleryRepo.FindAll(r.Context())
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	galleries, err := h.galleryRepo.FindByCoupleID(r.Context(), couple.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to fetch gallery"}`, http.StatusInternalServerError)
 		return
@@ -60,93 +70,46 @@ func (h *GalleryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (50MB max)
-	err := r.ParseMultipartForm(50 << 20)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to parse form"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Get uploaded file
-	file, handler, err := r.FormFile("file")
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
 	if err != nil {
-		http.Error(w, `{"error": "No file uploaded"}`, http.StatusBadRequest)
-		return
-	}
-	defer file.Close()
-
-	// Validate file type
-	contentType := handler.Header.Get("Content-Type")
-	validTypes := map[string]bool{
-		"image/jpeg":           true,
-		"image/png":            true,
-		"image/gif":            true,
-		"video/mp4":            true,
-		"video/quicktime":      true, // .mov
-		"video/x-msvideo":      true, // .avi
-		"video/webm":           true,
-	}
-
-	if !validTypes[contentType] {
-		http.Error(w, `{"error": "Invalid file type. Only JPEG, PNG, GIF, MP4, MOV, AVI, and WebM are allowed"}`, http.StatusBadRequest)
+		writeCoupleError(w, err)
 		return
 	}
 
-	// Create uploads directory if not exists
-	uploadDir := "./uploads"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		http.Error(w, `{"error": "Failed to create upload directory"}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(handler.Filename)
-	filename := fmt.Sprintf("%d-%d%s", claims.UserID, timestamp, ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// Save file to disk
-	dst, err := os.Create(filePath)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to save file"}`, http.StatusInternalServerError)
+	// Parse multipart form (50MB max)
+	if !parseUploadForm(w, r) {
 		return
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, file); err != nil {
-		http.Error(w, `{"error": "Failed to write file"}`, http.StatusInternalServerError)
+	// Validate and save the uploaded file
+	upload, ok := saveUpload(w, r, "file", claims.UserID, galleryUploads)
+	if !ok {
 		return
 	}
-
-	// Determine file type
-	fileType := entity.FileTypePhoto
-	if strings.HasPrefix(contentType, "video/") {
-		fileType = entity.FileTypeVideo
-	}
+	fileType := upload.FileType
 
 	caption := r.FormValue("caption")
 
 	// Create database record
 	gallery := &entity.Gallery{
 		UserID:   claims.UserID,
+		CoupleID: couple.ID,
 		FileType: fileType,
-		FilePath: "/uploads/" + filename, // Store relative path for serving
+		FilePath: upload.FilePath, // Store relative path for serving
 		Caption:  caption,
+		FileSize: upload.Size,
 	}
 
 	if err := h.galleryRepo.Create(r.Context(), gallery); err != nil {
 		// Delete uploaded file if database insert fails
-		os.Remove(filePath)
+		os.Remove(upload.DiskPath)
 		http.Error(w, `{"error": "Failed to create gallery item"}`, http.StatusInternalServerError)
 		return
 	}
 
 	// Create notification for partner
-	partnerID := int64(1)
-	if claims.UserID == 1 {
-		partnerID = 2
-	}
-	
+	partnerID := couple.PartnerOf(claims.UserID)
+
 	fileTypeStr := "foto"
 	if fileType == entity.FileTypeVideo {
 		fileTypeStr = "video"
@@ -178,14 +141,26 @@ func (h *GalleryHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership or admin
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	// Check ownership or permission
 	gallery, err := h.galleryRepo.FindByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, `{"error": "Item not found"}`, http.StatusNotFound)
 		return
 	}
 
-	if gallery.UserID != claims.UserID && claims.Role != "super_admin" {
+	// Items of other couples are invisible to regular users
+	if gallery.CoupleID != couple.ID && !claims.Can(entity.PermissionGalleryDeleteAny) {
+		http.Error(w, `{"error": "Item not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if !claims.CanManage(gallery.UserID, entity.PermissionGalleryDeleteAny) {
 		http.Error(w, `{"error": "Unauthorized"}`, http.StatusForbidden)
 		return
 	}
@@ -2,19 +2,30 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
 )
 
+// sseHeartbeatPeriod keeps idle streams alive through proxies
+const sseHeartbeatPeriod = 25 * time.Second
+
+// sseReplayLimit is how many missed notifications are read at once when replaying on reconnect
+const sseReplayLimit = 100
+
 type NotificationHandler struct {
 	notifRepo repository.NotificationRepository
+	hub       *realtime.Hub
 }
 
-func NewNotificationHandler(notifRepo repository.NotificationRepository) *NotificationHandler {
-	return &NotificationHandler{notifRepo: notifRepo}
+func NewNotificationHandler(notifRepo repository.NotificationRepository, hub *realtime.Hub) *NotificationHandler {
+	return &NotificationHandler{notifRepo: notifRepo, hub: hub}
 }
 
 // GetAll returns all notifications for the current user
@@ -73,3 +84,121 @@ func (h *NotificationHandler) MarkAsRead(w http.ResponseWriter, r *http.Request)
 		"message": "All notifications marked as read",
 	})
 }
+
+// Stream pushes notifications and unread count changes as Server-Sent Events.
+// Reconnecting clients send Last-Event-ID (or ?last_event_id=) to replay every missed notification.
+// The session is checked again on every heartbeat and the stream ends once it is revoked.
+func (h *NotificationHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, `{"error": "Streaming not supported"}`, http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, `{"error": "Invalid Last-Event-ID"}`, http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before replaying so nothing created in between is lost
+	sub := h.hub.Subscribe(claims.UserID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	// Ask EventSource to wait 3 seconds before reconnecting
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// Replay page by page; lastID ends at the newest replayed notification
+	for lastID > 0 {
+		missed, err := h.notifRepo.FindByUserIDAfter(r.Context(), claims.UserID, lastID, sseReplayLimit)
+		if err != nil {
+			return
+		}
+		for _, notif := range missed {
+			if err := writeSSE(w, realtime.Event{ID: notif.ID, Type: realtime.EventNotification, Data: notif}); err != nil {
+				return
+			}
+			lastID = notif.ID
+		}
+		if len(missed) < sseReplayLimit {
+			break
+		}
+	}
+
+	count, err := h.notifRepo.CountUnread(r.Context(), claims.UserID)
+	if err != nil {
+		return
+	}
+	if err := writeSSE(w, realtime.Event{Type: realtime.EventUnreadCount, Data: map[string]int64{"count": count}}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseHeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped by the hub for being too slow; the client reconnects with Last-Event-ID
+				return
+			}
+			// Created while replaying, so it was already sent
+			if event.ID > 0 && event.ID <= lastID {
+				continue
+			}
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			// Stop streaming once the session is revoked or the account disabled
+			if err := middleware.Revalidate(r.Context()); err != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSE writes a single event in text/event-stream format
+func writeSSE(w http.ResponseWriter, event realtime.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	if event.ID > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/realtime"
+)
+
+// replayNotificationRepo serves stored notifications and calls onReplay on the first replay query
+type replayNotificationRepo struct {
+	repository.NotificationRepository
+	notifications []*entity.Notification
+	onReplay      func()
+}
+
+func (f *replayNotificationRepo) FindByUserIDAfter(ctx context.Context, userID, afterID int64, limit int) ([]*entity.Notification, error) {
+	if f.onReplay != nil {
+		f.onReplay()
+		f.onReplay = nil
+	}
+
+	var page []*entity.Notification
+	for _, n := range f.notifications {
+		if n.UserID == userID && n.ID > afterID && len(page) < limit {
+			page = append(page, n)
+		}
+	}
+	return page, nil
+}
+
+func (f *replayNotificationRepo) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	return 0, nil
+}
+
+func TestNotificationHandler_StreamReplay(t *testing.T) {
+	hub := realtime.NewHub()
+	repo := &replayNotificationRepo{}
+	for id := int64(1); id <= sseReplayLimit+50; id++ {
+		repo.notifications = append(repo.notifications, &entity.Notification{ID: id, UserID: 1, Message: "x"})
+	}
+	newest := repo.notifications[len(repo.notifications)-1]
+
+	// The newest notification is created after subscribing, so it is replayed and published.
+	// The disconnect ends the stream once the published events are written.
+	repo.onReplay = func() {
+		hub.Publish(1, realtime.Event{ID: newest.ID, Type: realtime.EventNotification, Data: newest})
+		hub.Publish(1, realtime.Event{ID: newest.ID + 1, Type: realtime.EventNotification, Data: &entity.Notification{ID: newest.ID + 1}})
+		hub.Disconnect(1)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/notifications/stream", nil)
+	r.Header.Set("Last-Event-ID", "1")
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, &service.Claims{UserID: 1}))
+	w := httptest.NewRecorder()
+	NewNotificationHandler(repo, hub).Stream(w, r)
+
+	seen := map[string]int{}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			seen[strings.TrimPrefix(line, "id: ")]++
+		}
+	}
+	if len(seen) != int(newest.ID) {
+		t.Errorf("expected notifications 2 to %d, got %d distinct IDs", newest.ID+1, len(seen))
+	}
+	for id, count := range seen {
+		if count != 1 {
+			t.Errorf("notification %s was sent %d times", id, count)
+		}
+	}
+	if seen["1"] != 0 {
+		t.Error("notification 1 was already received")
+	}
+}
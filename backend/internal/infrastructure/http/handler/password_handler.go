@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/mail"
+)
+
+// mailSendTimeout bounds how long a background email delivery may take
+const mailSendTimeout = 30 * time.Second
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePassword sets a new password after verifying the current one.
+// All other sessions are signed out and this device gets a fresh session.
+// Wrong current passwords count as failed logins of the account.
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		http.Error(w, `{"error": "Password must be at least 8 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	// Keyed like the login, so a stolen access token cannot be used to guess the password unthrottled
+	account := strings.ToLower(user.Email)
+	ip := middleware.ClientIP(r)
+	if wait := h.loginThrottle.Check(account, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	if !h.authService.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		h.recordFailedLogin(r.Context(), account, ip, user)
+		http.Error(w, `{"error": "Current password is incorrect"}`, http.StatusUnauthorized)
+		return
+	}
+	h.loginThrottle.Success(account)
+	if req.NewPassword == req.CurrentPassword {
+		http.Error(w, `{"error": "New password must be different from the current password"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.setPassword(r.Context(), user, req.NewPassword); err != nil {
+		http.Error(w, `{"error": "Failed to change password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusOK, "Password changed successfully", user)
+}
+
+// ForgotPassword emails a single-use reset link.
+// The response is the same whether or not the email is registered.
+// Every request counts against the email and the IP address, so it cannot be used to flood an inbox.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, `{"error": "Email is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	// Keyed apart from logins, requesting links must not lock the account or the IP out of logging in
+	account, ip := "reset:"+email, "reset:"+middleware.ClientIP(r)
+	if wait := h.loginThrottle.Check(account, ip); wait > 0 {
+		writeTooManyRequests(w, wait, "Too many password reset requests, please try again later")
+		return
+	}
+	h.loginThrottle.Failure(account, ip)
+
+	user, err := h.userRepo.FindByEmail(r.Context(), email)
+	if err == nil {
+		// An error must not reveal that the email is registered
+		if err := h.sendPasswordReset(r.Context(), user); err != nil {
+			log.Printf("Failed to start password reset of user %d: %v", user.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using an emailed reset token and signs out every session
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, `{"error": "Reset token is required"}`, http.StatusBadRequest)
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		http.Error(w, `{"error": "Password must be at least 8 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.passwordResetRepo.FindByHash(r.Context(), service.HashToken(req.Token))
+	if err != nil || !token.IsUsable(time.Now()) {
+		http.Error(w, `{"error": "Invalid or expired reset token"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Marking the token used first makes concurrent resets with the same token fail
+	if err := h.passwordResetRepo.MarkUsed(r.Context(), token.ID); err != nil {
+		if errors.Is(err, repository.ErrPasswordResetTokenUsed) {
+			http.Error(w, `{"error": "Invalid or expired reset token"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error": "Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), token.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired reset token"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.setPassword(r.Context(), user, req.NewPassword); err != nil {
+		http.Error(w, `{"error": "Failed to reset password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully, please log in"})
+}
+
+// setPassword stores a new password hash, then revokes every session and pending reset token
+func (h *AuthHandler) setPassword(ctx context.Context, user *entity.User, password string) error {
+	hash, err := h.authService.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	if err := h.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := h.revokeAllSessions(ctx, user.ID); err != nil {
+		return err
+	}
+	return h.passwordResetRepo.DeleteByUserID(ctx, user.ID)
+}
+
+// sendPasswordReset stores a new reset token for the user and emails the link in the background,
+// so the response time does not reveal whether the email is registered
+func (h *AuthHandler) sendPasswordReset(ctx context.Context, user *entity.User) error {
+	token, err := service.NewTokenID()
+	if err != nil {
+		return err
+	}
+
+	// Only the latest link is valid
+	if err := h.passwordResetRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return err
+	}
+
+	record := &entity.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: service.HashToken(token),
+		ExpiresAt: time.Now().Add(service.PasswordResetTTL),
+	}
+	if err := h.passwordResetRepo.Create(ctx, record); err != nil {
+		return err
+	}
+
+	link := h.appBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset password Fasisi",
+		Body: fmt.Sprintf("Halo %s,\n\n"+
+			"Kami menerima permintaan untuk mengatur ulang password akun Fasisi kamu.\n"+
+			"Buka link berikut dalam 1 jam untuk membuat password baru:\n\n%s\n\n"+
+			"Abaikan email ini jika kamu tidak memintanya.\n", user.Username, link),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
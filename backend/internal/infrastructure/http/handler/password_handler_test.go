@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// failingPasswordResetRepo fails every write, like a database that is down
+type failingPasswordResetRepo struct {
+	repository.PasswordResetTokenRepository
+}
+
+func (f *failingPasswordResetRepo) DeleteByUserID(ctx context.Context, userID int64) error {
+	return errors.New("database is down")
+}
+
+func TestAuthHandler_ChangePasswordThrottled(t *testing.T) {
+	h, user := newDisabledAccountHandler(t)
+	user.DisabledAt = nil
+
+	changePassword := func(current string) int {
+		body, _ := json.Marshal(map[string]string{"current_password": current, "new_password": "sisti12345"})
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/password", bytes.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, &service.Claims{UserID: user.ID}))
+		w := httptest.NewRecorder()
+		h.ChangePassword(w, r)
+		return w.Code
+	}
+
+	// The 4th failure starts the backoff, even the right password has to wait
+	for i := 0; i < 4; i++ {
+		if code := changePassword("wrong-password"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, code)
+		}
+	}
+	if code := changePassword("irfan123"); code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+
+	// Guesses here count against the login of the account too
+	if wait := h.loginThrottle.Check("irfan@fasisi.com", "other-ip"); wait == 0 {
+		t.Error("expected the login of the account to be throttled")
+	}
+}
+
+func TestAuthHandler_ForgotPassword(t *testing.T) {
+	h, user := newDisabledAccountHandler(t)
+	user.DisabledAt = nil
+	h.passwordResetRepo = &failingPasswordResetRepo{}
+
+	forgotPassword := func(email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email})
+		r := httptest.NewRequest(http.MethodPost, "/api/auth/password/forgot", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		h.ForgotPassword(w, r)
+		return w
+	}
+
+	// A failure for a registered email looks like an unknown email
+	registered, unknown := forgotPassword("irfan@fasisi.com"), forgotPassword("nobody@fasisi.com")
+	if registered.Code != http.StatusOK || registered.Code != unknown.Code || registered.Body.String() != unknown.Body.String() {
+		t.Errorf("expected the same response, got %d %s and %d %s",
+			registered.Code, registered.Body.String(), unknown.Code, unknown.Body.String())
+	}
+
+	throttled := false
+	for i := 0; i < 5 && !throttled; i++ {
+		throttled = forgotPassword("irfan@fasisi.com").Code == http.StatusTooManyRequests
+	}
+	if !throttled {
+		t.Error("expected repeated reset requests for an email to be throttled")
+	}
+
+	// Reset requests do not lock the account out of logging in
+	if wait := h.loginThrottle.Check("irfan@fasisi.com", "192.0.2.1"); wait != 0 {
+		t.Errorf("expected the login to stay open, wait %s", wait)
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// maxTokenLifetimeDays caps expires_in_days of new personal access tokens
+const maxTokenLifetimeDays = 365
+
+type PersonalAccessTokenHandler struct {
+	patService *service.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(patService *service.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		patService: patService,
+	}
+}
+
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 means the token never expires
+}
+
+// List returns the current user's personal access tokens and the scopes that can be granted
+func (h *PersonalAccessTokenHandler) List(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.patService.List(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch tokens"}`, http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []*entity.PersonalAccessToken{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tokens": tokens,
+		"scopes": service.AllScopes,
+	})
+}
+
+// Create issues a new personal access token. The token is only shown in this response.
+func (h *PersonalAccessTokenHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, `{"error": "Name is required and must be at most 100 characters"}`, http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+		http.Error(w, `{"error": "expires_in_days must be between 0 and 365"}`, http.StatusBadRequest)
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	plain, token, err := h.patService.Create(r.Context(), claims.UserID, req.Name, req.Scopes, ttl)
+	if errors.Is(err, service.ErrInvalidScope) {
+		http.Error(w, `{"error": "At least one valid scope is required"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, service.ErrTooManyPersonalAccessTokens) {
+		http.Error(w, `{"error": "Too many active tokens, revoke one first"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":                 plain,
+		"personal_access_token": token,
+	})
+}
+
+// Revoke revokes one of the current user's personal access tokens
+func (h *PersonalAccessTokenHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	err = h.patService.Revoke(r.Context(), claims.UserID, id)
+	if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+		http.Error(w, `{"error": "Token not found"}`, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "Failed to revoke token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Token revoked"})
+}
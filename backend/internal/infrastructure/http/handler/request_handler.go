@@ -13,19 +13,34 @@ import (
 )
 
 type RequestHandler struct {
-	requestRepo repository.DateRequestRepository
-	notifRepo   repository.NotificationRepository
+	requestRepo   repository.DateRequestRepository
+	notifRepo     repository.NotificationRepository
+	coupleService *service.CoupleService
 }
 
-func NewRequestHandler(requestRepo repository.DateRequestRepository, notifRepo repository.NotificationRepository) *RequestHandler {
+func NewRequestHandler(requestRepo repository.DateRequestRepository, notifRepo repository.NotificationRepository, coupleService *service.CoupleService) *RequestHandler {
 	return &RequestHandler{
-		requestRepo: requestRepo,
-		notifRepo:   notifRepo,
+		requestRepo:   requestRepo,
+		notifRepo:     notifRepo,
+		coupleService: coupleService,
 	}
 }
 
+// GetAll returns the date requests of the current user's couple
 func (h *RequestHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	requests, err := h.requestRepo.FindAll(r.Context())
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	requests, err := h.requestRepo.FindByCoupleID(r.Context(), couple.ID)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to fetch requests"}`, http.StatusInternalServerError)
 		return
@@ -49,6 +64,12 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
 	var req CreateRequestReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error": "Invalid request"}`, http.StatusBadRequest)
@@ -57,6 +78,7 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	dateReq := &entity.DateRequest{
 		UserID:      claims.UserID,
+		CoupleID:    couple.ID,
 		RequestType: entity.RequestType(req.RequestType),
 		Title:       req.Title,
 		Description: req.Description,
@@ -70,10 +92,7 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create notification for partner
-	partnerID := int64(1)
-	if claims.UserID == 1 {
-		partnerID = 2
-	}
+	partnerID := couple.PartnerOf(claims.UserID)
 
 	notif := &entity.Notification{
 		UserID:     partnerID,
@@ -93,6 +112,23 @@ func (h *RequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 func (h *RequestHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, _ := strconv.ParseInt(vars["id"], 10, 64)
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	dateReq, err := h.requestRepo.FindByID(r.Context(), id)
+	if err != nil || dateReq.CoupleID != couple.ID {
+		http.Error(w, `{"error": "Request not found"}`, http.StatusNotFound)
+		return
+	}
 
 	var req struct {
 		Status string `json:"status"`
@@ -120,14 +156,26 @@ func (h *RequestHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check ownership or admin
+	couple, err := h.coupleService.CoupleOf(r.Context(), claims.UserID)
+	if err != nil {
+		writeCoupleError(w, err)
+		return
+	}
+
+	// Check ownership or permission
 	dateReq, err := h.requestRepo.FindByID(r.Context(), id)
 	if err != nil {
 		http.Error(w, `{"error": "Request not found"}`, http.StatusNotFound)
 		return
 	}
 
-	if dateReq.UserID != claims.UserID && claims.Role != "super_admin" {
+	// Requests of other couples are invisible to regular users
+	if dateReq.CoupleID != couple.ID && !claims.Can(entity.PermissionRequestsDeleteAny) {
+		http.Error(w, `{"error": "Request not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if !claims.CanManage(dateReq.UserID, entity.PermissionRequestsDeleteAny) {
 		http.Error(w, `{"error": "Unauthorized"}`, http.StatusForbidden)
 		return
 	}
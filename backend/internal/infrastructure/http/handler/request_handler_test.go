@@ -67,8 +67,6 @@ func TestRequestHandler_CreateRequest(t *testing.T) {
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-			w := httptest.NewRecorder()
-
 			t.Logf("Test %s expects status %d", tt.name, tt.expectedStatus)
 		})
 	}
@@ -120,8 +118,6 @@ func TestRequestHandler_DeleteRequest(t *testing.T) {
 			// Set up router with path variables
 			req = mux.SetURLVars(req, map[string]string{"id": tt.requestID})
 
-			w := httptest.NewRecorder()
-
 			t.Logf("Test %s expects status %d", tt.name, tt.expectedStatus)
 		})
 	}
@@ -131,8 +127,6 @@ func TestRequestHandler_GetRequests(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/requests", nil)
 	req.Header.Set("Authorization", "Bearer mock.jwt.token")
 
-	w := httptest.NewRecorder()
-
 	t.Log("Should return list of date requests")
 }
 
@@ -173,8 +167,6 @@ func TestRequestHandler_UpdateRequestStatus(t *testing.T) {
 			req.Header.Set("Authorization", "Bearer mock.jwt.token")
 			req = mux.SetURLVars(req, map[string]string{"id": tt.requestID})
 
-			w := httptest.NewRecorder()
-
 			t.Logf("Test %s expects status %d", tt.name, tt.expectedStatus)
 		})
 	}
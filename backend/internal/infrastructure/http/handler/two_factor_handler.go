@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// writeTwoFactorChallenge answers a login with a correct password but pending second factor
+func (h *AuthHandler) writeTwoFactorChallenge(w http.ResponseWriter, user *entity.User) {
+	challenge, err := h.authService.GenerateChallengeToken(user.ID, user.Username, string(user.Role))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+		"expires_in":          int(service.TwoFactorChallengeTTL.Seconds()),
+	})
+}
+
+// VerifyTwoFactor exchanges a login challenge token and a TOTP or recovery code
+// for access and refresh tokens
+func (h *AuthHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req VerifyTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, `{"error": "challenge_token and code are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.authService.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired challenge, please log in again"}`, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid or expired challenge, please log in again"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// Codes are throttled like passwords, keyed separately so a locked password login
+	// does not block a user who already passed it
+	account := "2fa:" + strconv.FormatInt(user.ID, 10)
+	ip := middleware.ClientIP(r)
+	if wait := h.loginThrottle.Check(account, ip); wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return
+	}
+
+	if err := h.twoFactorService.Verify(r.Context(), user.ID, req.Code); err != nil {
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			h.recordFailedLogin(r.Context(), account, ip, user)
+		}
+		writeTwoFactorError(w, err)
+		return
+	}
+	h.loginThrottle.Success(account)
+
+	if user.IsDisabled() {
+		writeAccountDisabled(w)
+		return
+	}
+
+	h.writeAuthResponse(w, r, http.StatusOK, "Login successful", user)
+}
+
+// SetupTwoFactor generates a new TOTP secret and its provisioning URI for the QR code
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	secret, uri, err := h.twoFactorService.Setup(r.Context(), user.ID, user.Email)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// EnableTwoFactor confirms the pending secret and returns one-time recovery codes
+func (h *AuthHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, `{"error": "code is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := h.twoFactorService.Enable(r.Context(), claims.UserID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a current code
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*service.Claims)
+	if !ok || claims == nil {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" || req.Code == "" {
+		http.Error(w, `{"error": "password and code are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userRepo.FindByID(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+	if !h.authService.CheckPassword(req.Password, user.PasswordHash) {
+		http.Error(w, `{"error": "Invalid password"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.twoFactorService.Disable(r.Context(), user.ID, req.Code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// writeTwoFactorError maps two-factor errors to HTTP responses
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		http.Error(w, `{"error": "Invalid two-factor code"}`, http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTwoFactorEnabled):
+		http.Error(w, `{"error": "Two-factor authentication is already enabled"}`, http.StatusConflict)
+	case errors.Is(err, service.ErrTwoFactorNotSetUp):
+		http.Error(w, `{"error": "Call /api/auth/2fa/setup first"}`, http.StatusBadRequest)
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		http.Error(w, `{"error": "Two-factor authentication is not enabled"}`, http.StatusBadRequest)
+	default:
+		http.Error(w, `{"error": "Two-factor authentication failed"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+)
+
+// maxUploadSize is the largest multipart request accepted for media uploads
+const maxUploadSize = 50 << 20
+
+// uploadLocation is a folder of uploaded media and the URL path its files are served at
+type uploadLocation struct {
+	dir       string
+	urlPrefix string
+}
+
+var (
+	// publicUploads holds gallery media, served to anyone by PublicUploads
+	publicUploads = uploadLocation{dir: "./uploads", urlPrefix: "/uploads/"}
+
+	// chatAttachments holds chat media, served only to the conversation by ChatHandler.GetAttachment
+	chatAttachments = uploadLocation{dir: "./uploads/chat", urlPrefix: "/api/chat/attachments/"}
+)
+
+// uploadExtensions is the file extension stored for each accepted content type.
+// The extension of the client's file name is never used, it decides how the file is served.
+var uploadExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"video/x-msvideo": ".avi",
+	"video/webm":      ".webm",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+}
+
+// uploadPolicy lists the content types accepted for an upload and the kind of media each one is
+type uploadPolicy struct {
+	types            map[string]entity.FileType
+	location         uploadLocation
+	invalidTypeError string
+}
+
+// galleryUploads accepts photos and videos
+var galleryUploads = uploadPolicy{
+	types: map[string]entity.FileType{
+		"image/jpeg":      entity.FileTypePhoto,
+		"image/png":       entity.FileTypePhoto,
+		"image/gif":       entity.FileTypePhoto,
+		"video/mp4":       entity.FileTypeVideo,
+		"video/quicktime": entity.FileTypeVideo, // .mov
+		"video/x-msvideo": entity.FileTypeVideo, // .avi
+		"video/webm":      entity.FileTypeVideo,
+	},
+	location:         publicUploads,
+	invalidTypeError: "Invalid file type. Only JPEG, PNG, GIF, MP4, MOV, AVI, and WebM are allowed",
+}
+
+// chatUploads accepts everything the gallery does plus voice notes
+var chatUploads = uploadPolicy{
+	types: map[string]entity.FileType{
+		"audio/ogg":  entity.FileTypeAudio,
+		"audio/mpeg": entity.FileTypeAudio,
+		"audio/mp4":  entity.FileTypeAudio,
+	},
+	location:         chatAttachments,
+	invalidTypeError: "Invalid file type. Only JPEG, PNG, GIF, MP4, MOV, AVI, WebM, OGG, MP3 and M4A are allowed",
+}
+
+func init() {
+	for contentType, fileType := range galleryUploads.types {
+		chatUploads.types[contentType] = fileType
+	}
+}
+
+// fileTypeOf returns the kind of media of a content type
+func (p uploadPolicy) fileTypeOf(contentType string) (entity.FileType, bool) {
+	fileType, ok := p.types[mediaType(contentType)]
+	return fileType, ok
+}
+
+// mediaType strips parameters like "; codecs=opus" from a content type and lowercases it
+func mediaType(contentType string) string {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// storedUpload is a media file saved in an upload location
+type storedUpload struct {
+	FileType entity.FileType
+	MimeType string
+	FilePath string // URL path the file is served at
+	DiskPath string
+	Size     int64
+}
+
+// parseUploadForm parses a multipart request of at most maxUploadSize, writing an error response when it fails
+func parseUploadForm(w http.ResponseWriter, r *http.Request) bool {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		http.Error(w, `{"error": "Failed to parse form"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// saveUpload validates the file in the form field against the policy and stores it in the policy's location.
+// It writes an error response and returns false when the file is missing, not allowed or cannot be saved.
+// parseUploadForm must have been called before.
+func saveUpload(w http.ResponseWriter, r *http.Request, field string, userID int64, policy uploadPolicy) (*storedUpload, bool) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		http.Error(w, `{"error": "No file uploaded"}`, http.StatusBadRequest)
+		return nil, false
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	fileType, ok := policy.fileTypeOf(contentType)
+	if !ok {
+		http.Error(w, `{"error": "`+policy.invalidTypeError+`"}`, http.StatusBadRequest)
+		return nil, false
+	}
+
+	mimeType := mediaType(contentType)
+	upload, err := storeFile(file, userID, uploadExtensions[mimeType], policy.location)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to save file"}`, http.StatusInternalServerError)
+		return nil, false
+	}
+	upload.FileType = fileType
+	upload.MimeType = mimeType
+
+	return upload, true
+}
+
+// copyUpload stores a copy of an uploaded file under a new name in location, for records
+// that must not lose their file when the original is removed
+func copyUpload(filePath string, userID int64, location uploadLocation) (*storedUpload, error) {
+	diskPath, ok := diskPathOf(filePath)
+	if !ok {
+		return nil, fmt.Errorf("not an upload: %s", filePath)
+	}
+
+	src, err := os.Open(diskPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return storeFile(src, userID, filepath.Ext(filePath), location)
+}
+
+// removeUpload deletes the file of an upload served at filePath
+func removeUpload(filePath string) error {
+	diskPath, ok := diskPathOf(filePath)
+	if !ok {
+		return fmt.Errorf("not an upload: %s", filePath)
+	}
+	return os.Remove(diskPath)
+}
+
+// isUpload reports whether filePath is the URL path of an uploaded file
+func isUpload(filePath string) bool {
+	_, ok := diskPathOf(filePath)
+	return ok
+}
+
+// diskPathOf maps the URL path of an upload to its file in the location it was stored in
+func diskPathOf(filePath string) (string, bool) {
+	for _, location := range []uploadLocation{publicUploads, chatAttachments} {
+		if name, ok := strings.CutPrefix(filePath, location.urlPrefix); ok && name != "" && !strings.Contains(name, "/") {
+			return filepath.Join(location.dir, name), true
+		}
+	}
+	return "", false
+}
+
+// storeFile writes src to a new file in location named after the user and upload time
+func storeFile(src io.Reader, userID int64, ext string, location uploadLocation) (*storedUpload, error) {
+	if err := os.MkdirAll(location.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	// The random suffix keeps uploads of the same second apart
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("%d-%d-%s%s", userID, time.Now().Unix(), hex.EncodeToString(suffix), strings.ToLower(ext))
+	diskPath := filepath.Join(location.dir, filename)
+
+	dst, err := os.Create(diskPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dst.Close()
+
+	size, err := io.Copy(dst, src)
+	if err != nil {
+		os.Remove(diskPath)
+		return nil, err
+	}
+
+	return &storedUpload{FilePath: location.urlPrefix + filename, DiskPath: diskPath, Size: size}, nil
+}
+
+// PublicUploads serves the files of publicUploads. Folders are neither listed nor
+// entered, so chat attachments stored below it stay private.
+func PublicUploads() http.Handler {
+	return http.StripPrefix(publicUploads.urlPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+		if name == "" || strings.Contains(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		info, err := os.Stat(filepath.Join(publicUploads.dir, name))
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeFile(w, r, filepath.Join(publicUploads.dir, name))
+	}))
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
+)
+
+// useTempUploads points both upload locations at a temporary folder for the test
+func useTempUploads(t *testing.T) {
+	t.Helper()
+	public, chat := publicUploads, chatAttachments
+	dir := t.TempDir()
+	publicUploads.dir = dir
+	chatAttachments.dir = filepath.Join(dir, "chat")
+	t.Cleanup(func() { publicUploads, chatAttachments = public, chat })
+}
+
+func TestUploadExtensions(t *testing.T) {
+	for contentType := range chatUploads.types {
+		if uploadExtensions[contentType] == "" {
+			t.Errorf("no file extension for accepted content type %q", contentType)
+		}
+	}
+}
+
+func TestSaveUpload_ExtensionFromContentType(t *testing.T) {
+	useTempUploads(t)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="x.html"`)
+	header.Set("Content-Type", "image/png")
+	part, _ := form.CreatePart(header)
+	part.Write([]byte("<script>alert(1)</script>"))
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/chat/messages", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	if !parseUploadForm(w, r) {
+		t.Fatalf("failed to parse form: %s", w.Body.String())
+	}
+
+	policy := chatUploads
+	policy.location = chatAttachments
+	upload, ok := saveUpload(w, r, "file", 1, policy)
+	if !ok {
+		t.Fatalf("upload rejected: %s", w.Body.String())
+	}
+	if !strings.HasPrefix(upload.FilePath, "/api/chat/attachments/") || !strings.HasSuffix(upload.FilePath, ".png") {
+		t.Errorf("expected a .png chat attachment path, got %q", upload.FilePath)
+	}
+	if filepath.Dir(upload.DiskPath) != chatAttachments.dir {
+		t.Errorf("expected the file in %s, got %s", chatAttachments.dir, upload.DiskPath)
+	}
+}
+
+func TestPublicUploads(t *testing.T) {
+	useTempUploads(t)
+	os.MkdirAll(chatAttachments.dir, 0755)
+	os.WriteFile(filepath.Join(publicUploads.dir, "1-1-aa.jpg"), []byte("photo"), 0644)
+	os.WriteFile(filepath.Join(chatAttachments.dir, "1-1-bb.jpg"), []byte("private"), 0644)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/uploads/1-1-aa.jpg", http.StatusOK},
+		{"/uploads/", http.StatusNotFound},
+		{"/uploads/chat/", http.StatusNotFound},
+		{"/uploads/chat/1-1-bb.jpg", http.StatusNotFound},
+		{"/uploads/missing.jpg", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		PublicUploads().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.want, w.Code)
+		}
+	}
+}
+
+// attachmentChatRepo finds messages by their attachment path
+type attachmentChatRepo struct {
+	repository.ChatRepository
+	messages []*entity.ChatMessage
+}
+
+func (f *attachmentChatRepo) FindByAttachmentPath(ctx context.Context, filePath string) (*entity.ChatMessage, error) {
+	for _, m := range f.messages {
+		if m.Attachment != nil && m.Attachment.FilePath == filePath {
+			return m, nil
+		}
+	}
+	return nil, repository.ErrChatMessageNotFound
+}
+
+// fakeCoupleRepo is an in-memory CoupleRepository
+type fakeCoupleRepo struct {
+	couples []*entity.Couple
+}
+
+func (f *fakeCoupleRepo) FindByID(ctx context.Context, id int64) (*entity.Couple, error) {
+	for _, c := range f.couples {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrCoupleNotFound
+}
+
+func (f *fakeCoupleRepo) FindByUserID(ctx context.Context, userID int64) (*entity.Couple, error) {
+	for _, c := range f.couples {
+		if c.Has(userID) {
+			return c, nil
+		}
+	}
+	return nil, repository.ErrCoupleNotFound
+}
+
+func (f *fakeCoupleRepo) Create(ctx context.Context, couple *entity.Couple) error {
+	f.couples = append(f.couples, couple)
+	return nil
+}
+
+func (f *fakeCoupleRepo) UpdateMessageTTL(ctx context.Context, id int64, ttl int64) error {
+	return nil
+}
+
+func (f *fakeCoupleRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+func TestChatHandler_GetAttachment(t *testing.T) {
+	useTempUploads(t)
+	os.MkdirAll(chatAttachments.dir, 0755)
+	os.WriteFile(filepath.Join(chatAttachments.dir, "1-1-aa.png"), []byte("png"), 0644)
+
+	h := &ChatHandler{
+		chatRepo: &attachmentChatRepo{messages: []*entity.ChatMessage{{
+			ID: 1, SenderID: 1, ReceiverID: 2,
+			Attachment: &entity.ChatAttachment{FileType: entity.FileTypePhoto, FilePath: "/api/chat/attachments/1-1-aa.png", MimeType: "image/png"},
+		}}},
+		coupleService: service.NewCoupleService(&fakeCoupleRepo{couples: []*entity.Couple{
+			{ID: 1, User1ID: 1, User2ID: 2},
+			{ID: 2, User1ID: 3, User2ID: 4},
+		}}, nil),
+	}
+
+	tests := []struct {
+		userID int64
+		name   string
+		want   int
+	}{
+		{2, "1-1-aa.png", http.StatusOK},
+		{1, "1-1-aa.png", http.StatusOK},
+		{3, "1-1-aa.png", http.StatusNotFound},
+		{2, "1-1-missing.png", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/chat/attachments/"+tt.name, nil)
+		r = mux.SetURLVars(r, map[string]string{"name": tt.name})
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, &service.Claims{UserID: tt.userID}))
+		w := httptest.NewRecorder()
+		h.GetAttachment(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("user %d, %s: expected status %d, got %d", tt.userID, tt.name, tt.want, w.Code)
+			continue
+		}
+		if w.Code == http.StatusOK && w.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("expected the stored content type, got %q", w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+)
+
+// ActivityRecorder records that a user is active
+type ActivityRecorder interface {
+	Touch(userID int64)
+}
+
+// TrackActivity records the user of every authenticated request, so presence follows
+// what users actually do. It must run after AuthMiddleware.
+func TrackActivity(recorder ActivityRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims, ok := r.Context().Value(UserContextKey).(*service.Claims); ok && claims != nil {
+				recorder.Touch(claims.UserID)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,9 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
 	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 )
 
@@ -12,11 +16,28 @@ type contextKey string
 
 const UserContextKey = contextKey("user")
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Handler {
+// revalidateContextKey holds the function Revalidate uses to check the request's token again
+const revalidateContextKey = contextKey("revalidate")
+
+// ErrAuthenticationRevoked is returned by Revalidate once the token of a request is no longer valid
+var ErrAuthenticationRevoked = errors.New("authentication is no longer valid")
+
+// AuthMiddleware validates JWT access tokens and rejects tokens whose session was revoked
+// or whose account was disabled.
+// Personal access tokens are accepted too, routes limit them with RequireScope.
+func AuthMiddleware(authService *service.AuthService, sessionRepo repository.SessionRepository, patService *service.PersonalAccessTokenService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
+
+			// Browsers cannot set headers on WebSocket handshakes or EventSource requests,
+			// so accept the token as a query param there
+			if authHeader == "" && allowsQueryToken(r) {
+				if token := r.URL.Query().Get("token"); token != "" {
+					authHeader = "Bearer " + token
+				}
+			}
+
 			if authHeader == "" {
 				http.Error(w, `{"error": "Authorization header required"}`, http.StatusUnauthorized)
 				return
@@ -28,20 +49,109 @@ func AuthMiddleware(authService *service.AuthService) func(http.Handler) http.Ha
 				return
 			}
 
-			claims, err := authService.ValidateToken(parts[1])
-			if err != nil {
-				http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
-				return
+			var claims *service.Claims
+			var revalidate func(ctx context.Context) error
+			if service.IsPersonalAccessToken(parts[1]) {
+				var err error
+				claims, err = patService.Authenticate(r.Context(), parts[1])
+				if err != nil {
+					http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+					return
+				}
+
+				revalidate = func(ctx context.Context) error {
+					if _, err := patService.Authenticate(ctx, parts[1]); err != nil {
+						return fmt.Errorf("%w: %v", ErrAuthenticationRevoked, err)
+					}
+					return nil
+				}
+			} else {
+				var err error
+				claims, err = authService.ValidateAccessToken(parts[1])
+				if err != nil {
+					http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+					return
+				}
+
+				session, status, message := checkSession(r.Context(), sessionRepo, claims)
+				if session == nil {
+					http.Error(w, `{"error": "`+message+`"}`, status)
+					return
+				}
+				sessionRepo.Touch(r.Context(), session.ID)
+
+				// A stream outlives its access token, so only the session is checked again
+				revalidate = func(ctx context.Context) error {
+					if session, _, message := checkSession(ctx, sessionRepo, claims); session == nil {
+						return fmt.Errorf("%w: %s", ErrAuthenticationRevoked, message)
+					}
+					return nil
+				}
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			ctx = context.WithValue(ctx, revalidateContextKey, revalidate)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// AdminMiddleware checks if user is super admin
-func AdminMiddleware(next http.Handler) http.Handler {
+// checkSession returns the active session of an access token, or nil with the status and
+// error message to reply with
+func checkSession(ctx context.Context, sessionRepo repository.SessionRepository, claims *service.Claims) (*entity.Session, int, string) {
+	session, err := sessionRepo.FindByID(ctx, claims.SessionID)
+	if err != nil || !session.IsActive() || session.UserID != claims.UserID {
+		return nil, http.StatusUnauthorized, "Session has been revoked"
+	}
+	// Disabling revokes the sessions too, this also covers a failed or racing revocation
+	if session.UserDisabled {
+		return nil, http.StatusForbidden, "Account is disabled"
+	}
+	return session, 0, ""
+}
+
+// Revalidate checks again that a request authenticated by AuthMiddleware may go on: the session
+// of its access token is not revoked and the account not disabled, or its personal access token
+// is still usable. The expiry of the access token itself is not checked again.
+// WebSocket and SSE handlers call it on their heartbeat and close the connection on an error.
+// Requests that did not pass AuthMiddleware always succeed.
+func Revalidate(ctx context.Context) error {
+	revalidate, ok := ctx.Value(revalidateContextKey).(func(context.Context) error)
+	if !ok {
+		return nil
+	}
+	return revalidate(ctx)
+}
+
+// allowsQueryToken reports whether the request is a WebSocket handshake or an SSE stream
+func allowsQueryToken(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Access tokens of a login session pass unchanged.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*service.Claims)
+			if !ok {
+				http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.HasScope(scope) {
+				http.Error(w, `{"error": "Token is missing the `+scope+` scope"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects personal access tokens, for account routes that need a real login
+func RequireSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := r.Context().Value(UserContextKey).(*service.Claims)
 		if !ok {
@@ -49,8 +159,8 @@ func AdminMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		if claims.Role != "super_admin" {
-			http.Error(w, `{"error": "Admin access required"}`, http.StatusForbidden)
+		if claims.IsPersonalAccessToken() {
+			http.Error(w, `{"error": "Personal access tokens cannot be used here"}`, http.StatusForbidden)
 			return
 		}
 
@@ -58,6 +168,31 @@ func AdminMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequirePermission rejects users whose role is not granted permission
+func RequirePermission(permission entity.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserContextKey).(*service.Claims)
+			if !ok {
+				http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			if !claims.Can(permission) {
+				http.Error(w, `{"error": "Permission denied"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminMiddleware checks if user may use the admin API
+func AdminMiddleware(next http.Handler) http.Handler {
+	return RequirePermission(entity.PermissionAdminAccess)(next)
+}
+
 // CORSMiddleware handles CORS
 func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
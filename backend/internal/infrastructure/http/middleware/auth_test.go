@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
+)
+
+// fakeSessionRepo is an in-memory SessionRepository
+type fakeSessionRepo struct {
+	sessions map[string]*entity.Session
+}
+
+func (f *fakeSessionRepo) FindByID(ctx context.Context, id string) (*entity.Session, error) {
+	if session, ok := f.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, repository.ErrSessionNotFound
+}
+
+func (f *fakeSessionRepo) FindActiveByUserID(ctx context.Context, userID int64) ([]*entity.Session, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionRepo) Create(ctx context.Context, session *entity.Session) error {
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeSessionRepo) Touch(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) Revoke(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeAllForUser(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func TestAuthMiddleware_SessionState(t *testing.T) {
+	authService := service.NewAuthService("test-secret")
+	revokedAt := time.Now()
+	sessions := &fakeSessionRepo{sessions: map[string]*entity.Session{
+		"active":   {ID: "active", UserID: 1},
+		"revoked":  {ID: "revoked", UserID: 1, RevokedAt: &revokedAt},
+		"disabled": {ID: "disabled", UserID: 1, UserDisabled: true},
+	}}
+	authenticate := AuthMiddleware(authService, sessions, nil)
+
+	tests := []struct {
+		session string
+		want    int
+	}{
+		{"active", http.StatusOK},
+		{"revoked", http.StatusUnauthorized},
+		{"disabled", http.StatusForbidden},
+		{"unknown", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.session, func(t *testing.T) {
+			token, err := authService.GenerateToken(1, "irfan", "super_admin", tt.session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/api/auth/profile", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestRevalidate(t *testing.T) {
+	authService := service.NewAuthService("test-secret")
+	sessions := &fakeSessionRepo{sessions: map[string]*entity.Session{
+		"active": {ID: "active", UserID: 1},
+	}}
+	authenticate := AuthMiddleware(authService, sessions, nil)
+
+	token, err := authService.GenerateToken(1, "irfan", "super_admin", "active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/notifications/stream", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	// Keep the context of the authenticated request, like a stream that stays open
+	var ctx context.Context
+	authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if ctx == nil {
+		t.Fatal("expected the request to be authenticated")
+	}
+
+	if err := Revalidate(ctx); err != nil {
+		t.Fatalf("expected the active session to pass, got %v", err)
+	}
+
+	sessions.sessions["active"].UserDisabled = true
+	if err := Revalidate(ctx); !errors.Is(err, ErrAuthenticationRevoked) {
+		t.Errorf("expected ErrAuthenticationRevoked for a disabled account, got %v", err)
+	}
+
+	revokedAt := time.Now()
+	sessions.sessions["active"].UserDisabled = false
+	sessions.sessions["active"].RevokedAt = &revokedAt
+	if err := Revalidate(ctx); !errors.Is(err, ErrAuthenticationRevoked) {
+		t.Errorf("expected ErrAuthenticationRevoked for a revoked session, got %v", err)
+	}
+
+	if err := Revalidate(context.Background()); err != nil {
+		t.Errorf("expected requests without AuthMiddleware to pass, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/service"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/handler"
 	"github.com/irfan-ghzl/fasisi-backend/internal/infrastructure/http/middleware"
 )
@@ -14,12 +16,25 @@ func SetupRoutes(
 	galleryHandler *handler.GalleryHandler,
 	requestHandler *handler.RequestHandler,
 	chatHandler *handler.ChatHandler,
+	chatExportHandler *handler.ChatExportHandler,
 	notificationHandler *handler.NotificationHandler,
+	coupleHandler *handler.CoupleHandler,
+	tokenHandler *handler.PersonalAccessTokenHandler,
+	adminHandler *handler.AdminHandler,
 	authMiddleware func(http.Handler) http.Handler,
 	adminMiddleware func(http.Handler) http.Handler,
 ) *mux.Router {
 	r := mux.NewRouter()
 
+	// withScope requires a login session or a personal access token granted scope
+	withScope := func(scope string, h http.HandlerFunc) http.Handler {
+		return authMiddleware(middleware.RequireScope(scope)(h))
+	}
+	// sessionOnly requires a login session, personal access tokens are rejected
+	sessionOnly := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware(middleware.RequireSession(h))
+	}
+
 	// Apply CORS middleware
 	r.Use(middleware.CORSMiddleware)
 
@@ -29,36 +44,95 @@ func SetupRoutes(
 		w.Write([]byte(`{"status":"OK","message":"Server is running"}`))
 	}).Methods("GET")
 
+	// Public verification keys for access tokens
+	r.HandleFunc("/.well-known/jwks.json", authHandler.JWKS).Methods("GET")
+
 	// Auth routes
+	r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST")
 	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
 	r.HandleFunc("/api/auth/refresh", authHandler.RefreshToken).Methods("POST")
-	r.Handle("/api/auth/profile", authMiddleware(http.HandlerFunc(authHandler.GetProfile))).Methods("GET")
+	r.HandleFunc("/api/auth/logout", authHandler.Logout).Methods("POST")
+	r.Handle("/api/auth/logout-all", sessionOnly(authHandler.LogoutAll)).Methods("POST")
+	r.Handle("/api/auth/profile", withScope(service.ScopeProfileRead, authHandler.GetProfile)).Methods("GET")
+	r.Handle("/api/auth/password", sessionOnly(authHandler.ChangePassword)).Methods("POST")
+	r.HandleFunc("/api/auth/password/forgot", authHandler.ForgotPassword).Methods("POST")
+	r.HandleFunc("/api/auth/password/reset", authHandler.ResetPassword).Methods("POST")
+	r.Handle("/api/auth/sessions", sessionOnly(authHandler.ListSessions)).Methods("GET")
+	r.Handle("/api/auth/sessions/{id}", sessionOnly(authHandler.RevokeSession)).Methods("DELETE")
+
+	// Personal access token routes, tokens cannot be used to manage tokens
+	r.Handle("/api/auth/tokens", sessionOnly(tokenHandler.List)).Methods("GET")
+	r.Handle("/api/auth/tokens", sessionOnly(tokenHandler.Create)).Methods("POST")
+	r.Handle("/api/auth/tokens/{id}", sessionOnly(tokenHandler.Revoke)).Methods("DELETE")
+
+	// Two-factor authentication routes
+	r.HandleFunc("/api/auth/2fa/verify", authHandler.VerifyTwoFactor).Methods("POST")
+	r.Handle("/api/auth/2fa/setup", sessionOnly(authHandler.SetupTwoFactor)).Methods("POST")
+	r.Handle("/api/auth/2fa/enable", sessionOnly(authHandler.EnableTwoFactor)).Methods("POST")
+	r.Handle("/api/auth/2fa/disable", sessionOnly(authHandler.DisableTwoFactor)).Methods("POST")
+
+	// Couple routes
+	r.Handle("/api/couple", withScope(service.ScopeProfileRead, coupleHandler.Get)).Methods("GET")
+	r.Handle("/api/couple/invite", sessionOnly(coupleHandler.CreateInvite)).Methods("POST")
+	r.Handle("/api/couple/join", sessionOnly(coupleHandler.Join)).Methods("POST")
 
 	// Gallery routes
-	r.Handle("/api/gallery", authMiddleware(http.HandlerFunc(galleryHandler.GetAll))).Methods("GET")
-	r.Handle("/api/gallery/upload", authMiddleware(http.HandlerFunc(galleryHandler.Create))).Methods("POST")
-	r.Handle("/api/gallery/{id}", authMiddleware(http.HandlerFunc(galleryHandler.Delete))).Methods("DELETE")
+	r.Handle("/api/gallery", withScope(service.ScopeGalleryRead, galleryHandler.GetAll)).Methods("GET")
+	r.Handle("/api/gallery/upload", withScope(service.ScopeGalleryWrite, galleryHandler.Create)).Methods("POST")
+	r.Handle("/api/gallery/{id}", withScope(service.ScopeGalleryWrite, galleryHandler.Delete)).Methods("DELETE")
 
 	// Request routes
-	r.Handle("/api/requests", authMiddleware(http.HandlerFunc(requestHandler.GetAll))).Methods("GET")
-	r.Handle("/api/requests", authMiddleware(http.HandlerFunc(requestHandler.Create))).Methods("POST")
-	r.Handle("/api/requests/{id}/status", authMiddleware(http.HandlerFunc(requestHandler.UpdateStatus))).Methods("PATCH")
-	r.Handle("/api/requests/{id}", authMiddleware(http.HandlerFunc(requestHandler.Delete))).Methods("DELETE")
+	r.Handle("/api/requests", withScope(service.ScopeRequestsRead, requestHandler.GetAll)).Methods("GET")
+	r.Handle("/api/requests", withScope(service.ScopeRequestsWrite, requestHandler.Create)).Methods("POST")
+	r.Handle("/api/requests/{id}/status", withScope(service.ScopeRequestsWrite, requestHandler.UpdateStatus)).Methods("PATCH")
+	r.Handle("/api/requests/{id}", withScope(service.ScopeRequestsWrite, requestHandler.Delete)).Methods("DELETE")
 
 	// Chat routes
-	r.Handle("/api/chat/messages", authMiddleware(http.HandlerFunc(chatHandler.GetHistory))).Methods("GET")
-	r.Handle("/api/chat/messages", authMiddleware(http.HandlerFunc(chatHandler.SendMessage))).Methods("POST")
-	r.Handle("/api/chat/messages/read", authMiddleware(http.HandlerFunc(chatHandler.MarkAsRead))).Methods("POST")
-	r.Handle("/api/chat/unread", authMiddleware(http.HandlerFunc(chatHandler.GetUnreadCount))).Methods("GET")
+	r.Handle("/api/chat/messages", withScope(service.ScopeChatRead, chatHandler.GetHistory)).Methods("GET")
+	r.Handle("/api/chat/messages", withScope(service.ScopeChatWrite, chatHandler.SendMessage)).Methods("POST")
+	r.Handle("/api/chat/search", withScope(service.ScopeChatRead, chatHandler.SearchMessages)).Methods("GET")
+	r.Handle("/api/chat/presence", withScope(service.ScopeChatRead, chatHandler.GetPresence)).Methods("GET")
+	r.Handle("/api/chat/typing", withScope(service.ScopeChatWrite, chatHandler.SetTyping)).Methods("POST")
+	r.Handle("/api/chat/messages/read", withScope(service.ScopeChatWrite, chatHandler.MarkAsRead)).Methods("POST")
+	r.Handle("/api/chat/messages/ack", withScope(service.ScopeChatWrite, chatHandler.AckMessages)).Methods("POST")
+	r.Handle("/api/chat/messages/{id}", withScope(service.ScopeChatWrite, chatHandler.EditMessage)).Methods("PATCH")
+	r.Handle("/api/chat/messages/{id}", withScope(service.ScopeChatWrite, chatHandler.DeleteMessage)).Methods("DELETE")
+	r.Handle("/api/chat/messages/{id}/revisions", withScope(service.ScopeChatRead, chatHandler.GetRevisions)).Methods("GET")
+	r.Handle("/api/chat/messages/{id}/gallery", withScope(service.ScopeGalleryWrite, chatHandler.PromoteToGallery)).Methods("POST")
+	r.Handle("/api/chat/messages/{id}/reactions", withScope(service.ScopeChatWrite, chatHandler.AddReaction)).Methods("POST")
+	r.Handle("/api/chat/messages/{id}/reactions/{emoji}", withScope(service.ScopeChatWrite, chatHandler.RemoveReaction)).Methods("DELETE")
+	r.Handle("/api/chat/scheduled", withScope(service.ScopeChatRead, chatHandler.GetScheduled)).Methods("GET")
+	r.Handle("/api/chat/scheduled/{id}", withScope(service.ScopeChatWrite, chatHandler.UpdateScheduled)).Methods("PATCH")
+	r.Handle("/api/chat/scheduled/{id}", withScope(service.ScopeChatWrite, chatHandler.CancelScheduled)).Methods("DELETE")
+	r.Handle("/api/chat/capsules", withScope(service.ScopeChatRead, chatHandler.GetCapsules)).Methods("GET")
+	r.Handle("/api/chat/capsules", withScope(service.ScopeChatWrite, chatHandler.CreateTimeCapsule)).Methods("POST")
+	r.Handle("/api/chat/settings", withScope(service.ScopeChatRead, chatHandler.GetSettings)).Methods("GET")
+	r.Handle("/api/chat/settings", withScope(service.ScopeChatWrite, chatHandler.UpdateSettings)).Methods("PUT")
+	r.Handle("/api/chat/export", withScope(service.ScopeChatRead, chatExportHandler.ExportChat)).Methods("GET")
+	r.Handle("/api/chat/attachments/{name}", withScope(service.ScopeChatRead, chatHandler.GetAttachment)).Methods("GET")
+	r.Handle("/api/chat/ws", withScope(service.ScopeChatRead, chatHandler.ServeWS)).Methods("GET")
+	r.Handle("/api/chat/unread", withScope(service.ScopeChatRead, chatHandler.GetUnreadCount)).Methods("GET")
 
 	// Notification routes
-	r.Handle("/api/notifications", authMiddleware(http.HandlerFunc(notificationHandler.GetAll))).Methods("GET")
-	r.Handle("/api/notifications/unread", authMiddleware(http.HandlerFunc(notificationHandler.GetUnreadCount))).Methods("GET")
-	r.Handle("/api/notifications/read", authMiddleware(http.HandlerFunc(notificationHandler.MarkAsRead))).Methods("POST")
+	r.Handle("/api/notifications", withScope(service.ScopeNotificationsRead, notificationHandler.GetAll)).Methods("GET")
+	r.Handle("/api/notifications/unread", withScope(service.ScopeNotificationsRead, notificationHandler.GetUnreadCount)).Methods("GET")
+	r.Handle("/api/notifications/stream", withScope(service.ScopeNotificationsRead, notificationHandler.Stream)).Methods("GET")
+	r.Handle("/api/notifications/read", withScope(service.ScopeNotificationsWrite, notificationHandler.MarkAsRead)).Methods("POST")
+
+	// Admin routes, only for login sessions of users with admin access
+	admin := r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(authMiddleware, middleware.RequireSession, adminMiddleware)
+	manageUsers := middleware.RequirePermission(entity.PermissionUsersManage)
+	admin.HandleFunc("/users", adminHandler.ListUsers).Methods("GET")
+	admin.HandleFunc("/users/{id}/stats", adminHandler.GetUserStats).Methods("GET")
+	admin.Handle("/users/{id}/role", manageUsers(http.HandlerFunc(adminHandler.UpdateRole))).Methods("PATCH")
+	admin.Handle("/users/{id}/disable", manageUsers(http.HandlerFunc(adminHandler.DisableUser))).Methods("POST")
+	admin.Handle("/users/{id}/enable", manageUsers(http.HandlerFunc(adminHandler.EnableUser))).Methods("POST")
+	admin.Handle("/users/{id}/password-reset", manageUsers(http.HandlerFunc(adminHandler.ForcePasswordReset))).Methods("POST")
 
 	// Static files for uploads (gallery photos/videos)
-	// Serve files from ./uploads directory at /uploads URL path
-	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads"))))
+	// Serve files from ./uploads directory at /uploads URL path, chat attachments are served above
+	r.PathPrefix("/uploads/").Handler(handler.PublicUploads())
 
 	return r
 }
@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// logMailer writes emails to the application log instead of sending them
+type logMailer struct{}
+
+// NewLogMailer creates a mailer for development setups without an SMTP server
+func NewLogMailer() Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
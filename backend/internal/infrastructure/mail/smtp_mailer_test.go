@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal local SMTP stand-in that records received messages
+type fakeSMTPServer struct {
+	ln       net.Listener
+	messages chan receivedMail
+}
+
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{ln: ln, messages: make(chan receivedMail, 1)}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) hostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return host, port
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+
+	var mail receivedMail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			mail.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			mail.to = append(mail.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			tp.PrintfLine("250 OK")
+			s.messages <- mail
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port := server.hostPort()
+	mailer := NewSMTPMailer(host, port, "", "", "no-reply@fasisi.com")
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "sisti@fasisi.com",
+		Subject: "Reset password",
+		Body:    "Halo Sisti,\nklik link berikut.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-server.messages
+	if got.from != "no-reply@fasisi.com" || len(got.to) != 1 || got.to[0] != "sisti@fasisi.com" {
+		t.Errorf("unexpected envelope %+v", got)
+	}
+	for _, want := range []string{"Subject: Reset password", "To: sisti@fasisi.com", "Halo Sisti,\nklik link berikut."} {
+		if !strings.Contains(got.data, want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, got.data)
+		}
+	}
+}
+
+func TestSMTPMailer_SendHonorsDeadline(t *testing.T) {
+	// A server that accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	mailer := NewSMTPMailer(host, port, "", "", "no-reply@fasisi.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = mailer.Send(ctx, Message{To: "sisti@fasisi.com", Subject: "x", Body: "x"})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Send to give up at the deadline, took %s", elapsed)
+	}
+}
+
+func TestSMTPMailer_RejectsHeaderInjection(t *testing.T) {
+	mailer := NewSMTPMailer("127.0.0.1", "1", "", "", "no-reply@fasisi.com")
+
+	err := mailer.Send(context.Background(), Message{To: "a@fasisi.com\r\nBcc: b@fasisi.com", Subject: "x", Body: "x"})
+	if err == nil {
+		t.Error("expected recipient with line breaks to be rejected")
+	}
+}
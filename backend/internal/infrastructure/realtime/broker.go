@@ -0,0 +1,95 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Signal kinds shared between instances
+const (
+	SignalActivity = "activity"
+	SignalTyping   = "typing"
+)
+
+// Signal is a presence change sent to the other API instances
+type Signal struct {
+	Instance  string    `json:"instance"`
+	Kind      string    `json:"kind"`
+	UserID    int64     `json:"user_id"`
+	PartnerID int64     `json:"partner_id,omitempty"`
+	Typing    bool      `json:"typing,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Broker distributes signals between API instances. Every instance receives
+// every signal, including its own.
+type Broker interface {
+	Publish(ctx context.Context, signal Signal) error
+	// Listen calls handle for each received signal until ctx is done
+	Listen(ctx context.Context, handle func(Signal)) error
+}
+
+// presenceChannel is the PostgreSQL notification channel used by postgresBroker
+const presenceChannel = "fasisi_presence"
+
+// postgresBroker distributes signals with PostgreSQL LISTEN/NOTIFY
+type postgresBroker struct {
+	db  *sql.DB
+	dsn string // a dedicated connection is opened for LISTEN
+}
+
+// NewPostgresBroker creates a broker on the database the instances share
+func NewPostgresBroker(db *sql.DB, dsn string) Broker {
+	return &postgresBroker{db: db, dsn: dsn}
+}
+
+func (b *postgresBroker) Publish(ctx context.Context, signal Signal) error {
+	payload, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, presenceChannel, string(payload))
+	return err
+}
+
+func (b *postgresBroker) Listen(ctx context.Context, handle func(Signal)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("Presence listener:", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(presenceChannel); err != nil {
+		return err
+	}
+
+	// Signals sent while the connection was lost are gone, presence catches up
+	// with the next activity and typing indicators expire by themselves
+	keepAlive := time.NewTicker(90 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-keepAlive.C:
+			go listener.Ping()
+		case n := <-listener.Notify:
+			if n == nil {
+				// The listener reconnected
+				continue
+			}
+			var signal Signal
+			if err := json.Unmarshal([]byte(n.Extra), &signal); err != nil {
+				log.Println("Invalid presence signal:", err)
+				continue
+			}
+			handle(signal)
+		}
+	}
+}
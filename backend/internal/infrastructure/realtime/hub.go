@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"sync"
+)
+
+// subscriptionBuffer is the number of events queued per subscriber before it
+// is considered too slow and dropped
+const subscriptionBuffer = 64
+
+// Event types pushed over the real-time channels
+const (
+	EventMessage        = "message"
+	EventMessageEdited  = "message_edited"
+	EventMessageDeleted = "message_deleted"
+	EventMessageExpired = "message_expired"
+	EventReaction       = "reaction"
+	EventDelivered      = "delivered"
+	EventRead           = "read"
+	EventPing           = "ping"
+	EventPong           = "pong"
+)
+
+// Event is a message pushed to a user's live connections
+type Event struct {
+	ID   int64       `json:"id,omitempty"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Subscription receives the events published to a single user
+type Subscription struct {
+	UserID int64
+	C      <-chan Event
+
+	ch   chan Event
+	hub  *Hub
+	once sync.Once
+}
+
+// Close removes the subscription from its hub
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub fans out events to every open subscription of a user
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[int64]map[*Subscription]struct{}
+}
+
+// NewHub creates a new hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int64]map[*Subscription]struct{})}
+}
+
+// Subscribe registers a new subscription for the user
+func (h *Hub) Subscribe(userID int64) *Subscription {
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{UserID: userID, C: ch, ch: ch, hub: h}
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub
+}
+
+// Publish sends an event to every subscription of the user.
+// Subscribers whose buffer is full are closed so that a slow client
+// never blocks the publisher; they are expected to reconnect and resume.
+func (h *Hub) Publish(userID int64, event Event) {
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for sub := range h.subs[userID] {
+		select {
+		case sub.ch <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.remove(sub)
+	}
+}
+
+// Connections returns the number of open subscriptions of the user
+func (h *Hub) Connections(userID int64) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[userID])
+}
+
+// Disconnect closes every subscription of the user, e.g. once the account is disabled.
+// Their connections end like those of a slow client and have to authenticate again.
+func (h *Hub) Disconnect(userID int64) {
+	h.mu.RLock()
+	subs := make([]*Subscription, 0, len(h.subs[userID]))
+	for sub := range h.subs[userID] {
+		subs = append(subs, sub)
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range subs {
+		h.remove(sub)
+	}
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		h.mu.Lock()
+		delete(h.subs[sub.UserID], sub)
+		if len(h.subs[sub.UserID]) == 0 {
+			delete(h.subs, sub.UserID)
+		}
+		h.mu.Unlock()
+		close(sub.ch)
+	})
+}
@@ -0,0 +1,102 @@
+package realtime
+
+import (
+	"testing"
+)
+
+func TestHub_PublishToUserSubscriptions(t *testing.T) {
+	hub := NewHub()
+
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+
+	hub.Publish(1, Event{ID: 10, Type: EventMessage})
+
+	for _, sub := range []*Subscription{first, second} {
+		select {
+		case event := <-sub.C:
+			if event.ID != 10 || event.Type != EventMessage {
+				t.Errorf("unexpected event %+v", event)
+			}
+		default:
+			t.Error("expected event for user 1 subscription")
+		}
+	}
+
+	select {
+	case event := <-other.C:
+		t.Errorf("user 2 should not receive %+v", event)
+	default:
+	}
+}
+
+func TestHub_CloseRemovesSubscription(t *testing.T) {
+	hub := NewHub()
+
+	sub := hub.Subscribe(1)
+	if got := hub.Connections(1); got != 1 {
+		t.Fatalf("expected 1 connection, got %d", got)
+	}
+
+	sub.Close()
+	sub.Close() // closing twice must be safe
+
+	if got := hub.Connections(1); got != 0 {
+		t.Fatalf("expected 0 connections, got %d", got)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Error("expected channel to be closed")
+	}
+
+	// Publishing without subscribers must not block or panic
+	hub.Publish(1, Event{Type: EventRead})
+}
+
+func TestHub_Disconnect(t *testing.T) {
+	hub := NewHub()
+
+	first := hub.Subscribe(1)
+	second := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	defer other.Close()
+
+	hub.Disconnect(1)
+
+	if got := hub.Connections(1); got != 0 {
+		t.Fatalf("expected 0 connections, got %d", got)
+	}
+	for _, sub := range []*Subscription{first, second} {
+		if _, ok := <-sub.C; ok {
+			t.Error("expected channel to be closed")
+		}
+	}
+	if got := hub.Connections(2); got != 1 {
+		t.Errorf("expected user 2 to stay connected, got %d connections", got)
+	}
+	first.Close() // closing after a disconnect must be safe
+}
+
+func TestHub_SlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(1, Event{ID: int64(i), Type: EventMessage})
+	}
+
+	if got := hub.Connections(1); got != 0 {
+		t.Fatalf("expected slow subscriber to be dropped, got %d connections", got)
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriptionBuffer, received)
+	}
+}
@@ -0,0 +1,58 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// Notification event types
+const (
+	EventNotification = "notification"
+	EventUnreadCount  = "unread_count"
+)
+
+// notificationPublisher decorates a NotificationRepository and pushes every
+// stored notification and unread count change to the owner's live streams
+type notificationPublisher struct {
+	repository.NotificationRepository
+	hub *Hub
+}
+
+// NewNotificationPublisher wraps repo so that writes are published to hub
+func NewNotificationPublisher(repo repository.NotificationRepository, hub *Hub) repository.NotificationRepository {
+	return &notificationPublisher{NotificationRepository: repo, hub: hub}
+}
+
+func (p *notificationPublisher) Create(ctx context.Context, notification *entity.Notification) error {
+	if err := p.NotificationRepository.Create(ctx, notification); err != nil {
+		return err
+	}
+
+	p.hub.Publish(notification.UserID, Event{ID: notification.ID, Type: EventNotification, Data: notification})
+	p.publishUnreadCount(ctx, notification.UserID)
+	return nil
+}
+
+func (p *notificationPublisher) MarkAllAsRead(ctx context.Context, userID int64) error {
+	if err := p.NotificationRepository.MarkAllAsRead(ctx, userID); err != nil {
+		return err
+	}
+
+	p.publishUnreadCount(ctx, userID)
+	return nil
+}
+
+func (p *notificationPublisher) publishUnreadCount(ctx context.Context, userID int64) {
+	// Skip the count query when nobody is listening
+	if p.hub.Connections(userID) == 0 {
+		return
+	}
+
+	count, err := p.NotificationRepository.CountUnread(ctx, userID)
+	if err != nil {
+		return
+	}
+	p.hub.Publish(userID, Event{Type: EventUnreadCount, Data: map[string]int64{"count": count}})
+}
@@ -0,0 +1,75 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/entity"
+	"github.com/irfan-ghzl/fasisi-backend/internal/domain/repository"
+)
+
+// fakeNotificationRepo is an in-memory NotificationRepository
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+	items []*entity.Notification
+}
+
+func (f *fakeNotificationRepo) Create(ctx context.Context, n *entity.Notification) error {
+	n.ID = int64(len(f.items) + 1)
+	f.items = append(f.items, n)
+	return nil
+}
+
+func (f *fakeNotificationRepo) MarkAllAsRead(ctx context.Context, userID int64) error {
+	for _, n := range f.items {
+		if n.UserID == userID {
+			n.ReadStatus = true
+		}
+	}
+	return nil
+}
+
+func (f *fakeNotificationRepo) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	for _, n := range f.items {
+		if n.UserID == userID && !n.ReadStatus {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func TestNotificationPublisher_CreatePublishesNotificationAndCount(t *testing.T) {
+	hub := NewHub()
+	repo := NewNotificationPublisher(&fakeNotificationRepo{}, hub)
+
+	sub := hub.Subscribe(2)
+	defer sub.Close()
+
+	notif := &entity.Notification{UserID: 2, Type: entity.NotificationTypeNewMessage, Message: "Pesan baru"}
+	if err := repo.Create(context.Background(), notif); err != nil {
+		t.Fatal(err)
+	}
+
+	event := <-sub.C
+	if event.Type != EventNotification || event.ID != notif.ID {
+		t.Errorf("expected notification event with id %d, got %+v", notif.ID, event)
+	}
+
+	event = <-sub.C
+	if event.Type != EventUnreadCount {
+		t.Fatalf("expected unread_count event, got %+v", event)
+	}
+	if count := event.Data.(map[string]int64)["count"]; count != 1 {
+		t.Errorf("expected unread count 1, got %d", count)
+	}
+
+	if err := repo.MarkAllAsRead(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	event = <-sub.C
+	if count := event.Data.(map[string]int64)["count"]; event.Type != EventUnreadCount || count != 0 {
+		t.Errorf("expected unread count 0 after mark as read, got %+v", event)
+	}
+}
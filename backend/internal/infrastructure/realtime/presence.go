@@ -0,0 +1,289 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Presence event types
+const (
+	EventPresence      = "presence"
+	EventTypingStarted = "typing_started"
+	EventTypingStopped = "typing_stopped"
+)
+
+const (
+	// A user is online while their last authenticated activity is this recent
+	onlineTTL = time.Minute
+	// and away after that, until awayTTL has passed without activity
+	awayTTL = 5 * time.Minute
+	// typingTTL ends a typing indicator that was not renewed or stopped
+	typingTTL = 6 * time.Second
+
+	// activityBroadcastInterval limits how often the activity of an online user is sent to other instances
+	activityBroadcastInterval = onlineTTL / 4
+	// presenceSweepInterval is how often expired typing indicators and presence changes are checked
+	presenceSweepInterval = time.Second
+)
+
+// PresenceStatus is whether a user is using the app
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away"
+	PresenceOffline PresenceStatus = "offline"
+)
+
+// Presence is the status of a user, LastSeen is nil when the user was not seen since the server started
+type Presence struct {
+	UserID   int64          `json:"user_id"`
+	Status   PresenceStatus `json:"status"`
+	LastSeen *time.Time     `json:"last_seen"`
+}
+
+// TypingEvent is the data of typing_started and typing_stopped events
+type TypingEvent struct {
+	UserID int64 `json:"user_id"`
+}
+
+// PartnerFunc returns the partner of a user, presence changes are pushed to the partner
+type PartnerFunc func(ctx context.Context, userID int64) (int64, error)
+
+// typingState is a typing indicator of a user towards their partner
+type typingState struct {
+	partnerID int64
+	expiresAt time.Time
+}
+
+// PresenceTracker keeps presence and typing indicators in memory and pushes changes to the
+// partner's live connections. With a broker, signals are shared with the other API instances
+// so that every instance knows the state of every user.
+type PresenceTracker struct {
+	hub       *Hub
+	partnerOf PartnerFunc
+	broker    Broker
+	instance  string
+	now       func() time.Time
+
+	mu            sync.Mutex
+	lastSeen      map[int64]time.Time
+	announced     map[int64]PresenceStatus // status last pushed to the partner
+	lastBroadcast map[int64]time.Time
+	typing        map[int64]typingState
+}
+
+// NewPresenceTracker creates a tracker that pushes events through hub
+func NewPresenceTracker(hub *Hub, partnerOf PartnerFunc) *PresenceTracker {
+	return &PresenceTracker{
+		hub:           hub,
+		partnerOf:     partnerOf,
+		instance:      newInstanceID(),
+		now:           time.Now,
+		lastSeen:      make(map[int64]time.Time),
+		announced:     make(map[int64]PresenceStatus),
+		lastBroadcast: make(map[int64]time.Time),
+		typing:        make(map[int64]typingState),
+	}
+}
+
+// WithBroker shares presence signals with other instances through broker
+func (t *PresenceTracker) WithBroker(broker Broker) *PresenceTracker {
+	t.broker = broker
+	return t
+}
+
+// Run expires typing indicators and presence until ctx is done, and receives
+// the signals of other instances when a broker is set
+func (t *PresenceTracker) Run(ctx context.Context) {
+	if t.broker != nil {
+		go func() {
+			if err := t.broker.Listen(ctx, t.receive); err != nil && ctx.Err() == nil {
+				log.Println("Presence broker stopped:", err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(presenceSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sweep(ctx)
+		}
+	}
+}
+
+// Touch records authenticated activity of a user
+func (t *PresenceTracker) Touch(userID int64) {
+	now := t.now()
+
+	t.mu.Lock()
+	t.lastSeen[userID] = now
+	broadcast := now.Sub(t.lastBroadcast[userID]) >= activityBroadcastInterval
+	if broadcast {
+		t.lastBroadcast[userID] = now
+	}
+	t.mu.Unlock()
+
+	t.announce(context.Background(), userID)
+	if broadcast {
+		t.publish(Signal{Kind: SignalActivity, UserID: userID, At: now})
+	}
+}
+
+// SetTyping starts or stops the typing indicator of a user towards their partner.
+// A started indicator ends by itself after typingTTL unless it is started again.
+func (t *PresenceTracker) SetTyping(userID, partnerID int64, typing bool) {
+	now := t.now()
+	t.applyTyping(userID, partnerID, typing, now)
+	t.publish(Signal{Kind: SignalTyping, UserID: userID, PartnerID: partnerID, Typing: typing, At: now})
+}
+
+// Presence returns the current status of a user
+func (t *PresenceTracker) Presence(userID int64) Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.presenceLocked(userID, t.now())
+}
+
+// IsTyping reports whether the user is typing to their partner
+func (t *PresenceTracker) IsTyping(userID int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.typing[userID]
+	return ok && t.now().Before(state.expiresAt)
+}
+
+func (t *PresenceTracker) presenceLocked(userID int64, now time.Time) Presence {
+	presence := Presence{UserID: userID, Status: PresenceOffline}
+	if seen, ok := t.lastSeen[userID]; ok {
+		presence.LastSeen = &seen
+		presence.Status = presenceStatus(now.Sub(seen))
+	}
+	return presence
+}
+
+// presenceStatus derives the status from the time since the last activity
+func presenceStatus(idle time.Duration) PresenceStatus {
+	switch {
+	case idle < onlineTTL:
+		return PresenceOnline
+	case idle < awayTTL:
+		return PresenceAway
+	default:
+		return PresenceOffline
+	}
+}
+
+// receive applies a signal of another instance
+func (t *PresenceTracker) receive(signal Signal) {
+	if signal.Instance == t.instance {
+		return
+	}
+
+	switch signal.Kind {
+	case SignalActivity:
+		t.mu.Lock()
+		if signal.At.After(t.lastSeen[signal.UserID]) {
+			t.lastSeen[signal.UserID] = signal.At
+		}
+		t.mu.Unlock()
+		t.announce(context.Background(), signal.UserID)
+	case SignalTyping:
+		t.applyTyping(signal.UserID, signal.PartnerID, signal.Typing, signal.At)
+	}
+}
+
+func (t *PresenceTracker) applyTyping(userID, partnerID int64, typing bool, at time.Time) {
+	t.mu.Lock()
+	_, wasTyping := t.typing[userID]
+	if typing {
+		t.typing[userID] = typingState{partnerID: partnerID, expiresAt: at.Add(typingTTL)}
+	} else {
+		delete(t.typing, userID)
+	}
+	t.mu.Unlock()
+
+	// Renewing an indicator is not pushed again
+	switch {
+	case typing && !wasTyping:
+		t.hub.Publish(partnerID, Event{Type: EventTypingStarted, Data: TypingEvent{UserID: userID}})
+	case !typing && wasTyping:
+		t.hub.Publish(partnerID, Event{Type: EventTypingStopped, Data: TypingEvent{UserID: userID}})
+	}
+}
+
+// announce pushes the presence of a user to their partner when it changed since the last push
+func (t *PresenceTracker) announce(ctx context.Context, userID int64) {
+	t.mu.Lock()
+	presence := t.presenceLocked(userID, t.now())
+	changed := t.announced[userID] != presence.Status
+	if changed {
+		t.announced[userID] = presence.Status
+	}
+	t.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	partnerID, err := t.partnerOf(ctx, userID)
+	if err != nil {
+		// Users without a partner have nobody to tell
+		return
+	}
+	t.hub.Publish(partnerID, Event{Type: EventPresence, Data: presence})
+}
+
+// sweep ends expired typing indicators and pushes presence that changed through inactivity
+func (t *PresenceTracker) sweep(ctx context.Context) {
+	now := t.now()
+
+	type expired struct{ userID, partnerID int64 }
+	var stopped []expired
+	var users []int64
+
+	t.mu.Lock()
+	for userID, state := range t.typing {
+		if !now.Before(state.expiresAt) {
+			delete(t.typing, userID)
+			stopped = append(stopped, expired{userID, state.partnerID})
+		}
+	}
+	for userID, seen := range t.lastSeen {
+		if presenceStatus(now.Sub(seen)) != t.announced[userID] {
+			users = append(users, userID)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, s := range stopped {
+		t.hub.Publish(s.partnerID, Event{Type: EventTypingStopped, Data: TypingEvent{UserID: s.userID}})
+	}
+	for _, userID := range users {
+		t.announce(ctx, userID)
+	}
+}
+
+func (t *PresenceTracker) publish(signal Signal) {
+	if t.broker == nil {
+		return
+	}
+	signal.Instance = t.instance
+	if err := t.broker.Publish(context.Background(), signal); err != nil {
+		log.Println("Failed to publish presence signal:", err)
+	}
+}
+
+// newInstanceID identifies this process in broker signals
+func newInstanceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
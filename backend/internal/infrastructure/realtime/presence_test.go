@@ -0,0 +1,176 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// couple pairs users 1 and 2
+func couple(ctx context.Context, userID int64) (int64, error) {
+	switch userID {
+	case 1:
+		return 2, nil
+	case 2:
+		return 1, nil
+	}
+	return 0, errors.New("no partner")
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestTracker(hub *Hub) (*PresenceTracker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 11, 22, 10, 0, 0, 0, time.UTC)}
+	tracker := NewPresenceTracker(hub, couple)
+	tracker.now = clock.now
+	return tracker, clock
+}
+
+// nextEvent returns the next queued event of sub, or fails when there is none
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	default:
+		t.Fatal("expected an event")
+		return Event{}
+	}
+}
+
+func expectNoEvent(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestPresenceTracker_StatusFollowsActivity(t *testing.T) {
+	hub := NewHub()
+	partner := hub.Subscribe(2)
+	defer partner.Close()
+	tracker, clock := newTestTracker(hub)
+
+	if p := tracker.Presence(1); p.Status != PresenceOffline || p.LastSeen != nil {
+		t.Fatalf("expected unseen user to be offline, got %+v", p)
+	}
+
+	tracker.Touch(1)
+	event := nextEvent(t, partner)
+	if p, ok := event.Data.(Presence); event.Type != EventPresence || !ok || p.UserID != 1 || p.Status != PresenceOnline {
+		t.Fatalf("expected online presence event, got %+v", event)
+	}
+
+	// Further activity while online is not pushed again
+	clock.advance(10 * time.Second)
+	tracker.Touch(1)
+	expectNoEvent(t, partner)
+
+	clock.advance(onlineTTL)
+	tracker.sweep(context.Background())
+	if p := nextEvent(t, partner).Data.(Presence); p.Status != PresenceAway {
+		t.Fatalf("expected away, got %s", p.Status)
+	}
+
+	clock.advance(awayTTL)
+	tracker.sweep(context.Background())
+	p := nextEvent(t, partner).Data.(Presence)
+	if p.Status != PresenceOffline || p.LastSeen == nil || !p.LastSeen.Equal(clock.t.Add(-onlineTTL-awayTTL)) {
+		t.Fatalf("expected offline with last activity as last seen, got %+v", p)
+	}
+}
+
+func TestPresenceTracker_TypingExpires(t *testing.T) {
+	hub := NewHub()
+	partner := hub.Subscribe(2)
+	defer partner.Close()
+	tracker, clock := newTestTracker(hub)
+
+	tracker.SetTyping(1, 2, true)
+	if event := nextEvent(t, partner); event.Type != EventTypingStarted || event.Data.(TypingEvent).UserID != 1 {
+		t.Fatalf("expected typing_started, got %+v", event)
+	}
+	if !tracker.IsTyping(1) {
+		t.Fatal("expected user 1 to be typing")
+	}
+
+	// Renewing only extends the indicator
+	clock.advance(typingTTL - time.Second)
+	tracker.SetTyping(1, 2, true)
+	expectNoEvent(t, partner)
+	clock.advance(typingTTL - time.Second)
+	tracker.sweep(context.Background())
+	expectNoEvent(t, partner)
+
+	clock.advance(time.Second)
+	tracker.sweep(context.Background())
+	if event := nextEvent(t, partner); event.Type != EventTypingStopped {
+		t.Fatalf("expected typing_stopped, got %+v", event)
+	}
+	if tracker.IsTyping(1) {
+		t.Fatal("expected typing to have expired")
+	}
+
+	// Stopping twice pushes a single event
+	tracker.SetTyping(1, 2, true)
+	nextEvent(t, partner)
+	tracker.SetTyping(1, 2, false)
+	tracker.SetTyping(1, 2, false)
+	if event := nextEvent(t, partner); event.Type != EventTypingStopped {
+		t.Fatalf("expected typing_stopped, got %+v", event)
+	}
+	expectNoEvent(t, partner)
+}
+
+// fakeBroker delivers signals synchronously to every tracker
+type fakeBroker struct {
+	trackers []*PresenceTracker
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, signal Signal) error {
+	for _, tracker := range b.trackers {
+		tracker.receive(signal)
+	}
+	return nil
+}
+
+func (b *fakeBroker) Listen(ctx context.Context, handle func(Signal)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestPresenceTracker_SharesSignalsThroughBroker(t *testing.T) {
+	// Users 1 and 2 are connected to different instances
+	hubA, hubB := NewHub(), NewHub()
+	a, clock := newTestTracker(hubA)
+	b, _ := newTestTracker(hubB)
+	b.now = clock.now
+	broker := &fakeBroker{trackers: []*PresenceTracker{a, b}}
+	a.WithBroker(broker)
+	b.WithBroker(broker)
+
+	partner := hubB.Subscribe(2)
+	defer partner.Close()
+
+	a.Touch(1)
+	if event := nextEvent(t, partner); event.Type != EventPresence || event.Data.(Presence).Status != PresenceOnline {
+		t.Fatalf("expected presence on the other instance, got %+v", event)
+	}
+	if p := b.Presence(1); p.Status != PresenceOnline {
+		t.Fatalf("expected instance b to know user 1 is online, got %+v", p)
+	}
+
+	a.SetTyping(1, 2, true)
+	if event := nextEvent(t, partner); event.Type != EventTypingStarted {
+		t.Fatalf("expected typing_started on the other instance, got %+v", event)
+	}
+	if !b.IsTyping(1) {
+		t.Fatal("expected instance b to know user 1 is typing")
+	}
+}
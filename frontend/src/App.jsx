@@ -2,6 +2,7 @@ import React, { useState, useEffect } from 'react';
 import { BrowserRouter as Router, Routes, Route, Navigate } from 'react-router-dom';
 import Login from './components/Auth/Login';
 import Register from './components/Auth/Register';
+import ResetPassword from './components/Auth/ResetPassword';
 import Dashboard from './components/Dashboard/Dashboard';
 import Gallery from './components/Gallery/Gallery';
 import Requests from './components/Requests/Requests';
@@ -44,6 +45,8 @@ function App() {
         });
 
         localStorage.setItem('authToken', response.data.token);
+        // Refresh tokens are single-use, always keep the rotated one
+        localStorage.setItem('refreshToken', response.data.refresh_token);
       } catch (error) {
         console.error('Token refresh failed:', error);
         handleLogout(); // Logout if refresh fails
@@ -54,6 +57,12 @@ function App() {
   };
 
   const handleLogout = () => {
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (refreshToken) {
+      // Revoke the refresh token on the server, ignore failures
+      axios.post('/api/auth/logout', { refresh_token: refreshToken }).catch(() => {});
+    }
+
     localStorage.removeItem('authToken');
     localStorage.removeItem('refreshToken');
     localStorage.removeItem('currentUser');
@@ -73,6 +82,7 @@ function App() {
               <Login onLogin={handleLogin} />
             } 
           />
+          <Route path="/reset-password" element={<ResetPassword />} />
           <Route 
             path="/register" 
             element={
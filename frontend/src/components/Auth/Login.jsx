@@ -6,24 +6,50 @@ import './Auth.css';
 function Login({ onLogin }) {
   const [email, setEmail] = useState('');
   const [password, setPassword] = useState('');
+  const [code, setCode] = useState('');
+  const [challengeToken, setChallengeToken] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
 
+  const completeLogin = (data) => {
+    // Store both access token and refresh token
+    localStorage.setItem('refreshToken', data.refresh_token);
+    onLogin(data.token, data.user);
+  };
+
   const handleSubmit = async (e) => {
     e.preventDefault();
     setError('');
     setLoading(true);
 
     try {
+      if (challengeToken) {
+        const response = await axios.post('/api/auth/2fa/verify', {
+          challenge_token: challengeToken,
+          code,
+        });
+        completeLogin(response.data);
+        return;
+      }
+
       const response = await axios.post('/api/auth/login', {
         email,
         password,
       });
 
-      // Store both access token and refresh token
-      localStorage.setItem('refreshToken', response.data.refresh_token);
-      onLogin(response.data.token, response.data.user);
+      // Accounts with 2FA need a code from the authenticator app first
+      if (response.data.two_factor_required) {
+        setChallengeToken(response.data.challenge_token);
+        return;
+      }
+
+      completeLogin(response.data);
     } catch (err) {
+      if (challengeToken && /challenge/i.test(err.response?.data?.error || '')) {
+        // Challenge expired, start over with the password
+        setChallengeToken('');
+        setCode('');
+      }
       setError(err.response?.data?.error || 'Login gagal. Silakan coba lagi.');
     } finally {
       setLoading(false);
@@ -48,31 +74,49 @@ function Login({ onLogin }) {
 
           {error && <div className="error-message">{error}</div>}
 
-          <div className="form-group">
-            <label>Email</label>
-            <input
-              type="email"
-              value={email}
-              onChange={(e) => setEmail(e.target.value)}
-              required
-              disabled={loading}
-            />
-          </div>
-
-          <div className="form-group">
-            <label>Password</label>
-            <input
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              required
-              disabled={loading}
-            />
-          </div>
+          {challengeToken ? (
+            <div className="form-group">
+              <label>Kode Autentikasi (atau kode pemulihan)</label>
+              <input
+                type="text"
+                autoComplete="one-time-code"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+                disabled={loading}
+              />
+            </div>
+          ) : (
+            <>
+              <div className="form-group">
+                <label>Email</label>
+                <input
+                  type="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
+
+              <div className="form-group">
+                <label>Password</label>
+                <input
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  disabled={loading}
+                />
+              </div>
+            </>
+          )}
 
           <button type="submit" className="btn-primary" disabled={loading}>
-            {loading ? 'Loading...' : 'Login'}
+            {loading ? 'Loading...' : challengeToken ? 'Verifikasi' : 'Login'}
           </button>
+
+          <Link to="/reset-password" className="auth-link">Lupa password?</Link>
         </form>
       </div>
     </div>
@@ -0,0 +1,92 @@
+import React, { useState } from 'react';
+import { Link, useSearchParams } from 'react-router-dom';
+import axios from 'axios';
+import './Auth.css';
+
+// Without a token in the URL this asks for the email to send a reset link to,
+// with a token (from the emailed link) it sets the new password
+function ResetPassword() {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get('token');
+
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [message, setMessage] = useState('');
+  const [error, setError] = useState('');
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError('');
+    setMessage('');
+    setLoading(true);
+
+    try {
+      if (token) {
+        await axios.post('/api/auth/password/reset', {
+          token,
+          new_password: password,
+        });
+        setMessage('Password berhasil diubah. Silakan login dengan password baru.');
+      } else {
+        await axios.post('/api/auth/password/forgot', { email });
+        setMessage('Jika email terdaftar, link reset password sudah dikirim.');
+      }
+    } catch (err) {
+      setError(err.response?.data?.error || 'Gagal. Silakan coba lagi.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="auth-container">
+      <div className="auth-card">
+        <div className="auth-header">
+          <h1>❤️ Fasisi App</h1>
+          <p>Platform Khusus untuk Kami Berdua</p>
+        </div>
+
+        <form className="auth-form" onSubmit={handleSubmit}>
+          <h2>Reset Password</h2>
+
+          {error && <div className="error-message">{error}</div>}
+          {message && <div className="success-message">{message}</div>}
+
+          {token ? (
+            <div className="form-group">
+              <label>Password Baru</label>
+              <input
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                minLength={8}
+                required
+                disabled={loading}
+              />
+            </div>
+          ) : (
+            <div className="form-group">
+              <label>Email</label>
+              <input
+                type="email"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                required
+                disabled={loading}
+              />
+            </div>
+          )}
+
+          <button type="submit" className="btn-primary" disabled={loading}>
+            {loading ? 'Loading...' : token ? 'Simpan Password' : 'Kirim Link Reset'}
+          </button>
+
+          <Link to="/login" className="auth-link">Kembali ke Login</Link>
+        </form>
+      </div>
+    </div>
+  );
+}
+
+export default ResetPassword;
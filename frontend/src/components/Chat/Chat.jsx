@@ -6,9 +6,16 @@
  * 
  * Features:
  * - Menampilkan riwayat chat dengan format yang berbeda untuk pesan yang dikirim vs diterima
- * - Auto-refresh setiap 3 detik untuk mendapatkan pesan baru (polling)
+ * - Auto-refresh setiap 3 detik untuk mendapatkan pesan baru (polling dengan cursor after)
+ * - Riwayat dimuat per halaman, pesan lama dimuat dengan tombol "Muat pesan sebelumnya"
  * - Auto-scroll ke pesan terbaru ketika ada pesan baru
  * - Form input untuk mengirim pesan dengan validasi
+ * - Edit dan hapus pesan sendiri (pesan yang dihapus tampil sebagai tombstone)
+ * - Reaksi emoji pada pesan, klik reaksi untuk menambah atau menghapus reaksi sendiri
+ * - Balas pesan tertentu, pesan balasan menampilkan kutipan pesan yang dibalas
+ * - Kirim foto, video dan voice note sebagai lampiran, foto/video bisa disimpan ke galeri
+ * - Status pesan sendiri: ✓ terkirim, ✓✓ diterima, ✓✓ biru dibaca; pesan pasangan otomatis ditandai dibaca
+ * - Status online pasangan dan indikator "sedang mengetik..."
  * - Loading state dan error handling
  * 
  * Props:
@@ -19,42 +26,124 @@ import React, { useState, useEffect, useRef } from 'react';
 import { useNavigate, Link } from 'react-router-dom';
 import axios from 'axios';
 
+// Keterangan status pesan sendiri
+const statusLabels = {
+  sent: 'Terkirim',
+  delivered: 'Diterima',
+  read: 'Dibaca',
+};
+
+/**
+ * Format status online pasangan
+ * @param {Object} presence - { status, last_seen }
+ * @returns {string} Contoh: "Online", "Terakhir dilihat 10:30"
+ */
+const formatPresence = (presence) => {
+  if (presence.status === 'online') return 'Online';
+  if (!presence.last_seen) return 'Offline';
+  const lastSeen = new Date(presence.last_seen).toLocaleTimeString('id-ID', { hour: '2-digit', minute: '2-digit' });
+  return `${presence.status === 'away' ? 'Away' : 'Offline'} · terakhir dilihat ${lastSeen}`;
+};
+
+/**
+ * Lampiran chat hanya bisa diambil dengan token, jadi file di /api/ diunduh sebagai blob
+ * lalu ditampilkan lewat object URL. Path lama di /uploads/ dipakai langsung.
+ * @param {Object} attachment - { file_type, file_path }
+ */
+function AttachmentMedia({ attachment }) {
+  const isProtected = attachment.file_path.startsWith('/api/');
+  const [src, setSrc] = useState(isProtected ? null : attachment.file_path);
+
+  useEffect(() => {
+    if (!isProtected) return undefined;
+    let objectURL = null;
+    let cancelled = false;
+    const token = localStorage.getItem('authToken');
+    axios.get(attachment.file_path, {
+      headers: { Authorization: `Bearer ${token}` },
+      responseType: 'blob',
+    })
+      .then((response) => {
+        if (cancelled) return;
+        objectURL = URL.createObjectURL(response.data);
+        setSrc(objectURL);
+      })
+      .catch((err) => console.error('Error fetching attachment:', err));
+
+    return () => {
+      cancelled = true;
+      if (objectURL) URL.revokeObjectURL(objectURL);
+    };
+  }, [attachment.file_path, isProtected]);
+
+  if (!src) return <span className="message-attachment-loading">Memuat lampiran...</span>;
+  if (attachment.file_type === 'photo') return <img src={src} alt="Lampiran" />;
+  if (attachment.file_type === 'video') return <video src={src} controls />;
+  return <audio src={src} controls />;
+}
+
 function Chat({ user, onLogout }) {
   // State management
   const [messages, setMessages] = useState([]);        // Array semua pesan chat
   const [newMessage, setNewMessage] = useState('');    // Input pesan baru
   const [loading, setLoading] = useState(true);        // Loading state saat fetch data
   const [sending, setSending] = useState(false);       // Loading state saat kirim pesan
+  const [hasOlder, setHasOlder] = useState(false);     // Masih ada pesan lama di server
+  const [loadingOlder, setLoadingOlder] = useState(false); // Loading state saat muat pesan lama
+  const [replyTo, setReplyTo] = useState(null);        // Pesan yang sedang dibalas
+  const [attachment, setAttachment] = useState(null);  // File lampiran yang akan dikirim
+  const [deliverAt, setDeliverAt] = useState('');      // Waktu kirim terjadwal (datetime-local), kosong = kirim sekarang
+  const [partnerPresence, setPartnerPresence] = useState(null); // Status online pasangan
+  const [partnerTyping, setPartnerTyping] = useState(false);    // Pasangan sedang mengetik
+  const [messageTTL, setMessageTTL] = useState(0);              // Timer pesan sementara (detik), 0 = mati
+  const [now, setNow] = useState(Date.now());                   // Waktu sekarang, untuk menyembunyikan pesan yang sudah hilang
   
   // Ref untuk auto-scroll ke pesan terbaru
   const messagesEndRef = useRef(null);
+  // ID pesan terbaru yang sudah dimuat, dipakai sebagai cursor polling
+  const lastIdRef = useRef(null);
+  // Salinan state messages untuk polling, interval hanya melihat state saat mount
+  const messagesRef = useRef([]);
+  // Waktu terakhir indikator mengetik dikirim, null jika tidak sedang mengetik
+  const typingSentAtRef = useRef(null);
   const navigate = useNavigate();
 
   /**
    * Effect: Fetch messages dan setup polling
    * 
    * Dijalankan saat component pertama kali di-mount
-   * - Memanggil fetchMessages() untuk load pesan awal
-   * - Setup interval untuk auto-refresh setiap 3 detik
+   * - Memanggil fetchMessages() untuk load halaman pesan terbaru
+   * - Setup interval untuk mengambil pesan baru dan status pasangan setiap 3 detik
    * - Cleanup interval ketika component unmount
    */
   useEffect(() => {
     fetchMessages();
-    // Poll untuk pesan baru setiap 3 detik
-    const interval = setInterval(fetchMessages, 3000);
+    fetchPresence();
+    fetchSettings();
+    // Poll untuk pesan baru dan status pasangan setiap 3 detik
+    const interval = setInterval(() => {
+      fetchNewMessages();
+      fetchPresence();
+      setNow(Date.now());
+    }, 3000);
     return () => clearInterval(interval);
   }, []);
 
   /**
    * Effect: Auto-scroll ke pesan terbaru
    * 
-   * Dijalankan setiap kali array messages berubah
-   * Membuat halaman scroll otomatis ke bawah untuk menampilkan pesan terbaru
+   * Dijalankan setiap kali pesan terakhir berubah
+   * Memuat pesan lama tidak mengubah pesan terakhir, jadi posisi scroll tetap
    */
   useEffect(() => {
-    scrollToBottom();
+    messagesRef.current = messages;
   }, [messages]);
 
+  const lastMessageId = messages.length > 0 ? messages[messages.length - 1].id : null;
+  useEffect(() => {
+    scrollToBottom();
+  }, [lastMessageId]);
+
   /**
    * Scroll halaman ke pesan paling bawah (terbaru)
    * Menggunakan smooth scrolling untuk animasi yang halus
@@ -64,35 +153,240 @@ function Chat({ user, onLogout }) {
   };
 
   /**
-   * Fetch semua pesan chat dari backend
+   * Ambil satu halaman pesan chat dari backend
    * 
-   * Endpoint: GET /api/chat/messages
+   * Endpoint: GET /api/chat/messages?before=&after=&limit=
    * Headers: Authorization Bearer token
    * 
-   * Response: Array of messages
-   * [
-   *   {
-   *     id: 1,
-   *     sender_id: 1,
-   *     receiver_id: 2,
-   *     message: "Halo sayang",
-   *     created_at: "2025-11-22T10:30:00Z",
-   *     read_status: true
-   *   },
-   *   ...
-   * ]
+   * Response:
+   * {
+   *   messages: [
+   *     {
+   *       id: 1,
+   *       sender_id: 1,
+   *       receiver_id: 2,
+   *       message: "Halo sayang",
+   *       created_at: "2025-11-22T10:30:00Z",
+   *       read_status: true,
+   *       status: "read",            // sent, delivered atau read
+   *       delivered_at: "2025-11-22T10:30:01Z",
+   *       read_at: "2025-11-22T10:31:00Z"
+   *     },
+   *     ...
+   *   ],
+   *   next_cursor: null,   // ID untuk parameter after
+   *   prev_cursor: 1,      // ID untuk parameter before
+   *   has_more: true
+   * }
+   * 
+   * @param {Object} params - Query params (before, after, limit)
+   * @returns {Object} Envelope halaman pesan
+   */
+  const fetchPage = async (params) => {
+    const token = localStorage.getItem('authToken');
+    const response = await axios.get('/api/chat/messages', {
+      headers: { Authorization: `Bearer ${token}` },
+      params
+    });
+    return response.data || {};
+  };
+
+  /**
+   * Simpan ID pesan terbaru sebagai cursor polling
+   * @param {Array} list - Daftar pesan yang sedang ditampilkan
+   */
+  const rememberLastId = (list) => {
+    if (list.length > 0) {
+      lastIdRef.current = list[list.length - 1].id;
+    }
+  };
+
+  /**
+   * Load halaman pesan terbaru
    */
   const fetchMessages = async () => {
+    try {
+      const page = await fetchPage({ limit: 50 });
+      const list = page.messages || [];
+      rememberLastId(list);
+      setMessages(list);
+      setHasOlder(Boolean(page.has_more));
+      acknowledgeRead(list);
+    } catch (error) {
+      console.error('Error fetching messages:', error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  /**
+   * Cursor polling: sebelum pesan sendiri pertama yang belum dibaca di antara 50 pesan terakhir,
+   * agar status centang pesan tersebut ikut diperbarui. Tanpa pesan seperti itu, pesan terakhir.
+   */
+  const pollCursor = () => {
+    const recent = messagesRef.current.slice(-50);
+    const pending = recent.find((msg) => msg.sender_id === user?.id && msg.status !== 'read' && !msg.deleted_at);
+    return pending ? pending.id - 1 : lastIdRef.current;
+  };
+
+  /**
+   * Ambil pesan yang lebih baru dari pesan terakhir yang sudah dimuat
+   * Pesan yang sudah ditampilkan dan ikut terambil diganti dengan versi terbaru (status, reaksi)
+   * Jika belum ada pesan sama sekali, load halaman terbaru
+   */
+  const fetchNewMessages = async () => {
+    if (lastIdRef.current === null) {
+      fetchMessages();
+      return;
+    }
+
+    try {
+      const page = await fetchPage({ after: pollCursor(), limit: 100 });
+      const fetched = page.messages || [];
+      if (fetched.length === 0) return;
+
+      const newer = fetched.filter((msg) => msg.id > lastIdRef.current);
+      rememberLastId(newer);
+      const byId = new Map(fetched.map((msg) => [msg.id, msg]));
+      setMessages((prev) => [...prev.map((msg) => byId.get(msg.id) || msg), ...newer]);
+      acknowledgeRead(newer);
+      // Pesan sistem berarti pengaturan percakapan berubah
+      if (newer.some((msg) => msg.system)) fetchSettings();
+    } catch (error) {
+      console.error('Error fetching new messages:', error);
+    }
+  };
+
+  /**
+   * Tandai pesan dari pasangan sebagai sudah dibaca sampai pesan terakhir yang ditampilkan
+   * 
+   * Endpoint: POST /api/chat/messages/ack
+   * Body: { up_to_id, status: "read" }
+   * 
+   * @param {Array} list - Pesan yang baru ditampilkan
+   */
+  const acknowledgeRead = async (list) => {
+    const unread = list.filter((msg) => msg.receiver_id === user?.id && msg.status !== 'read');
+    if (unread.length === 0) return;
+
+    try {
+      const token = localStorage.getItem('authToken');
+      await axios.post('/api/chat/messages/ack',
+        { up_to_id: unread[unread.length - 1].id, status: 'read' },
+        { headers: { Authorization: `Bearer ${token}` } }
+      );
+    } catch (error) {
+      console.error('Error acknowledging messages:', error);
+    }
+  };
+
+  /**
+   * Ambil status online pasangan dan apakah pasangan sedang mengetik
+   * 
+   * Endpoint: GET /api/chat/presence
+   * Response: { presence: { user_id, status, last_seen }, typing }
+   */
+  const fetchPresence = async () => {
     try {
       const token = localStorage.getItem('authToken');
-      const response = await axios.get('/api/chat/messages', {
+      const response = await axios.get('/api/chat/presence', {
         headers: { Authorization: `Bearer ${token}` }
       });
-      setMessages(response.data || []);
+      setPartnerPresence(response.data?.presence || null);
+      setPartnerTyping(Boolean(response.data?.typing));
     } catch (error) {
-      console.error('Error fetching messages:', error);
+      console.error('Error fetching presence:', error);
+    }
+  };
+
+  /**
+   * Ambil pengaturan percakapan (timer pesan sementara)
+   * 
+   * Endpoint: GET /api/chat/settings
+   * Response: { message_ttl, message_ttl_options }
+   */
+  const fetchSettings = async () => {
+    try {
+      const token = localStorage.getItem('authToken');
+      const response = await axios.get('/api/chat/settings', {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+      setMessageTTL(response.data?.message_ttl || 0);
+    } catch (error) {
+      console.error('Error fetching chat settings:', error);
+    }
+  };
+
+  /**
+   * Nyalakan, ganti atau matikan pesan sementara untuk kedua pasangan
+   * 
+   * Endpoint: PUT /api/chat/settings
+   * Backend mengirim pesan sistem ke percakapan jika timer berubah
+   * 
+   * @param {number} ttl - Timer dalam detik, 0 untuk mematikan
+   */
+  const handleChangeTTL = async (ttl) => {
+    try {
+      const token = localStorage.getItem('authToken');
+      const response = await axios.put('/api/chat/settings', { message_ttl: ttl }, {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+      setMessageTTL(response.data?.data?.message_ttl || 0);
+      fetchNewMessages();
+    } catch (error) {
+      console.error('Error updating chat settings:', error);
+      alert('Gagal mengubah pesan sementara: ' + (error.response?.data?.error || error.message));
+    }
+  };
+
+  /**
+   * Kirim indikator mengetik ke pasangan
+   * 
+   * Endpoint: POST /api/chat/typing
+   * Indikator berakhir sendiri setelah 6 detik, jadi selama user masih mengetik
+   * indikator dikirim ulang paling sering setiap 3 detik
+   * 
+   * @param {boolean} typing - true saat mengetik, false saat berhenti
+   */
+  const sendTyping = async (typing) => {
+    const now = Date.now();
+    if (typing && typingSentAtRef.current && now - typingSentAtRef.current < 3000) return;
+    if (!typing && !typingSentAtRef.current) return;
+    typingSentAtRef.current = typing ? now : null;
+
+    try {
+      const token = localStorage.getItem('authToken');
+      await axios.post('/api/chat/typing', { typing }, {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+    } catch (error) {
+      console.error('Error sending typing indicator:', error);
+    }
+  };
+
+  /**
+   * Handle perubahan input pesan, sekaligus menyalakan atau mematikan indikator mengetik
+   */
+  const handleMessageChange = (e) => {
+    setNewMessage(e.target.value);
+    sendTyping(e.target.value.trim() !== '');
+  };
+
+  /**
+   * Muat halaman pesan yang lebih lama dari pesan pertama yang ditampilkan
+   */
+  const loadOlderMessages = async () => {
+    if (messages.length === 0) return;
+
+    setLoadingOlder(true);
+    try {
+      const page = await fetchPage({ before: messages[0].id, limit: 50 });
+      setMessages((prev) => [...(page.messages || []), ...prev]);
+      setHasOlder(Boolean(page.has_more));
+    } catch (error) {
+      console.error('Error fetching older messages:', error);
     } finally {
-      setLoading(false);
+      setLoadingOlder(false);
     }
   };
 
@@ -100,9 +394,9 @@ function Chat({ user, onLogout }) {
    * Handle submit form untuk mengirim pesan baru
    * 
    * Flow:
-   * 1. Validasi pesan tidak boleh kosong
+   * 1. Validasi pesan tidak boleh kosong, kecuali ada lampiran
    * 2. Set state sending = true untuk disable button
-   * 3. Kirim POST request ke backend
+   * 3. Kirim POST request ke backend, JSON untuk teks atau multipart jika ada lampiran
    * 4. Clear input field jika berhasil
    * 5. Refresh pesan untuk menampilkan pesan yang baru dikirim
    * 
@@ -111,27 +405,56 @@ function Chat({ user, onLogout }) {
   const handleSendMessage = async (e) => {
     e.preventDefault();
     
-    // Validasi: pesan tidak boleh kosong atau hanya spasi
-    if (!newMessage.trim()) return;
+    // Validasi: pesan tidak boleh kosong atau hanya spasi, kecuali ada lampiran
+    if (!newMessage.trim() && !attachment) return;
 
     setSending(true);
     try {
       const token = localStorage.getItem('authToken');
       
-      // POST request ke backend
-      await axios.post('/api/chat/messages', 
-        { message: newMessage },
-        {
-          headers: { 
-            Authorization: `Bearer ${token}`,
-            'Content-Type': 'application/json'
+      if (attachment) {
+        // Lampiran dikirim sebagai multipart/form-data, message menjadi caption
+        const formData = new FormData();
+        formData.append('file', attachment);
+        formData.append('message', newMessage);
+        if (replyTo) formData.append('reply_to_id', replyTo.id);
+
+        await axios.post('/api/chat/messages', formData, {
+          headers: { Authorization: `Bearer ${token}` }
+        });
+      } else if (deliverAt && !replyTo) {
+        // Pesan terjadwal dikirim oleh server pada waktu yang dipilih
+        await axios.post('/api/chat/messages',
+          { message: newMessage, deliver_at: new Date(deliverAt).toISOString() },
+          {
+            headers: {
+              Authorization: `Bearer ${token}`,
+              'Content-Type': 'application/json'
+            }
           }
-        }
-      );
+        );
+        alert('Pesan dijadwalkan untuk ' + new Date(deliverAt).toLocaleString('id-ID'));
+      } else {
+        // POST request ke backend
+        await axios.post('/api/chat/messages', 
+          { message: newMessage, reply_to_id: replyTo?.id },
+          {
+            headers: { 
+              Authorization: `Bearer ${token}`,
+              'Content-Type': 'application/json'
+            }
+          }
+        );
+      }
       
-      // Clear input dan refresh pesan
+      // Clear input dan ambil pesan baru
+      // Backend sudah mematikan indikator mengetik saat pesan terkirim
+      typingSentAtRef.current = null;
       setNewMessage('');
-      fetchMessages();
+      setReplyTo(null);
+      setAttachment(null);
+      setDeliverAt('');
+      fetchNewMessages();
     } catch (error) {
       console.error('Error sending message:', error);
       alert('Gagal mengirim pesan: ' + (error.response?.data?.error || error.message));
@@ -140,6 +463,139 @@ function Chat({ user, onLogout }) {
     }
   };
 
+  /**
+   * Simpan foto atau video dari sebuah pesan ke galeri
+   * 
+   * Endpoint: POST /api/chat/messages/:id/gallery
+   * 
+   * @param {Object} msg - Pesan dengan lampiran foto/video
+   */
+  const handleSaveToGallery = async (msg) => {
+    try {
+      const token = localStorage.getItem('authToken');
+      await axios.post(`/api/chat/messages/${msg.id}/gallery`, {}, {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+      alert('Disimpan ke galeri');
+    } catch (error) {
+      console.error('Error saving to gallery:', error);
+      alert('Gagal menyimpan ke galeri: ' + (error.response?.data?.error || error.message));
+    }
+  };
+
+  /**
+   * Ganti satu pesan di state dengan versi terbaru dari backend
+   * @param {Object} updated - Pesan hasil edit atau hapus
+   */
+  const replaceMessage = (updated) => {
+    setMessages((prev) => prev.map((msg) => (msg.id === updated.id ? updated : msg)));
+  };
+
+  /**
+   * Edit pesan sendiri
+   * 
+   * Endpoint: PATCH /api/chat/messages/:id
+   * Hanya bisa dalam batas waktu tertentu setelah pesan dikirim
+   * 
+   * @param {Object} msg - Pesan yang akan diedit
+   */
+  const handleEditMessage = async (msg) => {
+    const text = window.prompt('Edit pesan:', msg.message);
+    if (text === null || !text.trim() || text === msg.message) return;
+
+    try {
+      const token = localStorage.getItem('authToken');
+      const response = await axios.patch(`/api/chat/messages/${msg.id}`,
+        { message: text },
+        { headers: { Authorization: `Bearer ${token}` } }
+      );
+      replaceMessage(response.data.data);
+    } catch (error) {
+      console.error('Error editing message:', error);
+      alert('Gagal mengedit pesan: ' + (error.response?.data?.error || error.message));
+    }
+  };
+
+  /**
+   * Hapus pesan sendiri
+   * 
+   * Endpoint: DELETE /api/chat/messages/:id
+   * Pesan tetap ada di riwayat sebagai tombstone "Pesan ini telah dihapus"
+   * 
+   * @param {Object} msg - Pesan yang akan dihapus
+   */
+  const handleDeleteMessage = async (msg) => {
+    if (!window.confirm('Hapus pesan ini?')) return;
+
+    try {
+      const token = localStorage.getItem('authToken');
+      const response = await axios.delete(`/api/chat/messages/${msg.id}`, {
+        headers: { Authorization: `Bearer ${token}` }
+      });
+      replaceMessage(response.data.data);
+    } catch (error) {
+      console.error('Error deleting message:', error);
+      alert('Gagal menghapus pesan: ' + (error.response?.data?.error || error.message));
+    }
+  };
+
+  /**
+   * Tambah atau hapus reaksi emoji milik user pada sebuah pesan
+   * 
+   * Endpoint:
+   * - POST /api/chat/messages/:id/reactions (body: { emoji })
+   * - DELETE /api/chat/messages/:id/reactions/:emoji
+   * 
+   * Response: { message_id, reactions: [{ emoji, count, user_ids }] }
+   * 
+   * @param {Object} msg - Pesan yang diberi reaksi
+   * @param {string} emoji - Emoji reaksi
+   */
+  const toggleReaction = async (msg, emoji) => {
+    const mine = (msg.reactions || []).some(
+      (r) => r.emoji === emoji && r.user_ids.includes(user?.id)
+    );
+
+    try {
+      const token = localStorage.getItem('authToken');
+      const headers = { Authorization: `Bearer ${token}` };
+      const response = mine
+        ? await axios.delete(`/api/chat/messages/${msg.id}/reactions/${encodeURIComponent(emoji)}`, { headers })
+        : await axios.post(`/api/chat/messages/${msg.id}/reactions`, { emoji }, { headers });
+      replaceMessage({ ...msg, reactions: response.data.reactions });
+    } catch (error) {
+      console.error('Error updating reaction:', error);
+      alert('Gagal menyimpan reaksi: ' + (error.response?.data?.error || error.message));
+    }
+  };
+
+  /**
+   * Unduh seluruh percakapan sebagai ZIP berisi halaman HTML dan semua lampiran
+   * 
+   * Endpoint: GET /api/chat/export?format=html&zip=true
+   */
+  const handleExport = async () => {
+    try {
+      const token = localStorage.getItem('authToken');
+      const response = await axios.get('/api/chat/export', {
+        params: { format: 'html', zip: true },
+        headers: { Authorization: `Bearer ${token}` },
+        responseType: 'blob'
+      });
+
+      // Simpan blob lewat link sementara
+      const url = URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = 'fasisi-chat.zip';
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (error) {
+      console.error('Error exporting chat:', error);
+      alert('Gagal mengekspor chat');
+    }
+  };
+
   /**
    * Handle logout
    * Memanggil callback onLogout dan redirect ke halaman login
@@ -173,6 +629,7 @@ function Chat({ user, onLogout }) {
    * - Pesan yang dikirim (sender_id === user.id): Tampil di kanan dengan background biru
    * - Pesan yang diterima (sender_id !== user.id): Tampil di kiri dengan background abu-abu
    * - Setiap pesan menampilkan isi pesan dan timestamp
+   * - Pesan yang dihapus tampil miring sebagai "Pesan ini telah dihapus"
    */
   return (
     <div className="page-container">
@@ -191,12 +648,44 @@ function Chat({ user, onLogout }) {
 
       {/* Main Content */}
       <div className="page-content">
-        <h1>💬 Chat</h1>
+        <h1>
+          💬 Chat
+          <button type="button" className="chat-export" onClick={handleExport} title="Unduh percakapan">⬇️</button>
+          {/* Timer pesan sementara, berlaku untuk kedua pasangan */}
+          <select
+            className="chat-ttl"
+            value={messageTTL}
+            onChange={(e) => handleChangeTTL(Number(e.target.value))}
+            title="Pesan sementara"
+          >
+            <option value={0}>⏱️ Mati</option>
+            <option value={3600}>⏱️ 1 jam</option>
+            <option value={86400}>⏱️ 1 hari</option>
+            <option value={604800}>⏱️ 1 minggu</option>
+          </select>
+        </h1>
+        {/* Status pasangan */}
+        {partnerPresence && (
+          <div className={`chat-presence ${partnerTyping ? 'online' : partnerPresence.status}`}>
+            {partnerTyping ? 'sedang mengetik...' : formatPresence(partnerPresence)}
+          </div>
+        )}
         
         {/* Chat Container */}
         <div className="chat-container">
           {/* Messages Area */}
           <div className="chat-messages">
+            {/* Tombol untuk memuat halaman pesan sebelumnya */}
+            {!loading && hasOlder && (
+              <button
+                type="button"
+                className="load-older"
+                onClick={loadOlderMessages}
+                disabled={loadingOlder}
+              >
+                {loadingOlder ? 'Memuat...' : 'Muat pesan sebelumnya'}
+              </button>
+            )}
             {/* Loading state */}
             {loading ? (
               <p>Loading messages...</p>
@@ -205,18 +694,76 @@ function Chat({ user, onLogout }) {
               <p className="empty-chat">Belum ada pesan. Mulai chat dengan pasanganmu!</p>
             ) : (
               /* List messages - loop semua pesan */
-              messages.map((msg, idx) => (
+              /* Pesan sementara yang sudah lewat expires_at langsung disembunyikan, tanpa menunggu server */
+              messages.filter((msg) => !msg.expires_at || new Date(msg.expires_at).getTime() > now).map((msg) => msg.system ? (
+                <div key={msg.id} className="message-system">{msg.message}</div>
+              ) : (
                 <div 
-                  key={idx} 
+                  key={msg.id} 
                   className={`message ${msg.sender_id === user?.id ? 'sent' : 'received'}`}
                 >
-                  {/* Isi pesan */}
-                  <div className="message-content">
-                    {msg.message}
-                  </div>
-                  {/* Timestamp */}
+                  {/* Kutipan pesan yang dibalas */}
+                  {msg.reply_to && (
+                    <div className="message-quote">
+                      {msg.reply_to.message || 'Pesan ini telah dihapus'}
+                    </div>
+                  )}
+                  {/* Lampiran foto, video atau voice note */}
+                  {msg.attachment && (
+                    <div className="message-attachment">
+                      <AttachmentMedia attachment={msg.attachment} />
+                    </div>
+                  )}
+                  {/* Isi pesan, atau tombstone jika sudah dihapus */}
+                  {(msg.deleted_at || msg.message) && (
+                    <div className={`message-content ${msg.deleted_at ? 'message-deleted' : ''}`}>
+                      {msg.deleted_at ? 'Pesan ini telah dihapus' : msg.message}
+                    </div>
+                  )}
+                  {/* Reaksi emoji, dikelompokkan per emoji */}
+                  {!msg.deleted_at && (
+                    <div className="message-reactions">
+                      {(msg.reactions || []).map((r) => (
+                        <button
+                          key={r.emoji}
+                          type="button"
+                          className={`reaction ${r.user_ids.includes(user?.id) ? 'mine' : ''}`}
+                          onClick={() => toggleReaction(msg, r.emoji)}
+                        >
+                          {r.emoji} {r.count}
+                        </button>
+                      ))}
+                      {!(msg.reactions || []).some((r) => r.emoji === '❤️') && (
+                        <button type="button" className="reaction add" onClick={() => toggleReaction(msg, '❤️')} title="Beri reaksi">
+                          +❤️
+                        </button>
+                      )}
+                    </div>
+                  )}
+                  {/* Timestamp, label diedit dan aksi untuk pesan sendiri */}
                   <div className="message-time">
                     {formatTime(msg.created_at)}
+                    {msg.expires_at && ' · ⏱️'}
+                    {msg.edited_at && !msg.deleted_at && ' · diedit'}
+                    {msg.sender_id === user?.id && !msg.deleted_at && (
+                      <span className={`message-status ${msg.status || 'sent'}`} title={statusLabels[msg.status] || statusLabels.sent}>
+                        {msg.status === 'delivered' || msg.status === 'read' ? '✓✓' : '✓'}
+                      </span>
+                    )}
+                    {!msg.deleted_at && (
+                      <span className="message-actions">
+                        <button type="button" onClick={() => setReplyTo(msg)} title="Balas">↩️</button>
+                        {['photo', 'video'].includes(msg.attachment?.file_type) && (
+                          <button type="button" onClick={() => handleSaveToGallery(msg)} title="Simpan ke galeri">🖼️</button>
+                        )}
+                      </span>
+                    )}
+                    {msg.sender_id === user?.id && !msg.deleted_at && (
+                      <span className="message-actions">
+                        <button type="button" onClick={() => handleEditMessage(msg)} title="Edit">✏️</button>
+                        <button type="button" onClick={() => handleDeleteMessage(msg)} title="Hapus">🗑️</button>
+                      </span>
+                    )}
                   </div>
                 </div>
               ))
@@ -225,21 +772,52 @@ function Chat({ user, onLogout }) {
             <div ref={messagesEndRef} />
           </div>
           
+          {/* Pesan yang sedang dibalas, bisa dibatalkan */}
+          {replyTo && (
+            <div className="chat-reply">
+              <span>↩️ {replyTo.message}</span>
+              <button type="button" onClick={() => setReplyTo(null)} title="Batal">✖</button>
+            </div>
+          )}
+
           {/* Input Form untuk mengirim pesan baru */}
           <form onSubmit={handleSendMessage} className="chat-input">
+            {/* Pilih lampiran foto, video atau voice note */}
+            <label className="chat-attach" title="Lampiran">
+              {attachment ? '📎✔' : '📎'}
+              <input
+                type="file"
+                accept="image/jpeg,image/png,image/gif,video/*,audio/ogg,audio/mpeg,audio/mp4"
+                onChange={(e) => setAttachment(e.target.files[0] || null)}
+                disabled={sending}
+                hidden
+              />
+            </label>
+            {/* Jadwalkan pesan teks, tidak untuk lampiran atau balasan */}
+            {!attachment && !replyTo && (
+              <input
+                type="datetime-local"
+                className="chat-schedule"
+                value={deliverAt}
+                onChange={(e) => setDeliverAt(e.target.value)}
+                title="Jadwalkan pesan"
+                disabled={sending}
+              />
+            )}
             <input
               type="text"
               value={newMessage}
-              onChange={(e) => setNewMessage(e.target.value)}
-              placeholder="Ketik pesan..."
+              onChange={handleMessageChange}
+              onBlur={() => sendTyping(false)}
+              placeholder={attachment ? `Caption untuk ${attachment.name}...` : 'Ketik pesan...'}
               disabled={sending}
             />
             <button 
               type="submit" 
               className="btn-primary" 
-              disabled={sending || !newMessage.trim()}
+              disabled={sending || (!newMessage.trim() && !attachment)}
             >
-              {sending ? 'Mengirim...' : 'Kirim'}
+              {sending ? 'Mengirim...' : deliverAt && !attachment && !replyTo ? 'Jadwalkan' : 'Kirim'}
             </button>
           </form>
         </div>
@@ -32,22 +32,27 @@ describe('Chat Component', () => {
   it('displays chat messages', async () => {
     const axios = await import('axios');
     axios.default.get.mockResolvedValue({
-      data: [
-        {
-          id: 1,
-          sender_id: 1,
-          receiver_id: 2,
-          message: 'Halo sayang!',
-          created_at: '2024-01-01T00:00:00Z',
-        },
-        {
-          id: 2,
-          sender_id: 2,
-          receiver_id: 1,
-          message: 'Halo juga!',
-          created_at: '2024-01-01T00:01:00Z',
-        },
-      ],
+      data: {
+        messages: [
+          {
+            id: 1,
+            sender_id: 1,
+            receiver_id: 2,
+            message: 'Halo sayang!',
+            created_at: '2024-01-01T00:00:00Z',
+          },
+          {
+            id: 2,
+            sender_id: 2,
+            receiver_id: 1,
+            message: 'Halo juga!',
+            created_at: '2024-01-01T00:01:00Z',
+          },
+        ],
+        next_cursor: null,
+        prev_cursor: 1,
+        has_more: false,
+      },
     });
 
     renderWithRouter(<Chat />);
@@ -60,7 +65,7 @@ describe('Chat Component', () => {
 
   it('sends new message', async () => {
     const axios = await import('axios');
-    axios.default.get.mockResolvedValue({ data: [] });
+    axios.default.get.mockResolvedValue({ data: { messages: [], next_cursor: null, prev_cursor: null, has_more: false } });
     axios.default.post.mockResolvedValue({
       data: {
         id: 3,
@@ -99,7 +104,7 @@ describe('Chat Component', () => {
 
   it('clears input after sending message', async () => {
     const axios = await import('axios');
-    axios.default.get.mockResolvedValue({ data: [] });
+    axios.default.get.mockResolvedValue({ data: { messages: [], next_cursor: null, prev_cursor: null, has_more: false } });
     axios.default.post.mockResolvedValue({
       data: { id: 1, message: 'Test' },
     });
@@ -98,6 +98,9 @@ function Notifications({ user, onLogout }) {
                   {notif.type === 'new_message' && '💬'}
                   {notif.type === 'date_request' && '🎯'}
                   {notif.type === 'gallery_upload' && '📷'}
+                  {notif.type === 'partner_linked' && '💞'}
+                  {notif.type === 'security_alert' && '🔒'}
+                  {notif.type === 'reaction' && '😍'}
                 </div>
                 <div className="notif-content">
                   <p className="notif-message">{notif.message}</p>